@@ -10,14 +10,34 @@ import (
 	"time"
 
 	"github.com/charmbracelet/log"
+	"github.com/muesli/termenv"
 	logport "pkt.systems/logport"
 )
 
+func init() {
+	logport.Register("charm", NewWithCommonOptions)
+}
+
 // ErrLoggerRequired signals that a charm adapter is missing its underlying logger.
 var (
 	ErrLoggerRequired error = errors.New("logger is required")
 )
 
+// Options configures NewWithAdapterOptions: charm's own options plus the
+// Fatal and Panic behaviour the other adapters take in their Options.
+type Options struct {
+	log.Options
+
+	// ExitFunc, when non-nil, terminates the process after Fatal instead of
+	// the process-wide exit function. Registered exit hooks run first either
+	// way.
+	ExitFunc logport.ExitFunc
+
+	// PanicPolicy controls whether Panic panics after logging (the default)
+	// or logs at error level and continues.
+	PanicPolicy logport.PanicPolicy
+}
+
 // New constructs a logging adapter backed by charmbracelet/log.
 func New(w io.Writer) logport.ForLogging {
 	return charmAdapter{logger: log.NewWithOptions(w, log.Options{
@@ -40,6 +60,57 @@ func NewWithOptions(w io.Writer, o log.Options) logport.ForLogging {
 	return charmAdapter{logger: log.NewWithOptions(w, o)}
 }
 
+// NewWithCommonOptions builds a charm adapter from the cross-adapter options,
+// with charm's text formatter for console and JSON formatter for structured
+// output. charm's field names are constants ("time", "level" and "msg") and
+// its JSON is never colored, so other keys and Color set to ColorAlways for
+// structured output yield an *logport.UnsupportedOptionError.
+func NewWithCommonOptions(w io.Writer, opts logport.CommonOptions) (logport.ForLogging, error) {
+	if err := opts.CheckFixedKeys("charm", log.TimestampKey, log.LevelKey, log.MessageKey); err != nil {
+		return nil, err
+	}
+	if opts.Structured && opts.Color == logport.ColorAlways {
+		return nil, &logport.UnsupportedOptionError{Backend: "charm", Option: "Color", Reason: "structured output is never colored"}
+	}
+	charmOpts := log.Options{TimeFormat: opts.TimeFormat, ReportTimestamp: !opts.DisableTimestamp}
+	if charmOpts.TimeFormat == "" {
+		charmOpts.TimeFormat = time.RFC3339
+	}
+	if opts.UTC {
+		charmOpts.TimeFunction = func(t time.Time) time.Time { return t.UTC() }
+	}
+	if opts.Structured {
+		charmOpts.Formatter = log.JSONFormatter
+	}
+	if opts.MinLevel != nil {
+		charmOpts.Level = log.DebugLevel
+	}
+	charm := log.NewWithOptions(w, charmOpts)
+	switch opts.Color {
+	case logport.ColorAlways:
+		charm.SetColorProfile(termenv.ANSI256)
+	case logport.ColorNever:
+		charm.SetColorProfile(termenv.Ascii)
+	}
+	var logger logport.ForLogging = charmAdapter{logger: charm}
+	if opts.MinLevel != nil {
+		logger = logger.LogLevel(*opts.MinLevel)
+	}
+	if opts.AddSource {
+		logger = logport.AddSource(logger)
+	}
+	return logger, nil
+}
+
+// NewWithAdapterOptions is like NewWithOptions but also applies opts.ExitFunc
+// and opts.PanicPolicy to Fatal and Panic.
+func NewWithAdapterOptions(w io.Writer, opts Options) logport.ForLogging {
+	return charmAdapter{
+		logger: log.NewWithOptions(w, opts.Options),
+		exit:   logport.ExitPolicy{ExitFunc: opts.ExitFunc, PanicPolicy: opts.PanicPolicy},
+	}
+}
+
 // ContextWithLogger stores a charm adapter inside the provided context.
 func ContextWithLogger(ctx context.Context, w io.Writer, o log.Options) context.Context {
 	return logport.ContextWithLogger(ctx, charmAdapter{logger: log.NewWithOptions(w, o)})
@@ -50,6 +121,7 @@ type charmAdapter struct {
 	groups          []string
 	forcedLevel     *logport.Level
 	includeLogLevel bool
+	exit            logport.ExitPolicy
 }
 
 func (c charmAdapter) LogLevel(level logport.Level) logport.ForLogging {
@@ -58,11 +130,11 @@ func (c charmAdapter) LogLevel(level logport.Level) logport.ForLogging {
 	}
 	if level == logport.NoLevel {
 		lvl := level
-		return charmAdapter{logger: c.logger, groups: c.groups, forcedLevel: &lvl, includeLogLevel: c.includeLogLevel}
+		return charmAdapter{logger: c.logger, groups: c.groups, forcedLevel: &lvl, includeLogLevel: c.includeLogLevel, exit: c.exit}
 	}
 	clone := c.logger.With()
 	clone.SetLevel(portLevelToCharm(level))
-	return charmAdapter{logger: clone, groups: c.groups, includeLogLevel: c.includeLogLevel}
+	return charmAdapter{logger: clone, groups: c.groups, includeLogLevel: c.includeLogLevel, exit: c.exit}
 }
 
 func (c charmAdapter) LogLevelFromEnv(key string) logport.ForLogging {
@@ -76,7 +148,7 @@ func (c charmAdapter) WithLogLevel() logport.ForLogging {
 	if c.includeLogLevel {
 		return c
 	}
-	return charmAdapter{logger: c.logger, groups: c.groups, forcedLevel: c.forcedLevel, includeLogLevel: true}
+	return charmAdapter{logger: c.logger, groups: c.groups, forcedLevel: c.forcedLevel, includeLogLevel: true, exit: c.exit}
 }
 
 func (c charmAdapter) Log(_ context.Context, level slog.Level, msg string, keyvals ...any) {
@@ -132,13 +204,13 @@ func (c charmAdapter) currentLevel() logport.Level {
 
 func (c charmAdapter) With(keyvals ...any) logport.ForLogging {
 	if c.logger == nil || len(keyvals) == 0 {
-		return charmAdapter{logger: c.logger, groups: c.groups, forcedLevel: c.forcedLevel, includeLogLevel: c.includeLogLevel}
+		return charmAdapter{logger: c.logger, groups: c.groups, forcedLevel: c.forcedLevel, includeLogLevel: c.includeLogLevel, exit: c.exit}
 	}
 	normalized := normalizeCharmKeyvals(keyvals, nil)
 	if len(normalized) == 0 {
-		return charmAdapter{logger: c.logger, groups: c.groups, forcedLevel: c.forcedLevel, includeLogLevel: c.includeLogLevel}
+		return charmAdapter{logger: c.logger, groups: c.groups, forcedLevel: c.forcedLevel, includeLogLevel: c.includeLogLevel, exit: c.exit}
 	}
-	return charmAdapter{logger: c.logger.With(normalized...), groups: c.groups, forcedLevel: c.forcedLevel, includeLogLevel: c.includeLogLevel}
+	return charmAdapter{logger: c.logger.With(normalized...), groups: c.groups, forcedLevel: c.forcedLevel, includeLogLevel: c.includeLogLevel, exit: c.exit}
 }
 
 func (c charmAdapter) WithTrace(ctx context.Context) logport.ForLogging {
@@ -220,10 +292,13 @@ func (c charmAdapter) Errorf(format string, args ...any) {
 	c.Error(formatMessage(format, args...))
 }
 
+// Fatal logs at FatalLevel and terminates through the adapter's ExitPolicy
+// rather than charm's own os.Exit, so exit hooks and ExitFunc apply.
 func (c charmAdapter) Fatal(msg string, keyvals ...any) {
 	keyvals = c.appendLogLevel(keyvals)
 	keyvals = normalizeCharmKeyvals(keyvals, c.groups)
-	c.logger.Fatal(msg, keyvals...)
+	c.logger.Log(log.FatalLevel, msg, keyvals...)
+	c.exit.Exit(1)
 }
 
 func (c charmAdapter) Fatalf(format string, args ...any) {
@@ -236,6 +311,9 @@ func (c charmAdapter) Panic(msg string, keyvals ...any) {
 		keyvals = normalizeCharmKeyvals(keyvals, c.groups)
 		c.logger.Error(msg, keyvals...)
 	}
+	if c.exit.PanicContinues() {
+		return
+	}
 	panic(msg)
 }
 
@@ -308,7 +386,7 @@ func (c charmAdapter) WithAttrs(attrs []slog.Attr) slog.Handler {
 		return c
 	}
 	keyvals := attrsToKeyvals(attrs, c.groups)
-	return charmAdapter{logger: c.logger.With(keyvals...), groups: c.groups, forcedLevel: c.forcedLevel, includeLogLevel: c.includeLogLevel}
+	return charmAdapter{logger: c.logger.With(keyvals...), groups: c.groups, forcedLevel: c.forcedLevel, includeLogLevel: c.includeLogLevel, exit: c.exit}
 }
 
 func (c charmAdapter) WithGroup(name string) slog.Handler {
@@ -316,7 +394,7 @@ func (c charmAdapter) WithGroup(name string) slog.Handler {
 		return c
 	}
 	groups := appendGroup(c.groups, name)
-	return charmAdapter{logger: c.logger, groups: groups, forcedLevel: c.forcedLevel, includeLogLevel: c.includeLogLevel}
+	return charmAdapter{logger: c.logger, groups: groups, forcedLevel: c.forcedLevel, includeLogLevel: c.includeLogLevel, exit: c.exit}
 }
 
 func slogLevelToCharm(level slog.Level) log.Level {
@@ -136,3 +136,26 @@ func TestCharmNoLevelRemovesSeverity(t *testing.T) {
 		t.Fatalf("expected no level in chained logger, got %q", buf.String())
 	}
 }
+
+func TestFatalAndPanicFollowExitPolicy(t *testing.T) {
+	buf := &bytes.Buffer{}
+	code := 0
+	logger := NewWithAdapterOptions(buf, Options{
+		ExitFunc:    func(c int) { code = c },
+		PanicPolicy: logport.PanicContinue,
+	}).With("svc", "api")
+
+	logger.Fatal("shutting down")
+	if code != 1 {
+		t.Fatalf("expected ExitFunc to receive 1, got %d", code)
+	}
+	if !strings.Contains(buf.String(), "FATA") || !strings.Contains(buf.String(), "shutting down") {
+		t.Fatalf("expected fatal entry, got %q", buf.String())
+	}
+
+	buf.Reset()
+	logger.Panic("bad state")
+	if !strings.Contains(buf.String(), "ERRO") || !strings.Contains(buf.String(), "bad state") {
+		t.Fatalf("expected panic to be logged as error, got %q", buf.String())
+	}
+}
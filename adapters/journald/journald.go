@@ -0,0 +1,417 @@
+// Package journald writes log entries to the systemd journal using its native
+// datagram protocol, so every keyval arrives as a journal field instead of
+// text the journal has to parse:
+//
+//	logger := journald.New()
+//	logger.WithTrace(ctx).Info("invoice.sent", "invoice_id", id)
+//
+// shows up in `journalctl -o verbose` with MESSAGE, PRIORITY,
+// SYSLOG_IDENTIFIER, CODE_FILE, CODE_LINE, CODE_FUNC, TRACE_ID, SPAN_ID and
+// INVOICE_ID. Keys are upper-cased and every character outside [A-Z0-9_] is
+// replaced with an underscore; grouped keys are joined with "_".
+//
+// Entries too large for a datagram are passed to the journal in a sealed
+// memfd (Linux only). When the journal socket cannot be reached and
+// JOURNAL_STREAM shows that stderr is connected to the journal, as it is for
+// services started by systemd, entries are written to stderr as
+// "<N>"-prefixed sd-daemon lines instead, and the socket is retried with
+// exponential backoff.
+package journald
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	logport "pkt.systems/logport"
+	"pkt.systems/logport/adapters/slogger"
+	"pkt.systems/logport/internal/netsink"
+	"pkt.systems/logport/internal/severity"
+)
+
+// DefaultSocketPath is where systemd-journald listens for native protocol
+// datagrams.
+const DefaultSocketPath = "/run/systemd/journal/socket"
+
+// maxFieldName is the longest field name journald accepts.
+const maxFieldName = 64
+
+// Options configures NewWithOptions and NewHandler.
+type Options struct {
+	// SocketPath is the journal's native socket. Defaults to
+	// DefaultSocketPath.
+	SocketPath string
+	// Identifier is sent as SYSLOG_IDENTIFIER. Defaults to the executable's
+	// base name.
+	Identifier string
+	// DisableSource omits CODE_FILE, CODE_LINE and CODE_FUNC.
+	DisableSource bool
+	// Stderr receives sd-daemon lines when the journal socket cannot be
+	// reached. When nil, os.Stderr is used if JOURNAL_STREAM matches it and
+	// entries are dropped otherwise.
+	Stderr io.Writer
+
+	MinLevel    *logport.Level
+	ExitFunc    logport.ExitFunc
+	PanicPolicy logport.PanicPolicy
+}
+
+// New returns a journald adapter using the default options.
+func New() logport.ForLogging {
+	return NewWithOptions(Options{})
+}
+
+// NewWithOptions returns a journald adapter configured by opts.
+func NewWithOptions(opts Options) logport.ForLogging {
+	return slogger.NewWithOptions(nil, slogger.Options{
+		Handler:     NewHandler(opts),
+		MinLevel:    opts.MinLevel,
+		ExitFunc:    opts.ExitFunc,
+		PanicPolicy: opts.PanicPolicy,
+	})
+}
+
+// NewWithCommonOptions builds a journald adapter from the cross-adapter
+// options. It is not registered with logport because the journal is not an
+// io.Writer. MinLevel applies as usual and AddSource controls the CODE_*
+// fields. The journal stamps, names and stores fields itself, so TimeFormat,
+// UTC, DisableTimestamp, TimeKey, LevelKey, MessageKey and Color set to
+// ColorAlways yield an *logport.UnsupportedOptionError. Structured has no
+// effect: journal entries are always structured.
+func NewWithCommonOptions(opts logport.CommonOptions) (logport.ForLogging, error) {
+	for _, unsupported := range []struct {
+		option string
+		set    bool
+	}{
+		{"TimeFormat", opts.TimeFormat != ""},
+		{"UTC", opts.UTC},
+		{"DisableTimestamp", opts.DisableTimestamp},
+		{"TimeKey", opts.TimeKey != ""},
+		{"LevelKey", opts.LevelKey != ""},
+		{"MessageKey", opts.MessageKey != ""},
+	} {
+		if unsupported.set {
+			return nil, &logport.UnsupportedOptionError{Backend: "journald", Option: unsupported.option, Reason: "the journal timestamps entries and names fields itself"}
+		}
+	}
+	if opts.Color == logport.ColorAlways {
+		return nil, &logport.UnsupportedOptionError{Backend: "journald", Option: "Color", Reason: "journal entries are never colored"}
+	}
+	return NewWithOptions(Options{MinLevel: opts.MinLevel, DisableSource: !opts.AddSource}), nil
+}
+
+// ContextWithLogger stores a configured journald adapter inside the context.
+func ContextWithLogger(ctx context.Context, opts Options) context.Context {
+	return logport.ContextWithLogger(ctx, NewWithOptions(opts))
+}
+
+// Handler is a slog.Handler writing to the journal. Handlers derived with
+// WithAttrs and WithGroup share the socket.
+type Handler struct {
+	journal *journal
+	attrs   []field
+	prefix  string
+}
+
+// NewHandler returns a Handler configured by opts. The socket is opened
+// lazily on the first entry.
+func NewHandler(opts Options) *Handler {
+	if opts.SocketPath == "" {
+		opts.SocketPath = DefaultSocketPath
+	}
+	if opts.Identifier == "" {
+		opts.Identifier = filepath.Base(os.Args[0])
+	}
+	if opts.Stderr == nil && isJournalStream(os.Stderr) {
+		opts.Stderr = os.Stderr
+	}
+	j := &journal{opts: opts}
+	j.conn = netsink.Conn{
+		Dial: func() (net.Conn, error) {
+			return net.DialUnix("unixgram", nil, &net.UnixAddr{Name: opts.SocketPath, Net: "unixgram"})
+		},
+		MinBackoff: netsink.DefaultMinBackoff,
+		MaxBackoff: netsink.DefaultMaxBackoff,
+	}
+	return &Handler{journal: j}
+}
+
+// Close closes the journal socket. Later entries reopen it.
+func (h *Handler) Close() error {
+	return h.journal.close()
+}
+
+// Enabled implements slog.Handler; level filtering is left to the logger.
+func (h *Handler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle implements slog.Handler.
+func (h *Handler) Handle(_ context.Context, record slog.Record) error {
+	level := logport.LevelFromSlog(record.Level)
+	fields := make([]field, 0, 6+len(h.attrs)+record.NumAttrs())
+	fields = append(fields,
+		field{name: "MESSAGE", value: record.Message},
+		field{name: "PRIORITY", value: strconv.Itoa(Priority(level))},
+		field{name: "SYSLOG_IDENTIFIER", value: h.journal.opts.Identifier},
+	)
+	if !h.journal.opts.DisableSource {
+		if frame, ok := callerFrame(record.PC); ok {
+			fields = append(fields,
+				field{name: "CODE_FILE", value: frame.File},
+				field{name: "CODE_LINE", value: strconv.Itoa(frame.Line)},
+				field{name: "CODE_FUNC", value: frame.Function},
+			)
+		}
+	}
+	fields = append(fields, h.attrs...)
+	record.Attrs(func(attr slog.Attr) bool {
+		fields = appendFields(fields, h.prefix, attr)
+		return true
+	})
+	return h.journal.send(level, fields)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	next := &Handler{journal: h.journal, prefix: h.prefix, attrs: append([]field(nil), h.attrs...)}
+	for _, attr := range attrs {
+		next.attrs = appendFields(next.attrs, h.prefix, attr)
+	}
+	return next
+}
+
+// WithGroup implements slog.Handler; grouped keys are joined with "_".
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &Handler{journal: h.journal, attrs: h.attrs, prefix: h.prefix + name + "_"}
+}
+
+// Priority maps a logport level onto a syslog priority as used by the
+// journal's PRIORITY field.
+func Priority(level logport.Level) int {
+	return severity.FromLevel(level)
+}
+
+// FieldName turns key into a valid journal field name: upper case, only
+// [A-Z0-9_], not starting with an underscore (reserved for trusted fields) or
+// a digit, at most 64 characters. It returns "" when nothing usable remains.
+func FieldName(key string) string {
+	b := make([]byte, 0, len(key))
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			c -= 'a' - 'A'
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		default:
+			c = '_'
+		}
+		if c == '_' && len(b) == 0 {
+			continue
+		}
+		b = append(b, c)
+	}
+	if len(b) > 0 && b[0] >= '0' && b[0] <= '9' {
+		b = append([]byte("F_"), b...)
+	}
+	if len(b) > maxFieldName {
+		b = b[:maxFieldName]
+	}
+	return string(b)
+}
+
+// reservedNames are the fields the handler sets itself; keyvals mapping onto
+// one of them are prefixed so they do not turn into a second value.
+var reservedNames = map[string]bool{
+	"MESSAGE": true, "PRIORITY": true, "SYSLOG_IDENTIFIER": true,
+	"CODE_FILE": true, "CODE_LINE": true, "CODE_FUNC": true,
+}
+
+type field struct {
+	key   string
+	name  string
+	value string
+}
+
+func appendFields(dst []field, prefix string, attr slog.Attr) []field {
+	attr.Value = attr.Value.Resolve()
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "_"
+		}
+		for _, nested := range attr.Value.Group() {
+			dst = appendFields(dst, prefix, nested)
+		}
+		return dst
+	}
+	key := prefix + attr.Key
+	name := FieldName(key)
+	if attr.Key == "" || name == "" {
+		return dst
+	}
+	if reservedNames[name] {
+		name = "F_" + name
+	}
+	var value string
+	if attr.Value.Kind() == slog.KindTime {
+		value = attr.Value.Time().Format(time.RFC3339Nano)
+	} else {
+		value = attr.Value.String()
+	}
+	return append(dst, field{key: key, name: name, value: value})
+}
+
+// encode serialises fields in the native protocol. Values containing a
+// newline use the binary form: name, newline, little-endian 64-bit length,
+// value, newline.
+func encode(fields []field) []byte {
+	size := 0
+	for _, f := range fields {
+		size += len(f.name) + len(f.value) + 10
+	}
+	b := make([]byte, 0, size)
+	for _, f := range fields {
+		b = append(b, f.name...)
+		if strings.IndexByte(f.value, '\n') >= 0 {
+			b = append(b, '\n')
+			b = binary.LittleEndian.AppendUint64(b, uint64(len(f.value)))
+		} else {
+			b = append(b, '=')
+		}
+		b = append(b, f.value...)
+		b = append(b, '\n')
+	}
+	return b
+}
+
+// line renders fields as an sd-daemon stderr line: "<N>message key=value".
+// The first three fields are MESSAGE, PRIORITY and SYSLOG_IDENTIFIER, which
+// the journal derives from the stream itself.
+func line(level logport.Level, fields []field) []byte {
+	var b strings.Builder
+	b.WriteByte('<')
+	b.WriteString(strconv.Itoa(Priority(level)))
+	b.WriteByte('>')
+	b.WriteString(strings.ReplaceAll(fields[0].value, "\n", `\n`))
+	for _, f := range fields[3:] {
+		if f.key == "" {
+			continue
+		}
+		b.WriteByte(' ')
+		b.WriteString(f.key)
+		b.WriteByte('=')
+		if f.value == "" || strings.ContainsAny(f.value, " \"=\n") {
+			b.WriteString(strconv.Quote(f.value))
+		} else {
+			b.WriteString(f.value)
+		}
+	}
+	b.WriteByte('\n')
+	return []byte(b.String())
+}
+
+type journal struct {
+	opts Options
+
+	mu   sync.Mutex
+	conn netsink.Conn
+}
+
+// send writes one entry to the journal socket, falling back to stderr when
+// the socket cannot be reached.
+func (j *journal) send(level logport.Level, fields []field) error {
+	data := encode(fields)
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	err := j.write(data)
+	if err == nil {
+		return nil
+	}
+	if j.opts.Stderr != nil {
+		_, err = j.opts.Stderr.Write(line(level, fields))
+	}
+	return err
+}
+
+func (j *journal) write(data []byte) error {
+	return j.conn.Send(func(conn net.Conn) error {
+		_, err := conn.Write(data)
+		if isTooLarge(err) {
+			return sendMemfd(conn.(*net.UnixConn), data)
+		}
+		return err
+	})
+}
+
+func (j *journal) close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.conn.Close()
+}
+
+// isJournalStream reports whether JOURNAL_STREAM ("device:inode") names f.
+func isJournalStream(f *os.File) bool {
+	dev, ino, ok := strings.Cut(os.Getenv("JOURNAL_STREAM"), ":")
+	if !ok {
+		return false
+	}
+	wantDev, err := strconv.ParseUint(dev, 10, 64)
+	if err != nil {
+		return false
+	}
+	wantIno, err := strconv.ParseUint(ino, 10, 64)
+	if err != nil {
+		return false
+	}
+	gotDev, gotIno, ok := fileID(f)
+	return ok && gotDev == wantDev && gotIno == wantIno
+}
+
+// callerFrame resolves the call site of an entry. Records built by the logport
+// adapters carry a PC inside the adapter, so in that case the current stack
+// is searched for the first frame outside logport and log/slog.
+func callerFrame(pc uintptr) (runtime.Frame, bool) {
+	if pc != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		if frame.Function != "" && !internalFrame(frame) {
+			return frame, true
+		}
+	}
+	var pcs [32]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs[:])])
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "" && !internalFrame(frame) {
+			return frame, true
+		}
+		if !more {
+			return runtime.Frame{}, false
+		}
+	}
+}
+
+func internalFrame(frame runtime.Frame) bool {
+	if strings.HasSuffix(frame.File, "_test.go") {
+		return false
+	}
+	fn := frame.Function
+	return strings.HasPrefix(fn, "pkt.systems/logport.") ||
+		strings.HasPrefix(fn, "pkt.systems/logport/") ||
+		strings.HasPrefix(fn, "log/slog.") ||
+		strings.HasPrefix(fn, "runtime.")
+}
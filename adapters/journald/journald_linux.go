@@ -0,0 +1,56 @@
+//go:build linux
+
+package journald
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+func isTooLarge(err error) bool {
+	return errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS)
+}
+
+// sendMemfd passes data in a sealed memfd, the way sd_journal_send does for
+// entries that do not fit in a datagram.
+func sendMemfd(conn *net.UnixConn, data []byte) error {
+	fd, err := unix.MemfdCreate("logport-journal", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return err
+	}
+	file := os.NewFile(uintptr(fd), "logport-journal")
+	defer file.Close()
+	if _, err := file.Write(data); err != nil {
+		return err
+	}
+	if _, err := unix.FcntlInt(file.Fd(), unix.F_ADD_SEALS, unix.F_SEAL_SHRINK|unix.F_SEAL_GROW|unix.F_SEAL_WRITE|unix.F_SEAL_SEAL); err != nil {
+		return err
+	}
+	// WriteMsgUnix refuses connected datagram sockets, so go through the raw
+	// descriptor.
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	rights := unix.UnixRights(int(file.Fd()))
+	var sendErr error
+	if err := raw.Write(func(sock uintptr) bool {
+		sendErr = unix.Sendmsg(int(sock), nil, rights, nil, 0)
+		return sendErr != unix.EAGAIN
+	}); err != nil {
+		return err
+	}
+	return sendErr
+}
+
+func fileID(f *os.File) (dev, ino uint64, ok bool) {
+	var st unix.Stat_t
+	if err := unix.Fstat(int(f.Fd()), &st); err != nil {
+		return 0, 0, false
+	}
+	return uint64(st.Dev), uint64(st.Ino), true
+}
@@ -0,0 +1,82 @@
+//go:build linux
+
+package journald
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestLargeEntryUsesMemfd(t *testing.T) {
+	path, conn := listen(t)
+	logger := NewWithOptions(Options{SocketPath: path})
+	big := strings.Repeat("x", 4<<20)
+	logger.Info("large", "payload", big)
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 64*1024)
+	oob := make([]byte, unix.CmsgSpace(4))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if n != 0 {
+		t.Fatalf("expected an empty datagram carrying a descriptor, got %d bytes", n)
+	}
+	msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("expected one control message, got %d (%v)", len(msgs), err)
+	}
+	fds, err := unix.ParseUnixRights(&msgs[0])
+	if err != nil || len(fds) != 1 {
+		t.Fatalf("expected one descriptor, got %v (%v)", fds, err)
+	}
+	file := os.NewFile(uintptr(fds[0]), "memfd")
+	defer file.Close()
+	if _, err := file.Write([]byte("more")); err == nil {
+		t.Fatalf("expected a sealed memfd")
+	}
+	data, err := io.ReadAll(io.NewSectionReader(file, 0, 1<<30))
+	if err != nil {
+		t.Fatalf("read memfd: %v", err)
+	}
+	fields := decode(t, data)
+	if got := fields["PAYLOAD"]; len(got) != 1 || got[0] != big {
+		t.Fatalf("payload mismatch, got %d bytes", len(strings.Join(got, "")))
+	}
+	if got := fields["MESSAGE"]; len(got) != 1 || got[0] != "large" {
+		t.Fatalf("unexpected MESSAGE %q", got)
+	}
+}
+
+func TestJournalStreamMatchesStderr(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "stream"))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	defer f.Close()
+	var st unix.Stat_t
+	if err := unix.Fstat(int(f.Fd()), &st); err != nil {
+		t.Fatalf("fstat: %v", err)
+	}
+
+	t.Setenv("JOURNAL_STREAM", fmt.Sprintf("%d:%d", st.Dev, st.Ino))
+	if !isJournalStream(f) {
+		t.Fatalf("expected JOURNAL_STREAM to match")
+	}
+	t.Setenv("JOURNAL_STREAM", fmt.Sprintf("%d:%d", st.Dev, st.Ino+1))
+	if isJournalStream(f) {
+		t.Fatalf("expected a different inode not to match")
+	}
+	t.Setenv("JOURNAL_STREAM", "")
+	if isJournalStream(f) {
+		t.Fatalf("expected no match without JOURNAL_STREAM")
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package journald
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+// errMemfdUnsupported is returned for entries too large for a datagram on
+// platforms without memfd.
+var errMemfdUnsupported = errors.New("journald: entry too large for a datagram")
+
+func isTooLarge(error) bool { return false }
+
+func sendMemfd(*net.UnixConn, []byte) error { return errMemfdUnsupported }
+
+func fileID(*os.File) (dev, ino uint64, ok bool) { return 0, 0, false }
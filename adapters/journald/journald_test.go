@@ -0,0 +1,151 @@
+package journald
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNativeProtocolFields(t *testing.T) {
+	path, conn := listen(t)
+	logger := NewWithOptions(Options{SocketPath: path, Identifier: "billing"})
+
+	logger.With("req", "r1").WithLogLevel().Warn("invoice.sent", "trace_id", "abc", "priority", "high", "note", "a\nb", "1st", 1, "-x-", "y")
+
+	fields := decode(t, readDatagram(t, conn))
+	want := map[string]string{
+		"MESSAGE":           "invoice.sent",
+		"PRIORITY":          "4",
+		"SYSLOG_IDENTIFIER": "billing",
+		"REQ":               "r1",
+		"TRACE_ID":          "abc",
+		"F_PRIORITY":        "high",
+		"NOTE":              "a\nb",
+		"F_1ST":             "1",
+		"X_":                "y",
+	}
+	for name, value := range want {
+		if got := fields[name]; len(got) != 1 || got[0] != value {
+			t.Fatalf("%s = %q, want %q (all fields %q)", name, got, value, fields)
+		}
+	}
+	if file := fields["CODE_FILE"]; len(file) != 1 || filepath.Base(file[0]) != "journald_test.go" {
+		t.Fatalf("unexpected CODE_FILE %q", file)
+	}
+	if fn := fields["CODE_FUNC"]; len(fn) != 1 || !strings.HasSuffix(fn[0], ".TestNativeProtocolFields") {
+		t.Fatalf("unexpected CODE_FUNC %q", fn)
+	}
+}
+
+func TestGroupsAndDisableSource(t *testing.T) {
+	path, conn := listen(t)
+	handler := NewHandler(Options{SocketPath: path, DisableSource: true})
+	NewWithOptions(Options{SocketPath: path, DisableSource: true}).Info("plain")
+
+	fields := decode(t, readDatagram(t, conn))
+	if _, ok := fields["CODE_FILE"]; ok {
+		t.Fatalf("expected no CODE_FILE, got %q", fields)
+	}
+	if got := fields["PRIORITY"]; len(got) != 1 || got[0] != "6" {
+		t.Fatalf("unexpected PRIORITY %q", got)
+	}
+
+	grouped := handler.WithGroup("http").WithAttrs([]slog.Attr{slog.String("method", "GET")})
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "request", 0)
+	record.Add("status", 200, slog.Group("tls", "version", "1.3"))
+	if err := grouped.Handle(t.Context(), record); err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+	fields = decode(t, readDatagram(t, conn))
+	for name, value := range map[string]string{"HTTP_METHOD": "GET", "HTTP_STATUS": "200", "HTTP_TLS_VERSION": "1.3"} {
+		if got := fields[name]; len(got) != 1 || got[0] != value {
+			t.Fatalf("%s = %q, want %q (all fields %q)", name, got, value, fields)
+		}
+	}
+}
+
+func TestStderrFallbackWhenSocketMissing(t *testing.T) {
+	var stderr bytes.Buffer
+	logger := NewWithOptions(Options{SocketPath: filepath.Join(t.TempDir(), "missing"), Stderr: &stderr})
+	logger.With("req", "r1").Error("boom\nagain", "err", "disk full", "n", 3)
+
+	want := "<3>boom\\nagain req=r1 err=\"disk full\" n=3\n"
+	if stderr.String() != want {
+		t.Fatalf("unexpected stderr line:\n got %q\nwant %q", stderr.String(), want)
+	}
+}
+
+func TestFieldName(t *testing.T) {
+	cases := map[string]string{
+		"trace_id":              "TRACE_ID",
+		"http.status":           "HTTP_STATUS",
+		"_hidden":               "HIDDEN",
+		"9lives":                "F_9LIVES",
+		"___":                   "",
+		strings.Repeat("a", 80): strings.Repeat("A", 64),
+	}
+	for key, want := range cases {
+		if got := FieldName(key); got != want {
+			t.Fatalf("FieldName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func listen(t *testing.T) (string, *net.UnixConn) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram sockets unavailable: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return path, conn
+}
+
+func readDatagram(t *testing.T, conn *net.UnixConn) []byte {
+	t.Helper()
+	buf := make([]byte, 256*1024)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return buf[:n]
+}
+
+// decode parses a native protocol entry into its (possibly repeated) fields.
+func decode(t *testing.T, data []byte) map[string][]string {
+	t.Helper()
+	fields := make(map[string][]string)
+	for len(data) > 0 {
+		end := bytes.IndexAny(data, "=\n")
+		if end < 0 {
+			t.Fatalf("truncated entry %q", data)
+		}
+		name := string(data[:end])
+		if data[end] == '=' {
+			data = data[end+1:]
+			nl := bytes.IndexByte(data, '\n')
+			if nl < 0 {
+				t.Fatalf("unterminated field %s", name)
+			}
+			fields[name] = append(fields[name], string(data[:nl]))
+			data = data[nl+1:]
+			continue
+		}
+		data = data[end+1:]
+		size := binary.LittleEndian.Uint64(data)
+		data = data[8:]
+		fields[name] = append(fields[name], string(data[:size]))
+		if data[size] != '\n' {
+			t.Fatalf("binary field %s not newline terminated", name)
+		}
+		data = data[size+1:]
+	}
+	return fields
+}
@@ -6,6 +6,8 @@ import (
 	"io"
 	"log/slog"
 	"math"
+	"os"
+	"reflect"
 	"strings"
 	"time"
 
@@ -13,6 +15,10 @@ import (
 	logport "pkt.systems/logport"
 )
 
+func init() {
+	logport.Register("onelog", NewWithCommonOptions)
+}
+
 // Options controls how the onelog adapter formats and filters log output.
 type Options struct {
 	// Levels configures which onelog levels are enabled when constructing a fresh
@@ -28,8 +34,14 @@ type Options struct {
 	Hook func(onelogpkg.Entry)
 
 	// ExitFunc overrides the logger's ExitFn; handy for testing fatal flows.
+	// Fatal runs the hooks registered with logport.RegisterExitHook before
+	// calling it. When nil, the adapter exits through logport.Exit.
 	ExitFunc onelogpkg.ExitFunc
 
+	// PanicPolicy controls whether Panic panics after logging (the default)
+	// or logs at error level and continues.
+	PanicPolicy logport.PanicPolicy
+
 	// Configure receives the newly created logger for additional tuning before it
 	// is wrapped by the adapter.
 	Configure func(*onelogpkg.Logger)
@@ -78,15 +90,60 @@ func NewWithOptions(w io.Writer, opts Options) logport.ForLogging {
 	if opts.MinLevel != nil {
 		minLevel = *opts.MinLevel
 	}
-	return adapter{logger: logger, minLevel: minLevel}
+	exit := logport.ExitPolicy{ExitFunc: logport.ExitFunc(opts.ExitFunc), PanicPolicy: opts.PanicPolicy}
+	return adapter{logger: logger, minLevel: minLevel, exit: exit}
+}
+
+// NewWithCommonOptions builds a onelog adapter from the cross-adapter
+// options. onelog only writes uncolored JSON, so Structured is implied and
+// console output is never produced; it uses package-global level and message
+// field names, so Color set to ColorAlways and LevelKey or MessageKey other
+// than "level" and "message" yield an *logport.UnsupportedOptionError.
+// Timestamps default to logport.DTGTimeFormat under the "ts" key.
+func NewWithCommonOptions(w io.Writer, opts logport.CommonOptions) (logport.ForLogging, error) {
+	opts.Structured = true
+	if opts.Color == logport.ColorAlways {
+		return nil, &logport.UnsupportedOptionError{Backend: "onelog", Option: "Color", Reason: "output is never colored"}
+	}
+	if err := opts.CheckFixedKeys("onelog", "", "level", "message"); err != nil {
+		return nil, err
+	}
+	oneOpts := Options{MinLevel: opts.MinLevel, DisableTimestamp: true}
+	if !opts.DisableTimestamp {
+		key, format, utc := opts.TimeKey, opts.TimeFormat, opts.UTC
+		if key == "" {
+			key = "ts"
+		}
+		if format == "" {
+			format = logport.DTGTimeFormat
+		}
+		oneOpts.Hook = func(e onelogpkg.Entry) {
+			now := time.Now()
+			if utc {
+				now = now.UTC()
+			}
+			e.String(key, now.Format(format))
+		}
+	}
+	logger := NewWithOptions(w, oneOpts)
+	if opts.AddSource {
+		logger = logport.AddSource(logger)
+	}
+	return logger, nil
 }
 
-// NewFromLogger wraps an existing onelog logger in the adapter.
+// NewFromLogger wraps an existing onelog logger in the adapter. Fatal exits
+// through logport.Exit, so logport.SetExitFunc applies, unless the logger's
+// ExitFn was replaced with something other than onelog's default os.Exit.
 func NewFromLogger(logger *onelogpkg.Logger) logport.ForLogging {
 	if logger == nil {
 		return adapter{}
 	}
-	return adapter{logger: logger, minLevel: logport.TraceLevel}
+	var exit logport.ExitPolicy
+	if logger.ExitFn != nil && reflect.ValueOf(logger.ExitFn).Pointer() != reflect.ValueOf(os.Exit).Pointer() {
+		exit.ExitFunc = logport.ExitFunc(logger.ExitFn)
+	}
+	return adapter{logger: logger, minLevel: logport.TraceLevel, exit: exit}
 }
 
 // NewWithLogger is an alias for NewFromLogger to mirror other adapters.
@@ -107,6 +164,7 @@ type adapter struct {
 	forcedLevel     *logport.Level
 	minLevel        logport.Level
 	includeLogLevel bool
+	exit            logport.ExitPolicy
 }
 
 func (a adapter) LogLevelFromEnv(key string) logport.ForLogging {
@@ -120,9 +178,9 @@ func (a adapter) LogLevel(level logport.Level) logport.ForLogging {
 	switch level {
 	case logport.NoLevel, logport.Disabled:
 		lvl := level
-		return adapter{logger: a.logger, baseKeyvals: a.baseKeyvals, groups: a.groups, forcedLevel: &lvl, minLevel: a.minLevel, includeLogLevel: a.includeLogLevel}
+		return adapter{logger: a.logger, baseKeyvals: a.baseKeyvals, groups: a.groups, forcedLevel: &lvl, minLevel: a.minLevel, includeLogLevel: a.includeLogLevel, exit: a.exit}
 	default:
-		return adapter{logger: a.logger, baseKeyvals: a.baseKeyvals, groups: a.groups, minLevel: level, includeLogLevel: a.includeLogLevel}
+		return adapter{logger: a.logger, baseKeyvals: a.baseKeyvals, groups: a.groups, minLevel: level, includeLogLevel: a.includeLogLevel, exit: a.exit}
 	}
 }
 
@@ -137,7 +195,7 @@ func (a adapter) With(keyvals ...any) logport.ForLogging {
 	base := make([]any, 0, len(a.baseKeyvals)+len(addition))
 	base = append(base, a.baseKeyvals...)
 	base = append(base, addition...)
-	return adapter{logger: a.logger, baseKeyvals: base, groups: a.groups, forcedLevel: a.forcedLevel, minLevel: a.minLevel, includeLogLevel: a.includeLogLevel}
+	return adapter{logger: a.logger, baseKeyvals: base, groups: a.groups, forcedLevel: a.forcedLevel, minLevel: a.minLevel, includeLogLevel: a.includeLogLevel, exit: a.exit}
 }
 
 func (a adapter) WithTrace(ctx context.Context) logport.ForLogging {
@@ -159,6 +217,7 @@ func (a adapter) WithLogLevel() logport.ForLogging {
 		forcedLevel:     a.forcedLevel,
 		minLevel:        a.minLevel,
 		includeLogLevel: true,
+		exit:            a.exit,
 	}
 }
 
@@ -224,6 +283,10 @@ func (a adapter) Fatal(msg string, keyvals ...any) { a.logFatal(msg, keyvals) }
 func (a adapter) Fatalf(format string, args ...any) { a.logFatal(formatMessage(format, args...), nil) }
 
 func (a adapter) Panic(msg string, keyvals ...any) {
+	if a.exit.PanicContinues() {
+		a.log(logport.ErrorLevel, msg, keyvals)
+		return
+	}
 	a.log(logport.PanicLevel, msg, keyvals)
 	panic(msg)
 }
@@ -262,9 +325,7 @@ func (a adapter) logFatal(msg string, keyvals []any) {
 	entry = addKeyvals(entry, addition)
 	entry = a.appendLogLevel(entry)
 	entry.Write()
-	if a.logger != nil && a.logger.ExitFn != nil {
-		a.logger.ExitFn(1)
-	}
+	a.exit.Exit(1)
 }
 
 func (a adapter) shouldLog(level logport.Level) bool {
@@ -386,6 +447,7 @@ func (a adapter) WithAttrs(attrs []slog.Attr) slog.Handler {
 		forcedLevel:     a.forcedLevel,
 		minLevel:        a.minLevel,
 		includeLogLevel: a.includeLogLevel,
+		exit:            a.exit,
 	}
 }
 
@@ -400,6 +462,7 @@ func (a adapter) WithGroup(name string) slog.Handler {
 		forcedLevel:     a.forcedLevel,
 		minLevel:        a.minLevel,
 		includeLogLevel: a.includeLogLevel,
+		exit:            a.exit,
 	}
 }
 
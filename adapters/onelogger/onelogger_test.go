@@ -191,6 +191,24 @@ func TestFatalUsesExitFunc(t *testing.T) {
 	}
 }
 
+func TestNewFromLoggerFollowsSetExitFunc(t *testing.T) {
+	var exitCode int
+	previous := logport.SetExitFunc(func(code int) { exitCode = code })
+	defer logport.SetExitFunc(previous)
+
+	NewFromLogger(onelogpkg.New(&bytes.Buffer{}, onelogpkg.ALL)).Fatal("fatal")
+	if exitCode != 1 {
+		t.Fatalf("expected the process-wide exit function to receive 1, got %d", exitCode)
+	}
+
+	custom := onelogpkg.New(&bytes.Buffer{}, onelogpkg.ALL)
+	custom.ExitFn = func(code int) { exitCode = code + 10 }
+	NewFromLogger(custom).Fatal("fatal")
+	if exitCode != 11 {
+		t.Fatalf("expected the logger's own ExitFn to be kept, got %d", exitCode)
+	}
+}
+
 func TestPanicLogsBeforePanicking(t *testing.T) {
 	buf := &bytes.Buffer{}
 	logger := New(buf)
@@ -274,3 +292,26 @@ func decodeLines(t *testing.T, data []byte) []map[string]any {
 	}
 	return records
 }
+
+func TestFatalAndPanicFollowExitPolicy(t *testing.T) {
+	buf := &bytes.Buffer{}
+	code := 0
+	logger := NewWithOptions(buf, Options{
+		ExitFunc:    func(c int) { code = c },
+		PanicPolicy: logport.PanicContinue,
+	}).With("svc", "api")
+
+	logger.Fatal("shutting down")
+	if code != 1 {
+		t.Fatalf("expected ExitFunc to receive 1, got %d", code)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("shutting down")) {
+		t.Fatalf("expected fatal entry, got %s", buf.String())
+	}
+
+	buf.Reset()
+	logger.Panic("bad state")
+	if !bytes.Contains(buf.Bytes(), []byte(`"level":"error"`)) || !bytes.Contains(buf.Bytes(), []byte("bad state")) {
+		t.Fatalf("expected panic to be logged as error, got %s", buf.String())
+	}
+}
@@ -5,17 +5,32 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"os"
 	"strings"
 	"sync/atomic"
 	"time"
 
 	plog "github.com/phuslu/log"
+	"golang.org/x/term"
 	logport "pkt.systems/logport"
 )
 
+func init() {
+	logport.Register("phuslu", NewWithCommonOptions)
+}
+
 // Options configures the phuslu adapter prior to construction.
 type Options struct {
 	Configure func(*plog.Logger)
+
+	// ExitFunc, when non-nil, terminates the process after Fatal instead of
+	// the process-wide exit function. Registered exit hooks run first either
+	// way.
+	ExitFunc logport.ExitFunc
+
+	// PanicPolicy controls whether Panic panics after logging (the default)
+	// or logs at error level and continues.
+	PanicPolicy logport.PanicPolicy
 }
 
 // New builds a phuslu-backed adapter with default configuration.
@@ -32,7 +47,52 @@ func NewWithOptions(w io.Writer, opts Options) logport.ForLogging {
 	if opts.Configure != nil {
 		opts.Configure(logger)
 	}
-	return adapter{logger: logger}
+	return adapter{logger: logger, exit: logport.ExitPolicy{ExitFunc: opts.ExitFunc, PanicPolicy: opts.PanicPolicy}}
+}
+
+// NewWithCommonOptions builds a phuslu adapter from the cross-adapter
+// options, with a ConsoleWriter for console output. phuslu always writes a
+// timestamp and names its level and message fields "level" and "message",
+// so DisableTimestamp, LevelKey and MessageKey yield an
+// *logport.UnsupportedOptionError, as does Color set to ColorAlways for
+// structured output.
+func NewWithCommonOptions(w io.Writer, opts logport.CommonOptions) (logport.ForLogging, error) {
+	if opts.DisableTimestamp {
+		return nil, &logport.UnsupportedOptionError{Backend: "phuslu", Option: "DisableTimestamp", Reason: "every entry is timestamped"}
+	}
+	if err := opts.CheckFixedKeys("phuslu", "", "level", "message"); err != nil {
+		return nil, err
+	}
+	if opts.Structured && opts.Color == logport.ColorAlways {
+		return nil, &logport.UnsupportedOptionError{Backend: "phuslu", Option: "Color", Reason: "structured output is never colored"}
+	}
+	logger := NewWithOptions(w, Options{Configure: func(logger *plog.Logger) {
+		logger.TimeFormat = opts.TimeFormat
+		if opts.UTC {
+			logger.TimeLocation = time.UTC
+		}
+		if opts.Structured {
+			logger.TimeField = opts.TimeKey
+		} else if w != nil {
+			color := opts.Color == logport.ColorAlways || opts.Color == logport.ColorAuto && isTerminal(w)
+			logger.Writer = &plog.ConsoleWriter{Writer: w, ColorOutput: color}
+		}
+		if opts.MinLevel != nil {
+			logger.Level = plog.TraceLevel
+		}
+	}})
+	if opts.MinLevel != nil {
+		logger = logger.LogLevel(*opts.MinLevel)
+	}
+	if opts.AddSource {
+		logger = logport.AddSource(logger)
+	}
+	return logger, nil
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(interface{ Fd() uintptr })
+	return ok && term.IsTerminal(int(f.Fd()))
 }
 
 // NewFromLogger wraps an existing phuslu logger with the logport adapter.
@@ -51,6 +111,7 @@ type adapter struct {
 	groups          []string
 	forcedLevel     *logport.Level
 	includeLogLevel bool
+	exit            logport.ExitPolicy
 }
 
 func (a adapter) LogLevel(level logport.Level) logport.ForLogging {
@@ -59,11 +120,11 @@ func (a adapter) LogLevel(level logport.Level) logport.ForLogging {
 	}
 	if level == logport.NoLevel {
 		lvl := level
-		return adapter{logger: a.logger, baseKeyvals: a.baseKeyvals, groups: a.groups, forcedLevel: &lvl, includeLogLevel: a.includeLogLevel}
+		return adapter{logger: a.logger, baseKeyvals: a.baseKeyvals, groups: a.groups, forcedLevel: &lvl, includeLogLevel: a.includeLogLevel, exit: a.exit}
 	}
 	clone := *a.logger
 	clone.Level = portLevelToPhuslu(level)
-	return adapter{logger: &clone, baseKeyvals: a.baseKeyvals, groups: a.groups, includeLogLevel: a.includeLogLevel}
+	return adapter{logger: &clone, baseKeyvals: a.baseKeyvals, groups: a.groups, includeLogLevel: a.includeLogLevel, exit: a.exit}
 }
 
 func (a adapter) LogLevelFromEnv(key string) logport.ForLogging {
@@ -77,7 +138,7 @@ func (a adapter) WithLogLevel() logport.ForLogging {
 	if a.includeLogLevel {
 		return a
 	}
-	return adapter{logger: a.logger, baseKeyvals: a.baseKeyvals, groups: a.groups, forcedLevel: a.forcedLevel, includeLogLevel: true}
+	return adapter{logger: a.logger, baseKeyvals: a.baseKeyvals, groups: a.groups, forcedLevel: a.forcedLevel, includeLogLevel: true, exit: a.exit}
 }
 
 func (a adapter) Log(_ context.Context, level slog.Level, msg string, keyvals ...any) {
@@ -135,7 +196,7 @@ func (a adapter) With(keyvals ...any) logport.ForLogging {
 	base := make([]any, 0, len(a.baseKeyvals)+len(addition))
 	base = append(base, a.baseKeyvals...)
 	base = append(base, addition...)
-	return adapter{logger: a.logger, baseKeyvals: base, groups: a.groups, forcedLevel: a.forcedLevel, includeLogLevel: a.includeLogLevel}
+	return adapter{logger: a.logger, baseKeyvals: base, groups: a.groups, forcedLevel: a.forcedLevel, includeLogLevel: a.includeLogLevel, exit: a.exit}
 }
 
 func (a adapter) WithTrace(ctx context.Context) logport.ForLogging {
@@ -210,11 +271,16 @@ func (a adapter) Errorf(format string, args ...any) {
 	a.Error(formatMessage(format, args...))
 }
 
+// Fatal logs at FatalLevel and terminates through the adapter's ExitPolicy
+// rather than phuslu's own os.Exit, so exit hooks and ExitFunc apply.
 func (a adapter) Fatal(msg string, keyvals ...any) {
 	if a.logger == nil {
 		return
 	}
-	a.logEntry(a.logger.Fatal(), msg, keyvals)
+	logger := *a.logger
+	logger.Writer = exitHook{writer: logger.Writer}
+	a.logEntry(logger.Fatal(), msg, keyvals)
+	a.exit.Exit(1)
 }
 
 func (a adapter) Fatalf(format string, args ...any) {
@@ -222,6 +288,10 @@ func (a adapter) Fatalf(format string, args ...any) {
 }
 
 func (a adapter) Panic(msg string, keyvals ...any) {
+	if a.exit.PanicContinues() {
+		a.Error(msg, keyvals...)
+		return
+	}
 	if a.logger == nil {
 		panic(msg)
 	}
@@ -236,6 +306,25 @@ func (a adapter) Panicf(format string, args ...any) {
 	a.Panic(formatMessage(format, args...))
 }
 
+// exitHook stands in for the exit hook phuslu lacks: plog.Entry.Msg calls
+// os.Exit itself right after writing a FatalLevel entry. The entry reaches
+// the wrapped writer untouched, so level-routing and async writers see
+// FatalLevel; only once it is written is it marked ErrorLevel, which Msg
+// reads to skip its os.Exit and lets Fatal exit through the ExitPolicy.
+type exitHook struct {
+	writer plog.Writer
+}
+
+func (w exitHook) WriteEntry(e *plog.Entry) (int, error) {
+	writer := w.writer
+	if writer == nil {
+		writer = plog.IOWriter{Writer: os.Stderr}
+	}
+	n, err := writer.WriteEntry(e)
+	e.Level = plog.ErrorLevel
+	return n, err
+}
+
 func (a adapter) Trace(msg string, keyvals ...any) {
 	if a.logger == nil {
 		return
@@ -432,7 +521,7 @@ func (a adapter) WithAttrs(attrs []slog.Attr) slog.Handler {
 	base := make([]any, 0, len(a.baseKeyvals)+len(addition))
 	base = append(base, a.baseKeyvals...)
 	base = append(base, addition...)
-	return adapter{logger: a.logger, baseKeyvals: base, groups: a.groups, forcedLevel: a.forcedLevel, includeLogLevel: a.includeLogLevel}
+	return adapter{logger: a.logger, baseKeyvals: base, groups: a.groups, forcedLevel: a.forcedLevel, includeLogLevel: a.includeLogLevel, exit: a.exit}
 }
 
 func (a adapter) WithGroup(name string) slog.Handler {
@@ -440,7 +529,7 @@ func (a adapter) WithGroup(name string) slog.Handler {
 		return a
 	}
 	groups := appendGroup(a.groups, name)
-	return adapter{logger: a.logger, baseKeyvals: a.baseKeyvals, groups: groups, forcedLevel: a.forcedLevel, includeLogLevel: a.includeLogLevel}
+	return adapter{logger: a.logger, baseKeyvals: a.baseKeyvals, groups: groups, forcedLevel: a.forcedLevel, includeLogLevel: a.includeLogLevel, exit: a.exit}
 }
 
 func slogLevelToPhuslu(level slog.Level) plog.Level {
@@ -4,8 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"os"
+	"os/exec"
 	"testing"
 
 	plog "github.com/phuslu/log"
@@ -188,3 +192,84 @@ func decodeLogLine(t *testing.T, data []byte) map[string]any {
 	}
 	return record
 }
+
+func TestFatalAndPanicFollowExitPolicy(t *testing.T) {
+	buf := &bytes.Buffer{}
+	code := 0
+	logger := NewWithOptions(buf, Options{
+		ExitFunc:    func(c int) { code = c },
+		PanicPolicy: logport.PanicContinue,
+	}).With("svc", "api")
+
+	logger.Fatal("shutting down")
+	if code != 1 {
+		t.Fatalf("expected ExitFunc to receive 1, got %d", code)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"level":"fatal"`)) || !bytes.Contains(buf.Bytes(), []byte("shutting down")) {
+		t.Fatalf("expected fatal entry, got %s", buf.String())
+	}
+
+	buf.Reset()
+	logger.Panic("bad state")
+	if !bytes.Contains(buf.Bytes(), []byte(`"level":"error"`)) || !bytes.Contains(buf.Bytes(), []byte("bad state")) {
+		t.Fatalf("expected panic to be logged as error, got %s", buf.String())
+	}
+}
+
+func TestFatalReachesLevelRoutingWritersAsFatal(t *testing.T) {
+	info, errs := &bytes.Buffer{}, &bytes.Buffer{}
+	code := 0
+	logger := NewFromLogger(&plog.Logger{
+		Level: plog.InfoLevel,
+		Writer: &plog.MultiLevelWriter{
+			InfoWriter:  plog.IOWriter{Writer: info},
+			ErrorWriter: plog.IOWriter{Writer: errs},
+		},
+	})
+	previous := logport.SetExitFunc(func(c int) { code = c })
+	defer logport.SetExitFunc(previous)
+
+	logger.Fatal("shutting down")
+	if code != 1 {
+		t.Fatalf("expected the exit function to receive 1, got %d", code)
+	}
+	if !bytes.Contains(errs.Bytes(), []byte(`"level":"fatal"`)) {
+		t.Fatalf("expected the fatal entry on the error writer, got %q", errs.String())
+	}
+}
+
+// exitChildEnv marks the re-executed test binary that runs a real Fatal.
+const exitChildEnv = "LOGPORT_PHUSLU_EXIT_CHILD"
+
+// TestFatalExitsThroughExitFunc runs Fatal in a child process, where nothing
+// keeps phuslu from calling os.Exit(255) itself, and checks that the process
+// ends through the adapter's ExitFunc instead.
+func TestFatalExitsThroughExitFunc(t *testing.T) {
+	if os.Getenv(exitChildEnv) == "1" {
+		logger := NewFromLogger(&plog.Logger{
+			Level: plog.InfoLevel,
+			Writer: &plog.MultiLevelWriter{
+				InfoWriter:  plog.IOWriter{Writer: io.Discard},
+				ErrorWriter: plog.IOWriter{Writer: os.Stdout},
+			},
+		})
+		logport.SetExitFunc(func(code int) {
+			fmt.Println("exit func ran")
+			os.Exit(code + 2)
+		})
+		logger.Fatal("shutting down")
+		fmt.Println("fatal returned")
+		os.Exit(0)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFatalExitsThroughExitFunc$")
+	cmd.Env = append(os.Environ(), exitChildEnv+"=1")
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Fatalf("expected the child to exit with 3 through ExitFunc, got %v (output %q)", err, out)
+	}
+	if !bytes.Contains(out, []byte(`"level":"fatal"`)) || !bytes.Contains(out, []byte("exit func ran")) {
+		t.Fatalf("expected the fatal entry followed by ExitFunc, got %q", out)
+	}
+}
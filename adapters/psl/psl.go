@@ -11,6 +11,10 @@ import (
 	pslog "pkt.systems/pslog"
 )
 
+func init() {
+	logport.Register("psl", NewWithCommonOptions)
+}
+
 // Mode aliases pslog.Mode so existing code can continue using psl.Mode.
 type Mode = pslog.Mode
 
@@ -27,10 +31,13 @@ type Options struct {
 	TimeFormat       string
 	DisableTimestamp bool
 	NoColor          bool
+	ForceColor       bool
 	ColorJSON        bool
 	MinLevel         *logport.Level
 	VerboseFields    bool
 	UTC              bool
+	ExitFunc         logport.ExitFunc
+	PanicPolicy      logport.PanicPolicy
 }
 
 // New constructs a console logger backed by pslog.
@@ -61,7 +68,7 @@ func NewWithOptions(w io.Writer, opts Options) logport.ForLogging {
 	if opts.Mode == ModeStructured {
 		psOpts.Mode = pslog.ModeStructured
 	}
-	if opts.ColorJSON && opts.Mode == ModeStructured && !opts.NoColor {
+	if (opts.ForceColor || opts.ColorJSON && opts.Mode == ModeStructured) && !opts.NoColor {
 		psOpts.ForceColor = true
 	}
 	minLevel := logport.TraceLevel
@@ -74,9 +81,62 @@ func NewWithOptions(w io.Writer, opts Options) logport.ForLogging {
 	return adapter{
 		logger:   logger,
 		minLevel: minLevel,
+		exit:     logport.ExitPolicy{ExitFunc: opts.ExitFunc, PanicPolicy: opts.PanicPolicy},
 	}
 }
 
+// NewWithCommonOptions builds a psl adapter from the cross-adapter options.
+// pslog names its structured fields either ts/lvl/msg (the default) or, with
+// VerboseFields, time/level/message; TimeKey, LevelKey and MessageKey must
+// pick from one of those two sets or an *logport.UnsupportedOptionError is
+// returned.
+func NewWithCommonOptions(w io.Writer, opts logport.CommonOptions) (logport.ForLogging, error) {
+	pslOpts := Options{
+		Mode:             ModeConsole,
+		TimeFormat:       opts.TimeFormat,
+		DisableTimestamp: opts.DisableTimestamp,
+		NoColor:          opts.Color == logport.ColorNever,
+		ForceColor:       opts.Color == logport.ColorAlways,
+		MinLevel:         opts.MinLevel,
+		UTC:              opts.UTC,
+	}
+	if opts.Structured {
+		pslOpts.Mode = ModeStructured
+		verbose, ok := verboseKeys(opts.TimeKey, opts.LevelKey, opts.MessageKey)
+		if !ok {
+			return nil, &logport.UnsupportedOptionError{Backend: "psl", Option: "TimeKey/LevelKey/MessageKey", Reason: "pslog only writes ts/lvl/msg or time/level/message"}
+		}
+		pslOpts.VerboseFields = verbose
+	}
+	logger := NewWithOptions(w, pslOpts)
+	if opts.AddSource {
+		logger = logport.AddSource(logger)
+	}
+	return logger, nil
+}
+
+// verboseKeys reports whether the requested keys select pslog's verbose
+// field names, and whether they fit either naming at all.
+func verboseKeys(keys ...string) (verbose, ok bool) {
+	short, long := []string{"ts", "lvl", "msg"}, []string{"time", "level", "message"}
+	fitsShort, fitsLong, anySet := true, true, false
+	for i, key := range keys {
+		if key == "" {
+			continue
+		}
+		anySet = true
+		fitsShort = fitsShort && key == short[i]
+		fitsLong = fitsLong && key == long[i]
+	}
+	switch {
+	case !anySet || fitsShort:
+		return false, true
+	case fitsLong:
+		return true, true
+	}
+	return false, false
+}
+
 // ContextWithLogger stores a logger built from the supplied options inside ctx.
 func ContextWithLogger(ctx context.Context, w io.Writer, opts Options) context.Context {
 	return logport.ContextWithLogger(ctx, NewWithOptions(w, opts))
@@ -87,6 +147,7 @@ type adapter struct {
 	minLevel    logport.Level
 	forcedLevel *logport.Level
 	groups      []string
+	exit        logport.ExitPolicy
 }
 
 func (a adapter) LogLevelFromEnv(key string) logport.ForLogging {
@@ -100,6 +161,7 @@ func (a adapter) LogLevel(level logport.Level) logport.ForLogging {
 	next := adapter{
 		logger: a.logger.LogLevel(toPslogLevel(level)),
 		groups: cloneGroups(a.groups),
+		exit:   a.exit,
 	}
 	switch level {
 	case logport.Disabled, logport.NoLevel:
@@ -118,6 +180,7 @@ func (a adapter) WithLogLevel() logport.ForLogging {
 		minLevel:    a.minLevel,
 		forcedLevel: cloneForced(a.forcedLevel),
 		groups:      cloneGroups(a.groups),
+		exit:        a.exit,
 	}
 }
 
@@ -125,7 +188,7 @@ func (a adapter) With(keyvals ...any) logport.ForLogging {
 	if len(keyvals) == 0 {
 		return a
 	}
-	promoted := promoteStaticKeyvals(keyvals)
+	promoted := promoteStaticKeyvals(flattenAttrKeyvals(keyvals))
 	if len(promoted) == 0 {
 		return a
 	}
@@ -134,6 +197,7 @@ func (a adapter) With(keyvals ...any) logport.ForLogging {
 		minLevel:    a.minLevel,
 		forcedLevel: cloneForced(a.forcedLevel),
 		groups:      cloneGroups(a.groups),
+		exit:        a.exit,
 	}
 }
 
@@ -150,6 +214,7 @@ func (a adapter) Log(ctx context.Context, level slog.Level, msg string, keyvals
 }
 
 func (a adapter) Logp(level logport.Level, msg string, keyvals ...any) {
+	keyvals = flattenAttrKeyvals(keyvals)
 	switch level {
 	case logport.Disabled:
 		return
@@ -164,9 +229,9 @@ func (a adapter) Logp(level logport.Level, msg string, keyvals ...any) {
 	case logport.ErrorLevel:
 		a.logger.Error(msg, keyvals...)
 	case logport.FatalLevel:
-		a.logger.Fatal(msg, keyvals...)
+		a.Fatal(msg, keyvals...)
 	case logport.PanicLevel:
-		a.logger.Panic(msg, keyvals...)
+		a.Panic(msg, keyvals...)
 	case logport.NoLevel:
 		a.logger.Log(pslog.NoLevel, msg, keyvals...)
 	default:
@@ -186,12 +251,25 @@ func (a adapter) Logf(level logport.Level, format string, args ...any) {
 	a.Logp(level, formatMessage(format, args...))
 }
 
-func (a adapter) Debug(msg string, keyvals ...any) { a.logger.Debug(msg, keyvals...) }
-func (a adapter) Info(msg string, keyvals ...any)  { a.logger.Info(msg, keyvals...) }
-func (a adapter) Warn(msg string, keyvals ...any)  { a.logger.Warn(msg, keyvals...) }
-func (a adapter) Error(msg string, keyvals ...any) { a.logger.Error(msg, keyvals...) }
+func (a adapter) Debug(msg string, keyvals ...any) {
+	a.logger.Debug(msg, flattenAttrKeyvals(keyvals)...)
+}
 
-func (a adapter) Trace(msg string, keyvals ...any) { a.logger.Trace(msg, keyvals...) }
+func (a adapter) Info(msg string, keyvals ...any) {
+	a.logger.Info(msg, flattenAttrKeyvals(keyvals)...)
+}
+
+func (a adapter) Warn(msg string, keyvals ...any) {
+	a.logger.Warn(msg, flattenAttrKeyvals(keyvals)...)
+}
+
+func (a adapter) Error(msg string, keyvals ...any) {
+	a.logger.Error(msg, flattenAttrKeyvals(keyvals)...)
+}
+
+func (a adapter) Trace(msg string, keyvals ...any) {
+	a.logger.Trace(msg, flattenAttrKeyvals(keyvals)...)
+}
 
 func (a adapter) Debugf(format string, args ...any) { a.logger.Debug(formatMessage(format, args...)) }
 func (a adapter) Infof(format string, args ...any)  { a.logger.Info(formatMessage(format, args...)) }
@@ -199,20 +277,29 @@ func (a adapter) Warnf(format string, args ...any)  { a.logger.Warn(formatMessag
 func (a adapter) Errorf(format string, args ...any) { a.logger.Error(formatMessage(format, args...)) }
 func (a adapter) Tracef(format string, args ...any) { a.logger.Trace(formatMessage(format, args...)) }
 
+// Fatal logs at FatalLevel and terminates through the adapter's ExitPolicy
+// rather than pslog's own os.Exit, so exit hooks and ExitFunc apply.
 func (a adapter) Fatal(msg string, keyvals ...any) {
-	a.logger.Fatal(msg, keyvals...)
+	keyvals = flattenAttrKeyvals(keyvals)
+	a.logger.Log(pslog.FatalLevel, msg, keyvals...)
+	a.exit.Exit(1)
 }
 
 func (a adapter) Fatalf(format string, args ...any) {
-	a.logger.Fatal(formatMessage(format, args...))
+	a.Fatal(formatMessage(format, args...))
 }
 
 func (a adapter) Panic(msg string, keyvals ...any) {
+	keyvals = flattenAttrKeyvals(keyvals)
+	if a.exit.PanicContinues() {
+		a.logger.Error(msg, keyvals...)
+		return
+	}
 	a.logger.Panic(msg, keyvals...)
 }
 
 func (a adapter) Panicf(format string, args ...any) {
-	a.logger.Panic(formatMessage(format, args...))
+	a.Panic(formatMessage(format, args...))
 }
 
 func (a adapter) Write(p []byte) (int, error) {
@@ -243,6 +330,7 @@ func (a adapter) WithAttrs(attrs []slog.Attr) slog.Handler {
 		minLevel:    a.minLevel,
 		forcedLevel: cloneForced(a.forcedLevel),
 		groups:      cloneGroups(a.groups),
+		exit:        a.exit,
 	}
 }
 
@@ -255,6 +343,7 @@ func (a adapter) WithGroup(name string) slog.Handler {
 		minLevel:    a.minLevel,
 		forcedLevel: cloneForced(a.forcedLevel),
 		groups:      appendGroup(a.groups, name),
+		exit:        a.exit,
 	}
 }
 
@@ -361,6 +450,38 @@ func joinAttrKey(groups []string, key string) string {
 	return strings.Join(parts, ".")
 }
 
+// flattenAttrKeyvals expands slog.Attr arguments into key/value pairs, joining
+// group keys with "." as Handle does. pslog treats an Attr in keyvals as a
+// value of its own. keyvals is returned as is when it holds no Attr.
+func flattenAttrKeyvals(keyvals []any) []any {
+	var out []any
+	for i := 0; i < len(keyvals); i++ {
+		switch kv := keyvals[i].(type) {
+		case slog.Attr:
+			if out == nil {
+				out = append(make([]any, 0, len(keyvals)+1), keyvals[:i]...)
+			}
+			out = appendAttrKeyvals(out, kv, nil)
+		case string:
+			if out != nil {
+				out = append(out, kv)
+				if i+1 < len(keyvals) {
+					out = append(out, keyvals[i+1])
+				}
+			}
+			i++
+		default:
+			if out != nil {
+				out = append(out, kv)
+			}
+		}
+	}
+	if out == nil {
+		return keyvals
+	}
+	return out
+}
+
 func promoteStaticKeyvals(keyvals []any) []any {
 	if len(keyvals) == 0 {
 		return nil
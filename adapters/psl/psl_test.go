@@ -104,6 +104,31 @@ func TestSlogWithGroup(t *testing.T) {
 	}
 }
 
+func TestAttrKeyvalsAreFlattened(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewWithOptions(buf, Options{Mode: ModeStructured, DisableTimestamp: true, NoColor: true})
+
+	logger.With(slog.Group("svc", slog.String("name", "api"))).Logp(logport.InfoLevel, "grouped",
+		"attempt", 2,
+		slog.Group("req", slog.String("id", "abc"), slog.Group("peer", slog.String("ip", "10.0.0.1"))),
+		slog.Bool("cached", true),
+	)
+
+	record := decodeLastJSONLine(t, buf.Bytes())
+	want := map[string]any{
+		"svc.name":    "api",
+		"attempt":     float64(2),
+		"req.id":      "abc",
+		"req.peer.ip": "10.0.0.1",
+		"cached":      true,
+	}
+	for key, value := range want {
+		if got := record[key]; got != value {
+			t.Fatalf("expected %s=%v, got %v", key, value, record)
+		}
+	}
+}
+
 func TestWithTraceAddsTraceIDs(t *testing.T) {
 	buf := &bytes.Buffer{}
 	logger := NewWithOptions(buf, Options{Mode: ModeStructured, DisableTimestamp: true, NoColor: true})
@@ -144,3 +169,29 @@ func decodeLastJSONLine(t *testing.T, data []byte) map[string]any {
 	}
 	return record
 }
+
+func TestFatalAndPanicFollowExitPolicy(t *testing.T) {
+	buf := &bytes.Buffer{}
+	code := 0
+	logger := NewWithOptions(buf, Options{
+		Mode:             ModeStructured,
+		DisableTimestamp: true,
+		NoColor:          true,
+		ExitFunc:         func(c int) { code = c },
+		PanicPolicy:      logport.PanicContinue,
+	}).With("svc", "api")
+
+	logger.Fatal("shutting down")
+	if code != 1 {
+		t.Fatalf("expected ExitFunc to receive 1, got %d", code)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"msg":"shutting down"`)) {
+		t.Fatalf("expected fatal entry, got %s", buf.String())
+	}
+
+	buf.Reset()
+	logger.Panic("bad state")
+	if !bytes.Contains(buf.Bytes(), []byte(`"lvl":"error"`)) || !bytes.Contains(buf.Bytes(), []byte("bad state")) {
+		t.Fatalf("expected panic to be logged as error, got %s", buf.String())
+	}
+}
@@ -5,17 +5,22 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
-	"os"
 
 	logport "pkt.systems/logport"
 )
 
+func init() {
+	logport.Register("slog", NewWithCommonOptions)
+}
+
 // Options configures the slog adapter when constructing a logger.
 type Options struct {
 	Handler        slog.Handler
 	HandlerOptions slog.HandlerOptions
 	JSON           bool
 	MinLevel       *logport.Level
+	ExitFunc       logport.ExitFunc
+	PanicPolicy    logport.PanicPolicy
 }
 
 // New returns a slog adapter that emits text output to w.
@@ -59,7 +64,64 @@ func NewWithOptions(w io.Writer, opts Options) logport.ForLogging {
 	if opts.MinLevel != nil {
 		min = *opts.MinLevel
 	}
-	return newAdapter(slog.New(handler), handler, min)
+	return adapter{
+		logger:   slog.New(handler),
+		handler:  handler,
+		minLevel: min,
+		exit:     logport.ExitPolicy{ExitFunc: opts.ExitFunc, PanicPolicy: opts.PanicPolicy},
+	}
+}
+
+// NewWithCommonOptions builds a slog adapter from the cross-adapter options,
+// with slog.JSONHandler for structured and slog.TextHandler for console
+// output. Neither handler colors, so Color set to ColorAlways yields an
+// *logport.UnsupportedOptionError.
+func NewWithCommonOptions(w io.Writer, opts logport.CommonOptions) (logport.ForLogging, error) {
+	if opts.Color == logport.ColorAlways {
+		return nil, &logport.UnsupportedOptionError{Backend: "slog", Option: "Color", Reason: "log/slog handlers do not color"}
+	}
+	slogOpts := Options{JSON: opts.Structured, MinLevel: opts.MinLevel}
+	if opts.MinLevel != nil {
+		slogOpts.HandlerOptions.Level = slog.LevelDebug - 4
+	}
+	renames := map[string]string{}
+	if opts.Structured {
+		for from, to := range map[string]string{slog.TimeKey: opts.TimeKey, slog.LevelKey: opts.LevelKey, slog.MessageKey: opts.MessageKey} {
+			if to != "" && to != from {
+				renames[from] = to
+			}
+		}
+	}
+	adjustTime := opts.DisableTimestamp || opts.TimeFormat != "" || opts.UTC
+	if adjustTime || len(renames) > 0 {
+		slogOpts.HandlerOptions.ReplaceAttr = func(groups []string, attr slog.Attr) slog.Attr {
+			if len(groups) > 0 {
+				return attr
+			}
+			if attr.Key == slog.TimeKey && attr.Value.Kind() == slog.KindTime && adjustTime {
+				if opts.DisableTimestamp {
+					return slog.Attr{}
+				}
+				t := attr.Value.Time()
+				if opts.UTC {
+					t = t.UTC()
+				}
+				attr.Value = slog.TimeValue(t)
+				if opts.TimeFormat != "" {
+					attr.Value = slog.StringValue(t.Format(opts.TimeFormat))
+				}
+			}
+			if to, ok := renames[attr.Key]; ok {
+				attr.Key = to
+			}
+			return attr
+		}
+	}
+	logger := NewWithOptions(w, slogOpts)
+	if opts.AddSource {
+		logger = logport.AddSource(logger)
+	}
+	return logger, nil
 }
 
 // ContextWithLogger stores a configured slog adapter inside the context.
@@ -73,6 +135,7 @@ type adapter struct {
 	forcedLevel     *logport.Level
 	minLevel        logport.Level
 	includeLogLevel bool
+	exit            logport.ExitPolicy
 }
 
 func newAdapter(logger *slog.Logger, handler slog.Handler, min logport.Level) logport.ForLogging {
@@ -88,9 +151,9 @@ func newAdapter(logger *slog.Logger, handler slog.Handler, min logport.Level) lo
 func (a adapter) LogLevel(level logport.Level) logport.ForLogging {
 	if level == logport.NoLevel {
 		lvl := level
-		return adapter{logger: a.logger, handler: a.handler, forcedLevel: &lvl, minLevel: a.minLevel, includeLogLevel: a.includeLogLevel}
+		return adapter{logger: a.logger, handler: a.handler, forcedLevel: &lvl, minLevel: a.minLevel, includeLogLevel: a.includeLogLevel, exit: a.exit}
 	}
-	return adapter{logger: a.logger, handler: a.handler, minLevel: level, includeLogLevel: a.includeLogLevel}
+	return adapter{logger: a.logger, handler: a.handler, minLevel: level, includeLogLevel: a.includeLogLevel, exit: a.exit}
 }
 
 func (a adapter) LogLevelFromEnv(key string) logport.ForLogging {
@@ -104,7 +167,7 @@ func (a adapter) WithLogLevel() logport.ForLogging {
 	if a.includeLogLevel {
 		return a
 	}
-	return adapter{logger: a.logger, handler: a.handler, forcedLevel: a.forcedLevel, minLevel: a.minLevel, includeLogLevel: true}
+	return adapter{logger: a.logger, handler: a.handler, forcedLevel: a.forcedLevel, minLevel: a.minLevel, includeLogLevel: true, exit: a.exit}
 }
 
 func (a adapter) With(keyvals ...any) logport.ForLogging {
@@ -112,7 +175,7 @@ func (a adapter) With(keyvals ...any) logport.ForLogging {
 		return a
 	}
 	next := a.logger.With(keyvals...)
-	return adapter{logger: next, handler: next.Handler(), forcedLevel: a.forcedLevel, minLevel: a.minLevel, includeLogLevel: a.includeLogLevel}
+	return adapter{logger: next, handler: next.Handler(), forcedLevel: a.forcedLevel, minLevel: a.minLevel, includeLogLevel: a.includeLogLevel, exit: a.exit}
 }
 
 func (a adapter) WithTrace(ctx context.Context) logport.ForLogging {
@@ -161,10 +224,14 @@ func (a adapter) Error(msg string, keyvals ...any) { a.Logp(logport.ErrorLevel,
 
 func (a adapter) Fatal(msg string, keyvals ...any) {
 	a.Logp(logport.FatalLevel, msg, keyvals...)
-	os.Exit(1)
+	a.exit.Exit(1)
 }
 
 func (a adapter) Panic(msg string, keyvals ...any) {
+	if a.exit.PanicContinues() {
+		a.Logp(logport.ErrorLevel, msg, keyvals...)
+		return
+	}
 	a.Logp(logport.PanicLevel, msg, keyvals...)
 	panic(msg)
 }
@@ -250,7 +317,7 @@ func (a adapter) WithAttrs(attrs []slog.Attr) slog.Handler {
 		return a
 	}
 	next := a.handler.WithAttrs(attrs)
-	return adapter{logger: slog.New(next), handler: next, forcedLevel: a.forcedLevel, minLevel: a.minLevel, includeLogLevel: a.includeLogLevel}
+	return adapter{logger: slog.New(next), handler: next, forcedLevel: a.forcedLevel, minLevel: a.minLevel, includeLogLevel: a.includeLogLevel, exit: a.exit}
 }
 
 func (a adapter) WithGroup(name string) slog.Handler {
@@ -258,7 +325,7 @@ func (a adapter) WithGroup(name string) slog.Handler {
 		return a
 	}
 	next := a.handler.WithGroup(name)
-	return adapter{logger: slog.New(next), handler: next, forcedLevel: a.forcedLevel, minLevel: a.minLevel, includeLogLevel: a.includeLogLevel}
+	return adapter{logger: slog.New(next), handler: next, forcedLevel: a.forcedLevel, minLevel: a.minLevel, includeLogLevel: a.includeLogLevel, exit: a.exit}
 }
 
 func portLevelToSlog(level logport.Level) slog.Level {
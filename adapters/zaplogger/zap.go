@@ -6,18 +6,25 @@ import (
 	"io"
 	"log/slog"
 	"strings"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"golang.org/x/term"
 	logport "pkt.systems/logport"
 )
 
+func init() {
+	logport.Register("zap", NewWithCommonOptions)
+}
+
 type adapter struct {
 	logger          *zap.Logger
 	groups          []string
 	minLevel        *zapcore.Level
 	configuredLevel *logport.Level
 	includeLogLevel bool
+	exit            logport.ExitPolicy
 }
 
 // Options controls zap-backed adapter configuration.
@@ -44,6 +51,15 @@ type Options struct {
 	// logger the adapter should use. This makes it easy to apply tweaks such as
 	// Named or WithOptions.
 	Configure func(*zap.Logger) *zap.Logger
+
+	// ExitFunc, when non-nil, terminates the process after Fatal instead of
+	// the process-wide exit function. Registered exit hooks run first either
+	// way.
+	ExitFunc logport.ExitFunc
+
+	// PanicPolicy controls whether Panic panics after logging (the default)
+	// or logs at error level and continues.
+	PanicPolicy logport.PanicPolicy
 }
 
 // New returns a zap-backed ForLogging implementation that writes JSON logs to
@@ -59,7 +75,69 @@ func NewWithOptions(w io.Writer, opts Options) logport.ForLogging {
 	if logger == nil {
 		return logport.NoopLogger()
 	}
-	return adapter{logger: logger}
+	return adapter{logger: logger, exit: logport.ExitPolicy{ExitFunc: opts.ExitFunc, PanicPolicy: opts.PanicPolicy}}
+}
+
+// NewWithCommonOptions builds a zap adapter from the cross-adapter options.
+// Structured output uses zap's production JSON encoder and console output its
+// development console encoder. Color set to ColorAlways with structured
+// output yields an *logport.UnsupportedOptionError.
+func NewWithCommonOptions(w io.Writer, opts logport.CommonOptions) (logport.ForLogging, error) {
+	encoderConfig := zap.NewDevelopmentEncoderConfig()
+	if opts.Structured {
+		if opts.Color == logport.ColorAlways {
+			return nil, &logport.UnsupportedOptionError{Backend: "zap", Option: "Color", Reason: "structured output is never colored"}
+		}
+		encoderConfig = zap.NewProductionEncoderConfig()
+		if opts.TimeKey != "" {
+			encoderConfig.TimeKey = opts.TimeKey
+		}
+		if opts.LevelKey != "" {
+			encoderConfig.LevelKey = opts.LevelKey
+		}
+		if opts.MessageKey != "" {
+			encoderConfig.MessageKey = opts.MessageKey
+		}
+	} else if opts.Color == logport.ColorAlways || opts.Color == logport.ColorAuto && isTerminal(w) {
+		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	}
+	layout := opts.TimeFormat
+	if layout == "" && opts.UTC && !opts.Structured {
+		// The production encoder writes epoch seconds, which have no zone.
+		layout = "2006-01-02T15:04:05.000Z0700"
+	}
+	if layout != "" {
+		utc := opts.UTC
+		encoderConfig.EncodeTime = func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+			if utc {
+				t = t.UTC()
+			}
+			enc.AppendString(t.Format(layout))
+		}
+	}
+	if opts.DisableTimestamp {
+		encoderConfig.TimeKey = ""
+	}
+	zapOpts := Options{Encoder: zapcore.NewConsoleEncoder(encoderConfig)}
+	if opts.Structured {
+		zapOpts.Encoder = zapcore.NewJSONEncoder(encoderConfig)
+	}
+	if opts.MinLevel != nil {
+		zapOpts.Level = zapcore.DebugLevel
+	}
+	logger := NewWithOptions(w, zapOpts)
+	if opts.MinLevel != nil {
+		logger = logger.LogLevel(*opts.MinLevel)
+	}
+	if opts.AddSource {
+		logger = logport.AddSource(logger)
+	}
+	return logger, nil
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(interface{ Fd() uintptr })
+	return ok && term.IsTerminal(int(f.Fd()))
 }
 
 // NewFromLogger wraps an existing zap.Logger so it satisfies logport.ForLogging.
@@ -111,7 +189,7 @@ func (a adapter) With(keyvals ...any) logport.ForLogging {
 	if len(fields) == 0 {
 		return a
 	}
-	return adapter{logger: a.logger.With(fields...), groups: a.groups, minLevel: a.minLevel, configuredLevel: a.configuredLevel, includeLogLevel: a.includeLogLevel}
+	return adapter{logger: a.logger.With(fields...), groups: a.groups, minLevel: a.minLevel, configuredLevel: a.configuredLevel, includeLogLevel: a.includeLogLevel, exit: a.exit}
 }
 
 func (a adapter) WithTrace(ctx context.Context) logport.ForLogging {
@@ -136,18 +214,18 @@ func (a adapter) LogLevel(level logport.Level) logport.ForLogging {
 	if level == logport.NoLevel {
 		lvl := zapcore.DebugLevel
 		configured := level
-		return adapter{logger: a.logger, groups: a.groups, minLevel: &lvl, configuredLevel: &configured, includeLogLevel: a.includeLogLevel}
+		return adapter{logger: a.logger, groups: a.groups, minLevel: &lvl, configuredLevel: &configured, includeLogLevel: a.includeLogLevel, exit: a.exit}
 	}
 	zapLevel := portLevelToZap(level)
 	configured := level
-	return adapter{logger: a.logger, groups: a.groups, minLevel: &zapLevel, configuredLevel: &configured, includeLogLevel: a.includeLogLevel}
+	return adapter{logger: a.logger, groups: a.groups, minLevel: &zapLevel, configuredLevel: &configured, includeLogLevel: a.includeLogLevel, exit: a.exit}
 }
 
 func (a adapter) WithLogLevel() logport.ForLogging {
 	if a.includeLogLevel {
 		return a
 	}
-	return adapter{logger: a.logger, groups: a.groups, minLevel: a.minLevel, configuredLevel: a.configuredLevel, includeLogLevel: true}
+	return adapter{logger: a.logger, groups: a.groups, minLevel: a.minLevel, configuredLevel: a.configuredLevel, includeLogLevel: true, exit: a.exit}
 }
 
 func (a adapter) Log(ctx context.Context, level slog.Level, msg string, keyvals ...any) {
@@ -253,13 +331,16 @@ func (a adapter) Errorf(format string, args ...any) {
 	a.Error(formatMessage(format, args...))
 }
 
+// Fatal logs at FatalLevel and terminates through the adapter's ExitPolicy,
+// which replaces any fatal hook configured on the zap logger so exit hooks and
+// ExitFunc apply.
 func (a adapter) Fatal(msg string, keyvals ...any) {
 	if a.logger == nil {
 		return
 	}
 	fields := keyvalsToFields(a.groups, keyvals)
 	fields = a.appendLogLevelField(fields)
-	a.logger.Fatal(msg, fields...)
+	a.logger.WithOptions(zap.WithFatalHook(fatalHook{exit: a.exit})).Fatal(msg, fields...)
 }
 
 func (a adapter) Fatalf(format string, args ...any) {
@@ -267,6 +348,10 @@ func (a adapter) Fatalf(format string, args ...any) {
 }
 
 func (a adapter) Panic(msg string, keyvals ...any) {
+	if a.exit.PanicContinues() {
+		a.Error(msg, keyvals...)
+		return
+	}
 	if a.logger == nil {
 		panic(msg)
 	}
@@ -279,6 +364,15 @@ func (a adapter) Panicf(format string, args ...any) {
 	a.Panic(formatMessage(format, args...))
 }
 
+// fatalHook routes zap's post-write fatal handling through logport.
+type fatalHook struct {
+	exit logport.ExitPolicy
+}
+
+func (h fatalHook) OnWrite(*zapcore.CheckedEntry, []zapcore.Field) {
+	h.exit.Exit(1)
+}
+
 func (a adapter) Trace(msg string, keyvals ...any) {
 	if a.logger == nil {
 		return
@@ -334,14 +428,14 @@ func (a adapter) WithAttrs(attrs []slog.Attr) slog.Handler {
 	if len(fields) == 0 {
 		return a
 	}
-	return adapter{logger: a.logger.With(fields...), groups: a.groups, minLevel: a.minLevel, configuredLevel: a.configuredLevel, includeLogLevel: a.includeLogLevel}
+	return adapter{logger: a.logger.With(fields...), groups: a.groups, minLevel: a.minLevel, configuredLevel: a.configuredLevel, includeLogLevel: a.includeLogLevel, exit: a.exit}
 }
 
 func (a adapter) WithGroup(name string) slog.Handler {
 	if name == "" {
 		return a
 	}
-	return adapter{logger: a.logger, groups: appendGroup(a.groups, name), minLevel: a.minLevel, configuredLevel: a.configuredLevel, includeLogLevel: a.includeLogLevel}
+	return adapter{logger: a.logger, groups: appendGroup(a.groups, name), minLevel: a.minLevel, configuredLevel: a.configuredLevel, includeLogLevel: a.includeLogLevel, exit: a.exit}
 }
 
 func slogLevelToZap(level slog.Level) zapcore.Level {
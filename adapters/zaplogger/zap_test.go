@@ -116,3 +116,26 @@ func TestZapAdapterSupportsSlogHandler(t *testing.T) {
 		t.Fatalf("expected grouped attr from slog, got %q", got)
 	}
 }
+
+func TestFatalAndPanicFollowExitPolicy(t *testing.T) {
+	buf := &bytes.Buffer{}
+	code := 0
+	opts := testOptions()
+	opts.ExitFunc = func(c int) { code = c }
+	opts.PanicPolicy = logport.PanicContinue
+	logger := NewWithOptions(buf, opts).With("svc", "api")
+
+	logger.Fatal("shutting down")
+	if code != 1 {
+		t.Fatalf("expected ExitFunc to receive 1, got %d", code)
+	}
+	if !strings.Contains(buf.String(), "FATAL") || !strings.Contains(buf.String(), "shutting down") {
+		t.Fatalf("expected fatal entry, got %s", buf.String())
+	}
+
+	buf.Reset()
+	logger.Panic("bad state")
+	if !strings.Contains(buf.String(), "ERROR") || !strings.Contains(buf.String(), "bad state") {
+		t.Fatalf("expected panic to be logged as error, got %s", buf.String())
+	}
+}
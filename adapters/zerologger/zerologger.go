@@ -14,11 +14,16 @@ import (
 	logport "pkt.systems/logport"
 )
 
+func init() {
+	logport.Register("zerolog", NewWithCommonOptions)
+}
+
 type adapter struct {
 	logger          zerolog.Logger
 	groups          []string
 	forcedLevel     *logport.Level
 	includeLogLevel bool
+	exit            logport.ExitPolicy
 }
 
 // Options controls how the zerolog adapter formats log output.
@@ -47,18 +52,30 @@ type Options struct {
 	// zerolog's console writer. When true, ConfigureWriter is ignored and the
 	// adapter emits zerolog JSON events directly.
 	Structured bool
+
+	// ExitFunc, when non-nil, terminates the process after Fatal instead of
+	// the process-wide exit function. Registered exit hooks run first either
+	// way.
+	ExitFunc logport.ExitFunc
+
+	// PanicPolicy controls whether Panic panics after logging (the default)
+	// or logs at error level and continues.
+	PanicPolicy logport.PanicPolicy
 }
 
-// optionsJSON mirrors Options for JSON serialization without the ConfigureWriter.
+// optionsJSON mirrors Options for JSON serialization without the ConfigureWriter
+// and ExitFunc.
 type optionsJSON struct {
-	Level            *zerolog.Level `json:"level,omitempty"`
-	NoColor          bool           `json:"noColor,omitempty"`
-	TimeFormat       string         `json:"timeFormat,omitempty"`
-	DisableTimestamp bool           `json:"disableTimestamp,omitempty"`
-	Structured       bool           `json:"structured,omitempty"`
+	Level            *zerolog.Level      `json:"level,omitempty"`
+	NoColor          bool                `json:"noColor,omitempty"`
+	TimeFormat       string              `json:"timeFormat,omitempty"`
+	DisableTimestamp bool                `json:"disableTimestamp,omitempty"`
+	Structured       bool                `json:"structured,omitempty"`
+	PanicPolicy      logport.PanicPolicy `json:"panicPolicy,omitempty"`
 }
 
-// MarshalJSON supports encoding Options while omitting ConfigureWriter.
+// MarshalJSON supports encoding Options while omitting ConfigureWriter and
+// ExitFunc.
 func (o Options) MarshalJSON() ([]byte, error) {
 	return json.Marshal(optionsJSON{
 		Level:            o.Level,
@@ -66,10 +83,12 @@ func (o Options) MarshalJSON() ([]byte, error) {
 		TimeFormat:       o.TimeFormat,
 		DisableTimestamp: o.DisableTimestamp,
 		Structured:       o.Structured,
+		PanicPolicy:      o.PanicPolicy,
 	})
 }
 
-// UnmarshalJSON restores Options from JSON while leaving ConfigureWriter unset.
+// UnmarshalJSON restores Options from JSON while leaving ConfigureWriter and
+// ExitFunc unset.
 func (o *Options) UnmarshalJSON(data []byte) error {
 	var aux optionsJSON
 	if err := json.Unmarshal(data, &aux); err != nil {
@@ -80,7 +99,9 @@ func (o *Options) UnmarshalJSON(data []byte) error {
 	o.TimeFormat = aux.TimeFormat
 	o.DisableTimestamp = aux.DisableTimestamp
 	o.Structured = aux.Structured
+	o.PanicPolicy = aux.PanicPolicy
 	o.ConfigureWriter = nil
+	o.ExitFunc = nil
 	return nil
 }
 
@@ -105,6 +126,7 @@ func NewFromLogger(logger zerolog.Logger) logport.ForLogging {
 // supplied writer and options. The default behaviour is console-friendly output;
 // set Options.Structured to true for JSON emission.
 func NewWithOptions(w io.Writer, o Options) logport.ForLogging {
+	exit := logport.ExitPolicy{ExitFunc: o.ExitFunc, PanicPolicy: o.PanicPolicy}
 	useConsole := !o.Structured
 	if useConsole {
 		if o.TimeFormat == "" {
@@ -129,7 +151,7 @@ func NewWithOptions(w io.Writer, o Options) logport.ForLogging {
 		if o.Level != nil {
 			logger = logger.Level(*o.Level)
 		}
-		return adapter{logger: logger}
+		return adapter{logger: logger, exit: exit}
 	}
 
 	logger := zerolog.New(w)
@@ -139,7 +161,78 @@ func NewWithOptions(w io.Writer, o Options) logport.ForLogging {
 	if o.Level != nil {
 		logger = logger.Level(*o.Level)
 	}
-	return adapter{logger: logger}
+	return adapter{logger: logger, exit: exit}
+}
+
+// NewWithCommonOptions builds a zerolog adapter from the cross-adapter
+// options. zerolog's level and message field names are package globals, so
+// LevelKey and MessageKey other than "level" and "message" yield an
+// *logport.UnsupportedOptionError, as does Color set to ColorAlways for
+// structured output.
+func NewWithCommonOptions(w io.Writer, opts logport.CommonOptions) (logport.ForLogging, error) {
+	if err := opts.CheckFixedKeys("zerolog", "", zerolog.LevelFieldName, zerolog.MessageFieldName); err != nil {
+		return nil, err
+	}
+	var logger logport.ForLogging
+	if opts.Structured {
+		if opts.Color == logport.ColorAlways {
+			return nil, &logport.UnsupportedOptionError{Backend: "zerolog", Option: "Color", Reason: "structured output is never colored"}
+		}
+		zl := zerolog.New(w)
+		switch {
+		case opts.DisableTimestamp:
+		case opts.TimeKey == "" && opts.TimeFormat == "" && !opts.UTC:
+			zl = zl.With().Timestamp().Logger()
+		default:
+			zl = zl.Hook(timestampHook{key: opts.TimeKey, format: opts.TimeFormat, utc: opts.UTC})
+		}
+		logger = NewFromLogger(zl)
+	} else {
+		logger = NewWithOptions(w, Options{
+			NoColor:          opts.Color == logport.ColorNever,
+			TimeFormat:       opts.TimeFormat,
+			DisableTimestamp: opts.DisableTimestamp,
+			ConfigureWriter: func(cw *zerolog.ConsoleWriter) {
+				if opts.Color == logport.ColorAlways {
+					cw.NoColor = false
+				}
+				if opts.UTC {
+					cw.TimeLocation = time.UTC
+				}
+			},
+		})
+	}
+	if opts.MinLevel != nil {
+		logger = logger.LogLevel(*opts.MinLevel)
+	}
+	if opts.AddSource {
+		logger = logport.AddSource(logger)
+	}
+	return logger, nil
+}
+
+// timestampHook stamps events like zerolog's Timestamp but with its own
+// field name, layout and zone instead of the package globals.
+type timestampHook struct {
+	key    string
+	format string
+	utc    bool
+}
+
+func (h timestampHook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	now := time.Now()
+	if h.utc {
+		now = now.UTC()
+	}
+	key := h.key
+	if key == "" {
+		key = zerolog.TimestampFieldName
+	}
+	format := h.format
+	if format == "" {
+		format = time.RFC3339
+	}
+	e.Str(key, now.Format(format))
 }
 
 // ContextWithLogger returns a new context carrying a zerolog-backed logger.
@@ -155,7 +248,7 @@ func (a adapter) With(keyvals ...any) logport.ForLogging {
 	if fields := fieldsFromKeyvals(keyvals, nil); len(fields) > 0 {
 		ctx = ctx.Fields(fields)
 	}
-	return adapter{logger: ctx.Logger(), groups: a.groups, forcedLevel: a.forcedLevel, includeLogLevel: a.includeLogLevel}
+	return adapter{logger: ctx.Logger(), groups: a.groups, forcedLevel: a.forcedLevel, includeLogLevel: a.includeLogLevel, exit: a.exit}
 }
 
 func (a adapter) WithTrace(ctx context.Context) logport.ForLogging {
@@ -170,7 +263,7 @@ func (a adapter) WithLogLevel() logport.ForLogging {
 	if a.includeLogLevel {
 		return a
 	}
-	return adapter{logger: a.logger, groups: a.groups, forcedLevel: a.forcedLevel, includeLogLevel: true}
+	return adapter{logger: a.logger, groups: a.groups, forcedLevel: a.forcedLevel, includeLogLevel: true, exit: a.exit}
 }
 
 func (a adapter) LogLevelFromEnv(key string) logport.ForLogging {
@@ -183,9 +276,9 @@ func (a adapter) LogLevelFromEnv(key string) logport.ForLogging {
 func (a adapter) LogLevel(level logport.Level) logport.ForLogging {
 	if level == logport.NoLevel {
 		lvl := level
-		return adapter{logger: a.logger, groups: a.groups, forcedLevel: &lvl, includeLogLevel: a.includeLogLevel}
+		return adapter{logger: a.logger, groups: a.groups, forcedLevel: &lvl, includeLogLevel: a.includeLogLevel, exit: a.exit}
 	}
-	return adapter{logger: a.logger.Level(portLevelToZero(level)), groups: a.groups, includeLogLevel: a.includeLogLevel}
+	return adapter{logger: a.logger.Level(portLevelToZero(level)), groups: a.groups, includeLogLevel: a.includeLogLevel, exit: a.exit}
 }
 
 func (a adapter) Debug(msg string, keyvals ...any) {
@@ -273,11 +366,14 @@ func (a adapter) Errorf(format string, args ...any) {
 	a.Error(formatMessage(format, args...))
 }
 
+// Fatal logs at FatalLevel and terminates through the adapter's ExitPolicy
+// rather than zerolog's own os.Exit, so exit hooks and ExitFunc apply.
 func (a adapter) Fatal(msg string, keyvals ...any) {
-	event := a.logger.Fatal()
+	event := a.logger.WithLevel(zerolog.FatalLevel)
 	addFields(event, keyvals, a.groups)
 	a.addLogLevel(event)
 	event.Msg(msg)
+	a.exit.Exit(1)
 }
 
 func (a adapter) Fatalf(format string, args ...any) {
@@ -285,6 +381,10 @@ func (a adapter) Fatalf(format string, args ...any) {
 }
 
 func (a adapter) Panic(msg string, keyvals ...any) {
+	if a.exit.PanicContinues() {
+		a.Error(msg, keyvals...)
+		return
+	}
 	event := a.logger.Panic()
 	if event == nil {
 		panic(msg)
@@ -524,14 +624,14 @@ func (a adapter) WithAttrs(attrs []slog.Attr) slog.Handler {
 	if fields := fieldsFromKeyvals(attrsToKeyvals(attrs, a.groups), nil); len(fields) > 0 {
 		ctx = ctx.Fields(fields)
 	}
-	return adapter{logger: ctx.Logger(), groups: a.groups, forcedLevel: a.forcedLevel, includeLogLevel: a.includeLogLevel}
+	return adapter{logger: ctx.Logger(), groups: a.groups, forcedLevel: a.forcedLevel, includeLogLevel: a.includeLogLevel, exit: a.exit}
 }
 
 func (a adapter) WithGroup(name string) slog.Handler {
 	if name == "" {
 		return a
 	}
-	return adapter{logger: a.logger, groups: appendGroup(a.groups, name), forcedLevel: a.forcedLevel, includeLogLevel: a.includeLogLevel}
+	return adapter{logger: a.logger, groups: appendGroup(a.groups, name), forcedLevel: a.forcedLevel, includeLogLevel: a.includeLogLevel, exit: a.exit}
 }
 
 func slogLevelToZero(level slog.Level) zerolog.Level {
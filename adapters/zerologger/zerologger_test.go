@@ -453,3 +453,28 @@ func decodeZerologJSON(t *testing.T, data []byte) map[string]any {
 	}
 	return record
 }
+
+func TestFatalAndPanicFollowExitPolicy(t *testing.T) {
+	buf := &bytes.Buffer{}
+	code := 0
+	logger := NewWithOptions(buf, Options{
+		Structured:       true,
+		DisableTimestamp: true,
+		ExitFunc:         func(c int) { code = c },
+		PanicPolicy:      logport.PanicContinue,
+	}).With("svc", "api")
+
+	logger.Fatal("shutting down")
+	if code != 1 {
+		t.Fatalf("expected ExitFunc to receive 1, got %d", code)
+	}
+	if !strings.Contains(buf.String(), `"level":"fatal"`) || !strings.Contains(buf.String(), "shutting down") {
+		t.Fatalf("expected fatal entry, got %s", buf.String())
+	}
+
+	buf.Reset()
+	logger.Panic("bad state")
+	if !strings.Contains(buf.String(), `"level":"error"`) || !strings.Contains(buf.String(), "bad state") {
+		t.Fatalf("expected panic to be logged as error, got %s", buf.String())
+	}
+}
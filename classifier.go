@@ -0,0 +1,236 @@
+package logport
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Classifier derives the level and message of a line of text written through
+// the io.Writer bridges (WriteToLogger, LogLogger, LineWriter). Classify
+// receives the line without its trailing newline and returns NoLevel when it
+// cannot tell; msg is the line with any recognised level marker removed.
+type Classifier interface {
+	Classify(line string) (level Level, msg string)
+}
+
+// ClassifierFunc adapts a function to the Classifier interface.
+type ClassifierFunc func(line string) (Level, string)
+
+// Classify calls f(line).
+func (f ClassifierFunc) Classify(line string) (Level, string) {
+	return f(line)
+}
+
+// DefaultClassifier returns the built-in rule set used when no classifier is
+// supplied: JSON and logfmt decoding, syslog, klog/glog, log package and
+// timestamp header parsing, the net/http TLS handshake special case,
+// bracketed and leading level tokens ("[WARN]", "error:", "INFO -") and a
+// case-insensitive "error" substring match.
+func DefaultClassifier() Classifier {
+	return defaultClassifier
+}
+
+var defaultClassifier Classifier = &RuleClassifier{}
+
+// RuleClassifier is a Classifier assembled with ClassifierBuilder. Rules are
+// evaluated in the order they were added; the first match wins. Lines no rule
+// matches fall through to the enabled built-in heuristics. RuleClassifier
+// implements FieldClassifier.
+type RuleClassifier struct {
+	rules            []classifierRule
+	noTLSHandshake   bool
+	noLevelTokens    bool
+	noErrorSubstring bool
+	noStructured     bool
+	noHeaders        bool
+}
+
+type classifierRule func(trimmed string) (Level, string, bool)
+
+// ClassifierBuilder assembles a RuleClassifier:
+//
+//	classifier := logport.NewClassifierBuilder().
+//		Substring("0 errors found", logport.InfoLevel).
+//		Prefix("E ", logport.ErrorLevel).
+//		Regexp(regexp.MustCompile(`^(?P<level>[A-Z]+) \| (?P<msg>.*)$`), logport.NoLevel).
+//		DisableErrorSubstring().
+//		Build()
+type ClassifierBuilder struct {
+	c RuleClassifier
+}
+
+// NewClassifierBuilder returns a builder whose rule set starts out identical
+// to DefaultClassifier.
+func NewClassifierBuilder() *ClassifierBuilder {
+	return &ClassifierBuilder{}
+}
+
+// Prefix classifies lines starting with prefix (after leading whitespace) at
+// level; the prefix is removed from the message.
+func (b *ClassifierBuilder) Prefix(prefix string, level Level) *ClassifierBuilder {
+	b.c.rules = append(b.c.rules, func(trimmed string) (Level, string, bool) {
+		if prefix == "" || !strings.HasPrefix(trimmed, prefix) {
+			return NoLevel, "", false
+		}
+		return level, strings.TrimSpace(trimmed[len(prefix):]), true
+	})
+	return b
+}
+
+// Substring classifies lines containing substr at level, keeping the whole
+// line as the message. Add substring rules first to override later rules and
+// the heuristics for known lines such as "0 errors found".
+func (b *ClassifierBuilder) Substring(substr string, level Level) *ClassifierBuilder {
+	b.c.rules = append(b.c.rules, func(trimmed string) (Level, string, bool) {
+		if substr == "" || !strings.Contains(trimmed, substr) {
+			return NoLevel, "", false
+		}
+		return level, trimmed, true
+	})
+	return b
+}
+
+// Regexp classifies lines matched by re. A subexpression named "level" is
+// parsed as a level token ("warn", "E", "error", …) and a subexpression named
+// "msg" becomes the message; otherwise level and the whole line are used.
+func (b *ClassifierBuilder) Regexp(re *regexp.Regexp, level Level) *ClassifierBuilder {
+	if re == nil {
+		return b
+	}
+	levelIdx := re.SubexpIndex("level")
+	msgIdx := re.SubexpIndex("msg")
+	b.c.rules = append(b.c.rules, func(trimmed string) (Level, string, bool) {
+		match := re.FindStringSubmatch(trimmed)
+		if match == nil {
+			return NoLevel, "", false
+		}
+		lvl, msg := level, trimmed
+		if levelIdx >= 0 {
+			if parsed, ok := levelFromToken(match[levelIdx]); ok {
+				lvl = parsed
+			} else if parsed, ok := levelFromLetter(match[levelIdx]); ok {
+				lvl = parsed
+			}
+		}
+		if msgIdx >= 0 {
+			msg = strings.TrimSpace(match[msgIdx])
+		}
+		return lvl, msg, true
+	})
+	return b
+}
+
+// Func adds an arbitrary rule. fn reports whether it matched the trimmed line.
+func (b *ClassifierBuilder) Func(fn func(line string) (Level, string, bool)) *ClassifierBuilder {
+	if fn != nil {
+		b.c.rules = append(b.c.rules, fn)
+	}
+	return b
+}
+
+// DisableTLSHandshake turns off the "http: TLS handshake error" special case.
+func (b *ClassifierBuilder) DisableTLSHandshake() *ClassifierBuilder {
+	b.c.noTLSHandshake = true
+	return b
+}
+
+// DisableLevelTokens turns off detection of bracketed and leading level
+// tokens.
+func (b *ClassifierBuilder) DisableLevelTokens() *ClassifierBuilder {
+	b.c.noLevelTokens = true
+	return b
+}
+
+// DisableErrorSubstring turns off classifying any line containing "error" at
+// ErrorLevel.
+func (b *ClassifierBuilder) DisableErrorSubstring() *ClassifierBuilder {
+	b.c.noErrorSubstring = true
+	return b
+}
+
+// DisableStructured turns off decoding of JSON and logfmt lines.
+func (b *ClassifierBuilder) DisableStructured() *ClassifierBuilder {
+	b.c.noStructured = true
+	return b
+}
+
+// DisableHeaders turns off stripping and parsing of syslog, klog/glog, log
+// package and timestamp headers.
+func (b *ClassifierBuilder) DisableHeaders() *ClassifierBuilder {
+	b.c.noHeaders = true
+	return b
+}
+
+// DisableHeuristics turns off every built-in heuristic, including JSON and
+// logfmt decoding and header parsing, so only the added rules apply.
+func (b *ClassifierBuilder) DisableHeuristics() *ClassifierBuilder {
+	return b.DisableTLSHandshake().DisableLevelTokens().DisableErrorSubstring().DisableStructured().DisableHeaders()
+}
+
+// Build returns the assembled classifier. The builder may be reused.
+func (b *ClassifierBuilder) Build() *RuleClassifier {
+	c := b.c
+	c.rules = append([]classifierRule(nil), b.c.rules...)
+	return &c
+}
+
+// Classify implements Classifier. Fields of structured lines are dropped; use
+// ClassifyFields to keep them.
+func (c *RuleClassifier) Classify(raw string) (Level, string) {
+	level, msg, _ := c.ClassifyFields(raw)
+	return level, msg
+}
+
+func (c *RuleClassifier) classifyHeuristics(trimmed string) (Level, string) {
+	if !c.noTLSHandshake && strings.HasPrefix(trimmed, httpTLSHandshakePrefix) {
+		msg := strings.TrimSpace(trimmed[len(httpTLSHandshakePrefix):])
+		msg = strings.TrimLeft(msg, ": ")
+		return ErrorLevel, msg
+	}
+
+	if !c.noLevelTokens {
+		if level, msg, ok := classifyLevelToken(trimmed); ok {
+			return level, msg
+		}
+	}
+
+	if !c.noErrorSubstring {
+		if idx := indexErrorSubstring(trimmed); idx >= 0 {
+			msg := ""
+			if idx+len(errorNeedle) < len(trimmed) {
+				msg = strings.TrimSpace(trimmed[idx+len(errorNeedle):])
+				msg = strings.TrimLeft(msg, ": ")
+			}
+			if msg == "" {
+				msg = trimmed
+			}
+			return ErrorLevel, msg
+		}
+	}
+
+	return NoLevel, trimmed
+}
+
+// levelFromLetter maps single-letter severities (klog's I/W/E/F and D/T) to
+// levels.
+func levelFromLetter(token string) (Level, bool) {
+	if len(token) != 1 {
+		return NoLevel, false
+	}
+	switch token[0] {
+	case 'T', 't':
+		return TraceLevel, true
+	case 'D', 'd':
+		return DebugLevel, true
+	case 'I', 'i':
+		return InfoLevel, true
+	case 'W', 'w':
+		return WarnLevel, true
+	case 'E', 'e':
+		return ErrorLevel, true
+	case 'F', 'f':
+		return FatalLevel, true
+	default:
+		return NoLevel, false
+	}
+}
@@ -0,0 +1,76 @@
+package logport
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestClassifierBuilderRules(t *testing.T) {
+	classifier := NewClassifierBuilder().
+		Substring("0 errors found", InfoLevel).
+		Prefix("E ", ErrorLevel).
+		Regexp(regexp.MustCompile(`^(?P<level>[A-Za-z]+) \| (?P<msg>.*)$`), NoLevel).
+		Build()
+
+	cases := []struct {
+		input string
+		level Level
+		msg   string
+	}{
+		{"lint: 0 errors found", InfoLevel, "lint: 0 errors found"},
+		{"E  disk gone", ErrorLevel, "disk gone"},
+		{"W | retrying", WarnLevel, "retrying"},
+		{"warning | slow", WarnLevel, "slow"},
+		{"[DEBUG] heuristics still apply", DebugLevel, "heuristics still apply"},
+		{"plain", NoLevel, "plain"},
+		{"   ", NoLevel, ""},
+	}
+	for _, tc := range cases {
+		level, msg := classifier.Classify(tc.input)
+		if level != tc.level || msg != tc.msg {
+			t.Fatalf("Classify(%q) = %v %q, want %v %q", tc.input, level, msg, tc.level, tc.msg)
+		}
+	}
+}
+
+func TestClassifierBuilderDisablesHeuristics(t *testing.T) {
+	noSubstring := NewClassifierBuilder().DisableErrorSubstring().Build()
+	if level, msg := noSubstring.Classify("3 errors found"); level != NoLevel || msg != "3 errors found" {
+		t.Fatalf("expected substring heuristic to be disabled, got %v %q", level, msg)
+	}
+	if level, _ := noSubstring.Classify("WARN: tokens still work"); level != WarnLevel {
+		t.Fatalf("expected level tokens to remain enabled, got %v", level)
+	}
+
+	none := NewClassifierBuilder().DisableHeuristics().Build()
+	for _, line := range []string{"ERROR: boom", "http: TLS handshake error from x", "[INFO] hi"} {
+		if level, msg := none.Classify(line); level != NoLevel || msg != line {
+			t.Fatalf("expected %q to pass through unclassified, got %v %q", line, level, msg)
+		}
+	}
+}
+
+func TestWritersAcceptCustomClassifier(t *testing.T) {
+	classifier := NewClassifierBuilder().Substring("0 errors", InfoLevel).Build()
+
+	rec := &recordingLogger{}
+	_, _ = WriteToLoggerWithClassifier(rec, []byte("build ok, 0 errors\n"), classifier)
+	LogLoggerWithClassifier(rec, classifier).Println("vet: 0 errors")
+	LogLoggerWithLevelAndClassifier(rec, WarnLevel, ClassifierFunc(func(line string) (Level, string) {
+		return DebugLevel, "rewritten"
+	})).Println("anything")
+
+	want := []logEntry{
+		{level: InfoLevel, msg: "build ok, 0 errors"},
+		{level: InfoLevel, msg: "vet: 0 errors"},
+		{level: WarnLevel, msg: "rewritten"},
+	}
+	if len(rec.entries) != len(want) {
+		t.Fatalf("expected %d entries, got %+v", len(want), rec.entries)
+	}
+	for i, entry := range rec.entries {
+		if entry.level != want[i].level || entry.msg != want[i].msg {
+			t.Fatalf("entry %d = %+v, want %+v", i, entry, want[i])
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Command logportd collects log entries from local processes over a unix
+// socket and routes them to rotating files, the console or HTTP shippers.
+//
+//	logportd -config /etc/logportd.json
+//
+// Without -config every entry goes to stdout. Processes connect with the
+// sink/logportd client. SIGINT and SIGTERM stop the daemon after the entries
+// in flight are written.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"pkt.systems/logport/collector"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "logportd:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	configPath := flag.String("config", "", "JSON routing configuration")
+	socket := flag.String("socket", "", "unix socket to listen on (overrides the configuration)")
+	flag.Parse()
+
+	cfg := collector.Config{Routes: []collector.RouteConfig{{Output: collector.OutputConfig{Type: "console"}}}}
+	if *configPath != "" {
+		var err error
+		if cfg, err = collector.LoadConfig(*configPath); err != nil {
+			return err
+		}
+	}
+	if *socket != "" {
+		cfg.Socket = *socket
+	}
+	routes, closeOutputs, err := cfg.Build()
+	if err != nil {
+		return err
+	}
+	defer closeOutputs()
+
+	server, err := collector.Listen(cfg.Socket, routes)
+	if err != nil {
+		return err
+	}
+	server.OnError = func(err error) { fmt.Fprintln(os.Stderr, "logportd:", err) }
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		_ = server.Close()
+	}()
+	fmt.Fprintln(os.Stderr, "logportd: listening on", server.Addr())
+	return server.Serve()
+}
@@ -0,0 +1,350 @@
+package collector_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	logport "pkt.systems/logport"
+	psladapter "pkt.systems/logport/adapters/psl"
+	"pkt.systems/logport/collector"
+	"pkt.systems/logport/sink/logportd"
+)
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// socketPath returns a short path; unix socket paths are limited to about
+// 100 bytes, which t.TempDir can exceed.
+func socketPath(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "lpd")
+	if err != nil {
+		t.Fatalf("tempdir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "s")
+}
+
+func serve(t *testing.T, socket string, routes []collector.Route) *collector.Server {
+	t.Helper()
+	server, err := collector.Listen(socket, routes)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	go server.Serve()
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+func decodeLines(t *testing.T, data string) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(data), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("decode %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRoutesEntriesWithSource(t *testing.T) {
+	socket := socketPath(t)
+	logPath := filepath.Join(t.TempDir(), "build.log")
+	file, err := collector.OpenRotatingFile(logPath, 0, 0)
+	if err != nil {
+		t.Fatalf("OpenRotatingFile failed: %v", err)
+	}
+	defer file.Close()
+	warn := logport.WarnLevel
+	console := &lockedBuffer{}
+	serve(t, socket, []collector.Route{
+		{Name: "build-*", Output: file},
+		{MinLevel: &warn, Output: console},
+	})
+
+	build, err := logportd.New(logportd.Options{Socket: socket, Name: "build-a"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer build.Close()
+	test, err := logportd.New(logportd.Options{Socket: socket, Name: "test-b"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer test.Close()
+
+	opts := psladapter.Options{Mode: psladapter.ModeStructured, DisableTimestamp: true, NoColor: true}
+	psladapter.NewWithOptions(build, opts).Info("compiling", "pkg", "net")
+	psladapter.NewWithOptions(build, opts).Warn("slow")
+	psladapter.NewWithOptions(test, opts).Error("failed")
+	if _, err := test.Write([]byte("plain text\n{}\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	waitFor(t, func() bool { return strings.Count(console.String(), "\n") == 4 })
+	records := decodeLines(t, console.String())
+	byMsg := make(map[string]map[string]any)
+	for _, record := range records {
+		msg, _ := record["msg"].(string)
+		byMsg[msg] = record
+	}
+	if byMsg["slow"][collector.SourceNameKey] != "build-a" || byMsg["failed"][collector.SourceNameKey] != "test-b" {
+		t.Fatalf("unexpected console records %v", records)
+	}
+	if byMsg["plain text"] == nil || byMsg[""] == nil || byMsg[""][collector.SourcePIDKey] != float64(os.Getpid()) {
+		t.Fatalf("expected plain and empty entries to pass with the source pid, got %v", records)
+	}
+	if byMsg["compiling"] != nil {
+		t.Fatalf("info entry passed the warn route: %v", records)
+	}
+
+	build.Close()
+	waitFor(t, func() bool {
+		data, _ := os.ReadFile(logPath)
+		return strings.Count(string(data), "\n") == 2
+	})
+	data, _ := os.ReadFile(logPath)
+	fileRecords := decodeLines(t, string(data))
+	if fileRecords[0]["msg"] != "compiling" || fileRecords[0]["pkg"] != "net" || fileRecords[1]["msg"] != "slow" {
+		t.Fatalf("unexpected file records %v", fileRecords)
+	}
+}
+
+// blockingWriter blocks every Write until release is closed.
+type blockingWriter struct {
+	release chan struct{}
+}
+
+func (w blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return len(p), nil
+}
+
+func TestSlowOutputAppliesBackpressure(t *testing.T) {
+	socket := socketPath(t)
+	release := make(chan struct{})
+	serve(t, socket, []collector.Route{{Output: blockingWriter{release: release}}})
+	defer close(release)
+
+	client, err := logportd.New(logportd.Options{Socket: socket, WriteTimeout: 50 * time.Millisecond, MinBackoff: time.Hour})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer client.Close()
+	line := []byte(strings.Repeat("x", 4096) + "\n")
+	for i := 0; i < 100000; i++ {
+		if _, err = client.Write(line); err != nil {
+			break
+		}
+	}
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("expected a write timeout once the daemon stopped reading, got %v", err)
+	}
+	if _, err := client.Write(line); err != logportd.ErrBackoff {
+		t.Fatalf("expected ErrBackoff after the timeout, got %v", err)
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+	file, err := collector.OpenRotatingFile(path, 20, 2)
+	if err != nil {
+		t.Fatalf("OpenRotatingFile failed: %v", err)
+	}
+	for _, line := range []string{"line-one\n", "line-two\n", "line-three\n", "line-four\n", "line-five\n", "line-six\n"} {
+		if _, err := file.Write([]byte(line)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	for suffix, want := range map[string]string{"": "line-six\n", ".1": "line-four\nline-five\n", ".2": "line-three\n"} {
+		data, err := os.ReadFile(path + suffix)
+		if err != nil || string(data) != want {
+			t.Fatalf("%s%s = %q (%v), want %q", path, suffix, data, err, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expected at most two backups")
+	}
+}
+
+func TestConfigBuild(t *testing.T) {
+	var received sync.WaitGroup
+	received.Add(1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Done()
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	raw := `{"socket": "/tmp/x.sock", "routes": [
+		{"name": "build-*", "output": {"type": "file", "path": "` + filepath.Join(dir, "a.log") + `", "max_bytes": 100}},
+		{"min_level": "warn", "output": {"type": "file", "path": "` + filepath.Join(dir, "a.log") + `"}},
+		{"output": {"type": "console", "stream": "stderr"}},
+		{"output": {"type": "http", "url": "` + srv.URL + `", "encoder": "loki", "labels": ["source_name"]}}
+	]}`
+	configPath := filepath.Join(dir, "logportd.json")
+	if err := os.WriteFile(configPath, []byte(raw), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, err := collector.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	routes, closeOutputs, err := cfg.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if cfg.Socket != "/tmp/x.sock" || len(routes) != 4 || routes[0].Output != routes[1].Output || routes[2].Output != os.Stderr {
+		t.Fatalf("unexpected routes %+v", routes)
+	}
+	if routes[1].MinLevel == nil || *routes[1].MinLevel != logport.WarnLevel {
+		t.Fatalf("expected warn minimum level")
+	}
+	_, _ = routes[3].Output.Write([]byte(`{"msg":"shipped"}`))
+	if err := closeOutputs(); err != nil {
+		t.Fatalf("closing outputs failed: %v", err)
+	}
+	received.Wait()
+
+	for _, bad := range []string{
+		`{"routes": [{"output": {"type": "carrier-pigeon"}}]}`,
+		`{"routes": [{"min_level": "loud"}]}`,
+		`{"routes": [{"output": {"type": "http", "url": "http://x", "encoder": "xml"}}]}`,
+	} {
+		var cfg collector.Config
+		if err := json.Unmarshal([]byte(bad), &cfg); err != nil {
+			t.Fatalf("decode %s: %v", bad, err)
+		}
+		if _, _, err := cfg.Build(); err == nil {
+			t.Fatalf("expected an error for %s", bad)
+		}
+	}
+}
+
+func TestFrameLimit(t *testing.T) {
+	frame := collector.AppendFrame(nil, []byte("abc"))
+	if got, err := collector.ReadFrame(bytes.NewReader(frame), nil); err != nil || string(got) != "abc" {
+		t.Fatalf("ReadFrame = %q, %v", got, err)
+	}
+	huge := []byte{0xff, 0xff, 0xff, 0xff}
+	if _, err := collector.ReadFrame(bytes.NewReader(huge), nil); !errors.Is(err, collector.ErrFrameTooLarge) {
+		t.Fatalf("expected ErrFrameTooLarge, got %v", err)
+	}
+}
+
+func TestSocketPermissionsAndPeerPID(t *testing.T) {
+	socket := filepath.Join(socketPath(t), "run", "s")
+	console := &lockedBuffer{}
+	serve(t, socket, []collector.Route{{Output: console}})
+	if info, err := os.Stat(socket); err != nil || info.Mode().Perm() != collector.SocketMode {
+		t.Fatalf("expected mode %v, got %v, %v", collector.SocketMode, info, err)
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	hello, _ := json.Marshal(collector.Hello{PID: 1, Name: "forged"})
+	if _, err := conn.Write(collector.AppendFrame(collector.AppendFrame(nil, hello), []byte(`{"msg":"hi"}`))); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	waitFor(t, func() bool { return strings.Contains(console.String(), "\n") })
+	want := float64(1)
+	if runtime.GOOS == "linux" {
+		want = float64(os.Getpid())
+	}
+	if records := decodeLines(t, console.String()); records[0][collector.SourcePIDKey] != want {
+		t.Fatalf("expected source pid %v, got %v", want, records)
+	}
+}
+
+func TestClientCannotSpoofSourceFields(t *testing.T) {
+	socket := socketPath(t)
+	console := &lockedBuffer{}
+	serve(t, socket, []collector.Route{{Output: console}})
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	hello, _ := json.Marshal(collector.Hello{PID: os.Getpid(), Name: "real"})
+	frames := collector.AppendFrame(nil, hello)
+	for _, entry := range []string{
+		`{"msg":"plain","source_pid":1,"source_name":"forged"}`,
+		`{"source_pid":1,"SOURCE_NAME":"forged","msg":"case"}`,
+		`{"msg":"escaped","source\u005fpid":1,"\u0073ource_name":"forged"}`,
+		`{"msg":"folded","ſource_pid":1,"source_extra":"dropped"}`,
+		`{"msg":"nested","nested":{"source_pid":2}}`,
+	} {
+		frames = collector.AppendFrame(frames, []byte(entry))
+	}
+	if _, err := conn.Write(frames); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	waitFor(t, func() bool { return strings.Count(console.String(), "\n") == 5 })
+
+	lines := strings.Split(strings.TrimSpace(console.String()), "\n")
+	for i, record := range decodeLines(t, console.String()) {
+		if record[collector.SourcePIDKey] != float64(os.Getpid()) || record[collector.SourceNameKey] != "real" {
+			t.Fatalf("client overrode the source fields: %s", lines[i])
+		}
+		for key := range record {
+			if key != collector.SourcePIDKey && key != collector.SourceNameKey && strings.HasPrefix(strings.ToLower(key), "source_") {
+				t.Fatalf("client source key %q kept: %s", key, lines[i])
+			}
+		}
+		if record["msg"] != "nested" && strings.Count(lines[i], "source_") != 2 {
+			t.Fatalf("expected only the daemon's source keys: %s", lines[i])
+		}
+	}
+	if !strings.Contains(console.String(), `"nested":{"source_pid":2}`) {
+		t.Fatalf("nested objects should be kept as sent: %s", console.String())
+	}
+}
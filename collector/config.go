@@ -0,0 +1,149 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	logport "pkt.systems/logport"
+	"pkt.systems/logport/sink/httpship"
+)
+
+// Config is the JSON routing configuration read by logportd:
+//
+//	{
+//	  "socket": "/run/logportd.sock",
+//	  "routes": [
+//	    {"name": "build-*", "output": {"type": "file", "path": "/var/log/build.log", "max_bytes": 52428800}},
+//	    {"min_level": "warn", "output": {"type": "console"}},
+//	    {"output": {"type": "http", "url": "http://loki:3100/loki/api/v1/push", "encoder": "loki", "labels": ["source_name", "lvl"]}}
+//	  ]
+//	}
+//
+// Every route an entry matches receives it.
+type Config struct {
+	Socket string        `json:"socket"`
+	Routes []RouteConfig `json:"routes"`
+}
+
+// RouteConfig describes one Route.
+type RouteConfig struct {
+	Name     string       `json:"name"`
+	MinLevel string       `json:"min_level"`
+	Output   OutputConfig `json:"output"`
+}
+
+// OutputConfig selects and configures a route's output.
+type OutputConfig struct {
+	// Type is "file", "console" or "http".
+	Type string `json:"type"`
+
+	// Path, MaxBytes and MaxBackups configure a file output.
+	Path       string `json:"path"`
+	MaxBytes   int64  `json:"max_bytes"`
+	MaxBackups int    `json:"max_backups"`
+
+	// Stream is "stdout" (default) or "stderr" for a console output.
+	Stream string `json:"stream"`
+
+	// URL, Encoder ("json", "loki" or "elasticsearch"), Labels, Index and
+	// Gzip configure an http output.
+	URL     string   `json:"url"`
+	Encoder string   `json:"encoder"`
+	Labels  []string `json:"labels"`
+	Index   string   `json:"index"`
+	Gzip    bool     `json:"gzip"`
+}
+
+// LoadConfig reads a JSON Config from path.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("collector: %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Build opens the outputs and returns the routes together with a function
+// closing every output. Routes naming the same file share one RotatingFile.
+func (c Config) Build() ([]Route, func() error, error) {
+	var (
+		routes  []Route
+		closers []io.Closer
+	)
+	closeAll := func() error {
+		var errs []error
+		for _, closer := range closers {
+			errs = append(errs, closer.Close())
+		}
+		return errors.Join(errs...)
+	}
+	files := make(map[string]*RotatingFile)
+	for i, rc := range c.Routes {
+		route := Route{Name: rc.Name}
+		if rc.MinLevel != "" {
+			level, ok := logport.ParseLevel(rc.MinLevel)
+			if !ok {
+				_ = closeAll()
+				return nil, nil, fmt.Errorf("collector: route %d: unknown level %q", i, rc.MinLevel)
+			}
+			route.MinLevel = &level
+		}
+		out := rc.Output
+		switch out.Type {
+		case "file":
+			file, ok := files[out.Path]
+			if !ok {
+				var err error
+				if file, err = OpenRotatingFile(out.Path, out.MaxBytes, out.MaxBackups); err != nil {
+					_ = closeAll()
+					return nil, nil, fmt.Errorf("collector: route %d: %w", i, err)
+				}
+				files[out.Path] = file
+				closers = append(closers, file)
+			}
+			route.Output = file
+		case "console", "":
+			switch out.Stream {
+			case "", "stdout":
+				route.Output = os.Stdout
+			case "stderr":
+				route.Output = os.Stderr
+			default:
+				_ = closeAll()
+				return nil, nil, fmt.Errorf("collector: route %d: unknown console stream %q", i, out.Stream)
+			}
+		case "http":
+			var encoder httpship.Encoder
+			switch out.Encoder {
+			case "", "json":
+				encoder = httpship.JSONArray()
+			case "loki":
+				encoder = httpship.Loki(httpship.LokiOptions{Labels: out.Labels})
+			case "elasticsearch":
+				encoder = httpship.Elasticsearch(httpship.ElasticsearchOptions{Index: out.Index})
+			default:
+				_ = closeAll()
+				return nil, nil, fmt.Errorf("collector: route %d: unknown encoder %q", i, out.Encoder)
+			}
+			shipper, err := httpship.New(httpship.Options{URL: out.URL, Encoder: encoder, Gzip: out.Gzip})
+			if err != nil {
+				_ = closeAll()
+				return nil, nil, fmt.Errorf("collector: route %d: %w", i, err)
+			}
+			closers = append(closers, shipper)
+			route.Output = shipper
+		default:
+			_ = closeAll()
+			return nil, nil, fmt.Errorf("collector: route %d: unknown output type %q", i, out.Type)
+		}
+		routes = append(routes, route)
+	}
+	return routes, closeAll, nil
+}
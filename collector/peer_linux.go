@@ -0,0 +1,30 @@
+//go:build linux
+
+package collector
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// peerPID returns the process ID the kernel recorded for the other end of
+// conn (SO_PEERCRED).
+func peerPID(conn net.Conn) (int, bool) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return 0, false
+	}
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return 0, false
+	}
+	var cred *unix.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	}); err != nil || credErr != nil {
+		return 0, false
+	}
+	return int(cred.Pid), true
+}
@@ -0,0 +1,94 @@
+// Package collector implements logportd, a local daemon that receives log
+// entries from many processes over a unix socket and fans them out to
+// rotating files, the console or an HTTP shipper according to routes.
+//
+// Clients (see sink/logportd) connect and send length-prefixed frames: a
+// 4-byte big-endian length followed by the payload. The first frame is a
+// JSON Hello naming the process; every later frame is one entry, normally a
+// JSON object. The daemon adds SourcePIDKey and SourceNameKey to each entry
+// before routing it. On Linux the process ID is taken from the socket's peer
+// credentials; elsewhere, and for the name everywhere, the daemon relies on
+// what the client claims, so any process able to connect can impersonate
+// another. The socket is created with SocketMode to limit who can connect.
+//
+// Backpressure comes from the socket itself: a connection's next frame is
+// read only after the previous entry was written to every matching route, so
+// a slow output fills the socket buffer and blocks the writing client.
+package collector
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// SourcePIDKey carries the sending process ID in every collected entry:
+	// the kernel's peer credentials on Linux, the Hello's PID elsewhere.
+	// Top-level source_* keys in client entries are dropped.
+	SourcePIDKey = "source_pid"
+	// SourceNameKey carries the sending process name in every collected
+	// entry.
+	SourceNameKey = "source_name"
+
+	// MaxFrameSize bounds one frame; larger frames close the connection.
+	MaxFrameSize = 1 << 20
+
+	// SocketMode is the permission Listen gives the socket: the daemon's
+	// user and group may connect, others may not.
+	SocketMode os.FileMode = 0o660
+)
+
+// ErrFrameTooLarge is returned for frames above MaxFrameSize.
+var ErrFrameTooLarge = errors.New("collector: frame too large")
+
+// DefaultSocketPath is where logportd listens and clients connect unless
+// configured otherwise: logportd.sock in $XDG_RUNTIME_DIR when it is set, as
+// it is for a user session, and /run/logportd/logportd.sock otherwise.
+func DefaultSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "logportd.sock")
+	}
+	return "/run/logportd/logportd.sock"
+}
+
+// Hello is the first frame of every connection.
+type Hello struct {
+	// PID is replaced with the peer's credentials where the platform
+	// provides them (Linux).
+	PID  int    `json:"pid"`
+	Name string `json:"name"`
+}
+
+// AppendFrame appends payload to b with its length prefix.
+func AppendFrame(b, payload []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(payload)))
+	return append(b, payload...)
+}
+
+// ReadFrame reads one frame into buf, growing it as needed, and returns the
+// payload.
+func ReadFrame(r io.Reader, buf []byte) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(header[:])
+	if n > MaxFrameSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, n)
+	}
+	if cap(buf) < int(n) {
+		buf = make([]byte, n)
+	}
+	buf = buf[:n]
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf, nil
+}
@@ -0,0 +1,328 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	logport "pkt.systems/logport"
+)
+
+// Route sends matching entries to Output. The zero Match accepts every
+// entry.
+type Route struct {
+	// Name is a path.Match pattern for the source name; empty matches all.
+	Name string
+	// MinLevel drops entries below it. Entries without a recognisable "lvl"
+	// or "level" field always pass.
+	MinLevel *logport.Level
+	// Output receives one newline-terminated JSON object per entry. Writes
+	// are serialised per route.
+	Output io.Writer
+
+	mu *sync.Mutex
+}
+
+func (r *Route) matches(name string, level logport.Level, hasLevel bool) bool {
+	if r.Name != "" {
+		if ok, _ := path.Match(r.Name, name); !ok {
+			return false
+		}
+	}
+	if r.MinLevel != nil && hasLevel && level < *r.MinLevel {
+		return false
+	}
+	return true
+}
+
+func (r *Route) write(line []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err := r.Output.Write(line)
+	return err
+}
+
+// Server accepts client connections on a unix socket and routes their
+// entries.
+type Server struct {
+	ln     net.Listener
+	routes []Route
+	// OnError, when set, is called with output write errors and failed
+	// connections. It must be set before Serve.
+	OnError func(error)
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// Listen creates the unix socket at socketPath with SocketMode, creating its
+// directory when missing and replacing a stale socket left by an earlier run,
+// and returns a Server routing to routes.
+func Listen(socketPath string, routes []Route) (*Server, error) {
+	if socketPath == "" {
+		socketPath = DefaultSocketPath()
+	}
+	if info, err := os.Lstat(socketPath); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", socketPath); err == nil {
+			conn.Close()
+			return nil, errors.New("collector: another daemon is listening on " + socketPath)
+		}
+		_ = os.Remove(socketPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(socketPath), 0o750); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(socketPath, SocketMode); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	s := &Server{ln: ln, routes: make([]Route, len(routes)), conns: make(map[net.Conn]struct{})}
+	for i, route := range routes {
+		if route.Output == nil {
+			ln.Close()
+			return nil, errors.New("collector: route " + strconv.Itoa(i) + " has no Output")
+		}
+		route.mu = &sync.Mutex{}
+		s.routes[i] = route
+	}
+	return s, nil
+}
+
+// Addr returns the socket address.
+func (s *Server) Addr() net.Addr {
+	return s.ln.Addr()
+}
+
+// Serve accepts connections until Close and returns nil after Close.
+func (s *Server) Serve() error {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+// Close stops accepting, disconnects clients and waits for their entries in
+// flight to be routed. Outputs are left open for the caller to close.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	err := s.ln.Close()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+		s.wg.Done()
+	}()
+	r := bufio.NewReader(conn)
+	frame, err := ReadFrame(r, nil)
+	if err != nil {
+		s.report(err)
+		return
+	}
+	var hello Hello
+	if err := json.Unmarshal(frame, &hello); err != nil {
+		s.report(errors.New("collector: invalid hello frame"))
+		return
+	}
+	if pid, ok := peerPID(conn); ok {
+		hello.PID = pid
+	}
+	prefix := sourcePrefix(hello)
+	var buf, line []byte
+	for {
+		buf, err = ReadFrame(r, buf[:0])
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				s.report(err)
+			}
+			return
+		}
+		level, hasLevel := entryLevel(buf)
+		line = enrich(line[:0], prefix, buf)
+		for i := range s.routes {
+			route := &s.routes[i]
+			if !route.matches(hello.Name, level, hasLevel) {
+				continue
+			}
+			if err := route.write(line); err != nil {
+				s.report(err)
+			}
+		}
+	}
+}
+
+func (s *Server) report(err error) {
+	if s.OnError != nil {
+		s.OnError(err)
+	}
+}
+
+// sourcePrefix renders the fields added to every entry of one connection.
+func sourcePrefix(hello Hello) []byte {
+	name, _ := json.Marshal(hello.Name)
+	b := []byte(`"` + SourcePIDKey + `":`)
+	b = strconv.AppendInt(b, int64(hello.PID), 10)
+	b = append(b, `,"`+SourceNameKey+`":`...)
+	return append(b, name...)
+}
+
+// enrich writes payload as a JSON object with the source fields first.
+// Payloads that are not JSON objects are wrapped under "msg". Top-level
+// source_* keys sent by the client are dropped so they cannot shadow the
+// daemon's own.
+func enrich(dst, prefix, payload []byte) []byte {
+	payload = bytes.TrimSpace(payload)
+	dst = append(dst, '{')
+	dst = append(dst, prefix...)
+	if len(payload) > 0 && payload[0] == '{' && json.Valid(payload) {
+		payload = stripSourceKeys(payload)
+		if rest := bytes.TrimSpace(payload[1:]); len(rest) > 0 && rest[0] != '}' {
+			dst = append(dst, ',')
+			dst = append(dst, rest...)
+		} else {
+			dst = append(dst, '}')
+		}
+	} else {
+		msg, _ := json.Marshal(string(payload))
+		dst = append(dst, `,"msg":`...)
+		dst = append(dst, msg...)
+		dst = append(dst, '}')
+	}
+	return append(dst, '\n')
+}
+
+// stripSourceKeys returns the JSON object payload without its top-level keys
+// that start with "source_", compared with case folding as encoding/json
+// matches struct fields.
+func stripSourceKeys(payload []byte) []byte {
+	if !mayHoldSourceKey(payload) {
+		return payload
+	}
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	if _, err := dec.Token(); err != nil {
+		return payload
+	}
+	out := make([]byte, 0, len(payload))
+	out = append(out, '{')
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return payload
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return payload
+		}
+		key, _ := tok.(string)
+		if hasSourcePrefix(key) {
+			continue
+		}
+		if len(out) > 1 {
+			out = append(out, ',')
+		}
+		name, _ := json.Marshal(key)
+		out = append(out, name...)
+		out = append(out, ':')
+		out = append(out, value...)
+	}
+	return append(out, '}')
+}
+
+const sourceKeyPrefix = "source_"
+
+// hasSourcePrefix reports whether key starts with sourceKeyPrefix under
+// Unicode case folding.
+func hasSourcePrefix(key string) bool {
+	for _, want := range sourceKeyPrefix {
+		r, size := utf8.DecodeRuneInString(key)
+		if size == 0 || !strings.EqualFold(string(r), string(want)) {
+			return false
+		}
+		key = key[size:]
+	}
+	return true
+}
+
+// mayHoldSourceKey reports whether payload can spell a key starting with
+// sourceKeyPrefix: in any ASCII case, or through escapes or non-ASCII letters
+// that fold to it.
+func mayHoldSourceKey(payload []byte) bool {
+	for _, c := range payload {
+		if c == '\\' || c >= utf8.RuneSelf {
+			return true
+		}
+	}
+	for i := 0; i+len(sourceKeyPrefix) <= len(payload); i++ {
+		if bytes.EqualFold(payload[i:i+len(sourceKeyPrefix)], []byte(sourceKeyPrefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// entryLevel reads the "lvl" or "level" field of a JSON entry.
+func entryLevel(payload []byte) (logport.Level, bool) {
+	if !bytes.Contains(payload, []byte(`"lvl"`)) && !bytes.Contains(payload, []byte(`"level"`)) {
+		return logport.NoLevel, false
+	}
+	var fields struct {
+		Lvl   string `json:"lvl"`
+		Level string `json:"level"`
+	}
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return logport.NoLevel, false
+	}
+	name := fields.Lvl
+	if name == "" {
+		name = fields.Level
+	}
+	return logport.ParseLevel(name)
+}
@@ -0,0 +1,154 @@
+package logport
+
+import (
+	"errors"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// ProcKey is the structured logging key carrying a subprocess name.
+	ProcKey = "proc"
+	// PidKey is the structured logging key carrying a subprocess id.
+	PidKey = "pid"
+	// StreamKey is the structured logging key naming the stream ("stdout" or
+	// "stderr") a subprocess line was read from.
+	StreamKey = "stream"
+	// ExitCodeKey is the structured logging key carrying a subprocess exit
+	// status.
+	ExitCodeKey = "exit_code"
+	// DurationKey is the structured logging key carrying how long a subprocess
+	// ran.
+	DurationKey = "duration"
+
+	// ProcessExitMessage is logged once an attached command has exited.
+	ProcessExitMessage = "process.exit"
+)
+
+// CommandOptions configures AttachCommand.
+type CommandOptions struct {
+	// Name is the value of the ProcKey field. Defaults to the base name of
+	// cmd.Path.
+	Name string
+
+	// StdoutLevel is the level of stdout lines without a detectable level
+	// prefix. Defaults to InfoLevel.
+	StdoutLevel *Level
+
+	// StderrLevel is the level of stderr lines without a detectable level
+	// prefix. Defaults to WarnLevel.
+	StderrLevel *Level
+
+	// Lines configures the LineWriter used for each stream. Its Level and
+	// DefaultLevel fields are overridden per stream unless Level is set, in
+	// which case every line is pinned to it.
+	Lines LineWriterOptions
+}
+
+// AttachedCommand is an exec.Cmd whose stdout and stderr are logged line by
+// line. Use its Start, Wait and Run methods instead of the exec.Cmd ones.
+type AttachedCommand struct {
+	cmd    *exec.Cmd
+	logger ForLogging
+	opts   CommandOptions
+	stdout io.ReadCloser
+	stderr io.ReadCloser
+
+	copies  sync.WaitGroup
+	started time.Time
+}
+
+// AttachCommand prepares cmd so that every line it writes to stdout and
+// stderr is logged through logger with ProcKey, PidKey and StreamKey fields.
+// Lines are parsed like WriteToLogger input, split and grouped like
+// NewLineWriter does, and fall back to the per-stream default level. When the
+// process ends a ProcessExitMessage entry records ExitCodeKey and
+// DurationKey. cmd.Stdout and cmd.Stderr must be nil.
+func AttachCommand(cmd *exec.Cmd, logger ForLogging, opts CommandOptions) (*AttachedCommand, error) {
+	if cmd == nil {
+		return nil, errors.New("logport: nil exec.Cmd")
+	}
+	if logger == nil {
+		logger = noopLogger{}
+	}
+	if opts.Name == "" {
+		opts.Name = filepath.Base(cmd.Path)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	return &AttachedCommand{cmd: cmd, logger: logger, opts: opts, stdout: stdout, stderr: stderr}, nil
+}
+
+// Cmd returns the underlying exec.Cmd.
+func (c *AttachedCommand) Cmd() *exec.Cmd {
+	return c.cmd
+}
+
+// Start starts the command and begins logging its output.
+func (c *AttachedCommand) Start() error {
+	if err := c.cmd.Start(); err != nil {
+		return err
+	}
+	c.started = time.Now()
+	c.logger = c.logger.With(ProcKey, c.opts.Name, PidKey, c.cmd.Process.Pid)
+	c.copies.Add(2)
+	go c.copy(c.stdout, "stdout", c.opts.StdoutLevel, InfoLevel)
+	go c.copy(c.stderr, "stderr", c.opts.StderrLevel, WarnLevel)
+	return nil
+}
+
+// Wait waits for the output to be drained and the command to exit, then logs
+// ProcessExitMessage at InfoLevel for a zero exit status and ErrorLevel
+// otherwise. It returns the error from exec.Cmd.Wait.
+func (c *AttachedCommand) Wait() error {
+	c.copies.Wait()
+	err := c.cmd.Wait()
+	elapsed := time.Since(c.started)
+
+	code := -1
+	if state := c.cmd.ProcessState; state != nil {
+		code = state.ExitCode()
+	}
+	keyvals := []any{ExitCodeKey, code, DurationKey, elapsed}
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		keyvals = append(keyvals, "error", err.Error())
+	}
+	level := InfoLevel
+	if err != nil {
+		level = ErrorLevel
+	}
+	c.logger.Logp(level, ProcessExitMessage, keyvals...)
+	return err
+}
+
+// Run starts the command and waits for it to finish.
+func (c *AttachedCommand) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+func (c *AttachedCommand) copy(r io.Reader, stream string, level *Level, fallback Level) {
+	defer c.copies.Done()
+	opts := c.opts.Lines
+	if opts.Level == nil {
+		if level == nil {
+			level = &fallback
+		}
+		opts.DefaultLevel = level
+	}
+	w := NewLineWriter(c.logger.With(StreamKey, stream), opts)
+	_, _ = io.Copy(w, r)
+	_ = w.Close()
+}
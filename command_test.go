@@ -0,0 +1,88 @@
+package logport_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os/exec"
+	"sync"
+	"testing"
+
+	logport "pkt.systems/logport"
+	psladapter "pkt.systems/logport/adapters/psl"
+)
+
+func TestAttachCommandLogsStreamsAndExit(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	buf := &lockedBuffer{}
+	logger := psladapter.NewWithOptions(buf, psladapter.Options{Mode: psladapter.ModeStructured, DisableTimestamp: true, NoColor: true})
+
+	cmd := exec.Command("sh", "-c", `echo "ERROR: bad input"; echo plain output; echo progress 1>&2; exit 3`)
+	attached, err := logport.AttachCommand(cmd, logger, logport.CommandOptions{Name: "tool"})
+	if err != nil {
+		t.Fatalf("AttachCommand failed: %v", err)
+	}
+	if err := attached.Run(); err == nil {
+		t.Fatalf("expected exit error")
+	}
+
+	var records []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var record map[string]any
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("decode %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	byMsg := map[string]map[string]any{}
+	for _, record := range records {
+		if record[logport.ProcKey] != "tool" || record[logport.PidKey] == nil {
+			t.Fatalf("expected proc and pid fields, got %v", record)
+		}
+		byMsg[record["msg"].(string)] = record
+	}
+
+	expect := []struct{ msg, lvl, stream string }{
+		{"bad input", "error", "stdout"},
+		{"plain output", "info", "stdout"},
+		{"progress", "warn", "stderr"},
+	}
+	for _, e := range expect {
+		record, ok := byMsg[e.msg]
+		if !ok {
+			t.Fatalf("missing %q entry in %s", e.msg, buf.String())
+		}
+		if record["lvl"] != e.lvl || record[logport.StreamKey] != e.stream {
+			t.Fatalf("entry %q: expected lvl=%s stream=%s, got %v", e.msg, e.lvl, e.stream, record)
+		}
+	}
+	exit := records[len(records)-1]
+	if exit["msg"] != logport.ProcessExitMessage || exit["lvl"] != "error" || exit[logport.ExitCodeKey] != float64(3) {
+		t.Fatalf("unexpected exit entry %v", exit)
+	}
+	if _, ok := exit[logport.DurationKey]; !ok {
+		t.Fatalf("expected duration on exit entry, got %v", exit)
+	}
+}
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}
+
+func (b *lockedBuffer) String() string {
+	return string(b.Bytes())
+}
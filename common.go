@@ -0,0 +1,108 @@
+package logport
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ColorMode selects when console output is colored.
+type ColorMode uint8
+
+const (
+	// ColorAuto colors output written to a terminal. It is the zero value.
+	ColorAuto ColorMode = iota
+	// ColorAlways colors output even when it is not written to a terminal.
+	ColorAlways
+	// ColorNever never colors output.
+	ColorNever
+)
+
+// String returns "auto", "always" or "never".
+func (c ColorMode) String() string {
+	switch c {
+	case ColorAlways:
+		return "always"
+	case ColorNever:
+		return "never"
+	default:
+		return "auto"
+	}
+}
+
+// CommonOptions are the settings every adapter understands through its
+// NewWithCommonOptions constructor, with the same meaning on every backend.
+// The zero value selects each backend's defaults. A setting a backend cannot
+// honor makes the constructor fail with an *UnsupportedOptionError instead of
+// being silently ignored; the adapter documents which ones those are.
+type CommonOptions struct {
+	// MinLevel is the lowest level written. When nil the backend's default
+	// applies.
+	MinLevel *Level
+
+	// TimeFormat is the time.Time layout of the timestamp. Empty keeps the
+	// backend's format.
+	TimeFormat string
+	// UTC writes timestamps in UTC instead of local time.
+	UTC bool
+	// DisableTimestamp omits the timestamp.
+	DisableTimestamp bool
+
+	// Structured selects JSON lines instead of the backend's console format.
+	Structured bool
+	// Color selects when output is colored. Defaults to ColorAuto.
+	Color ColorMode
+
+	// AddSource adds SourceKey with the file:line of the logging call site,
+	// resolved the same way on every backend (see AddSource).
+	AddSource bool
+
+	// TimeKey, LevelKey and MessageKey rename the timestamp, level and
+	// message fields of structured output. Empty keeps the backend's name.
+	// Console output has no field names and ignores them.
+	TimeKey    string
+	LevelKey   string
+	MessageKey string
+}
+
+// ErrUnsupportedOption matches every *UnsupportedOptionError with errors.Is.
+var ErrUnsupportedOption = errors.New("unsupported option")
+
+// UnsupportedOptionError reports a CommonOptions setting a backend cannot
+// honor.
+type UnsupportedOptionError struct {
+	// Backend is the registered backend name, such as "phuslu".
+	Backend string
+	// Option is the CommonOptions field, such as "DisableTimestamp".
+	Option string
+	// Reason explains the limitation.
+	Reason string
+}
+
+func (e *UnsupportedOptionError) Error() string {
+	return fmt.Sprintf("logport: %s: %s %s: %s", e.Backend, ErrUnsupportedOption, e.Option, e.Reason)
+}
+
+// Is reports whether target is ErrUnsupportedOption.
+func (e *UnsupportedOptionError) Is(target error) bool {
+	return target == ErrUnsupportedOption
+}
+
+// CheckFixedKeys returns an *UnsupportedOptionError when o is structured and
+// renames a field that backend always writes under a fixed name. An empty
+// timeKey, levelKey or messageKey means the backend can rename that field;
+// a non-empty one is the name it always uses, which o may repeat.
+func (o CommonOptions) CheckFixedKeys(backend, timeKey, levelKey, messageKey string) error {
+	if !o.Structured {
+		return nil
+	}
+	for _, key := range []struct{ option, want, got string }{
+		{"TimeKey", timeKey, o.TimeKey},
+		{"LevelKey", levelKey, o.LevelKey},
+		{"MessageKey", messageKey, o.MessageKey},
+	} {
+		if key.want != "" && key.got != "" && key.got != key.want {
+			return &UnsupportedOptionError{Backend: backend, Option: key.option, Reason: fmt.Sprintf("the field is always named %q", key.want)}
+		}
+	}
+	return nil
+}
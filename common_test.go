@@ -0,0 +1,150 @@
+package logport_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	logport "pkt.systems/logport"
+	journald "pkt.systems/logport/adapters/journald"
+)
+
+var utcStamp = regexp.MustCompile(`\d{4}-\d\d-\d\dT\d\d:\d\d:\d\dZ`)
+
+func openCommon(t *testing.T, name string, opts logport.CommonOptions) (logport.ForLogging, *bytes.Buffer) {
+	t.Helper()
+	var buf bytes.Buffer
+	logger, err := logport.Open(name, &buf, opts)
+	if err != nil {
+		t.Fatalf("%s: open: %v", name, err)
+	}
+	return logger, &buf
+}
+
+func TestCommonOptionsTimestamp(t *testing.T) {
+	for _, name := range logport.Backends() {
+		logger, buf := openCommon(t, name, logport.CommonOptions{Structured: true, UTC: true, TimeFormat: time.RFC3339})
+		logger.Info("stamped")
+		if !utcStamp.MatchString(buf.String()) {
+			t.Errorf("%s: expected an RFC3339 UTC timestamp, got %q", name, buf.String())
+		}
+
+		if name == "phuslu" {
+			continue
+		}
+		logger, buf = openCommon(t, name, logport.CommonOptions{Structured: true, UTC: true, TimeFormat: time.RFC3339, DisableTimestamp: true})
+		logger.Info("unstamped")
+		if utcStamp.MatchString(buf.String()) || !strings.Contains(buf.String(), "unstamped") {
+			t.Errorf("%s: expected no timestamp, got %q", name, buf.String())
+		}
+	}
+}
+
+func TestCommonOptionsRenameKeys(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts logport.CommonOptions
+	}{
+		{"zap", logport.CommonOptions{TimeKey: "at", LevelKey: "severity", MessageKey: "text"}},
+		{"slog", logport.CommonOptions{TimeKey: "at", LevelKey: "severity", MessageKey: "text"}},
+		{"psl", logport.CommonOptions{TimeKey: "time", LevelKey: "level", MessageKey: "message"}},
+		{"zerolog", logport.CommonOptions{TimeKey: "at"}},
+		{"phuslu", logport.CommonOptions{TimeKey: "at"}},
+		{"charm", logport.CommonOptions{TimeKey: "time", LevelKey: "level", MessageKey: "msg"}},
+	} {
+		tc.opts.Structured = true
+		logger, buf := openCommon(t, tc.name, tc.opts)
+		logger.Warn("renamed")
+		var entry map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("%s: decode %q: %v", tc.name, buf.String(), err)
+		}
+		if _, ok := entry[tc.opts.TimeKey]; !ok {
+			t.Errorf("%s: expected time under %q, got %v", tc.name, tc.opts.TimeKey, entry)
+		}
+		if tc.opts.LevelKey != "" && !strings.EqualFold(entry[tc.opts.LevelKey].(string), "warn") {
+			t.Errorf("%s: expected level under %q, got %v", tc.name, tc.opts.LevelKey, entry)
+		}
+		if tc.opts.MessageKey != "" && entry[tc.opts.MessageKey] != "renamed" {
+			t.Errorf("%s: expected message under %q, got %v", tc.name, tc.opts.MessageKey, entry)
+		}
+	}
+}
+
+func TestCommonOptionsAddSource(t *testing.T) {
+	for _, name := range logport.Backends() {
+		logger, buf := openCommon(t, name, logport.CommonOptions{Structured: true, AddSource: true})
+		logger.With("k", "v").Infof("from %s", name)
+		var entry map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+			t.Fatalf("%s: decode %q: %v", name, buf.String(), err)
+		}
+		if source, _ := entry[logport.SourceKey].(string); !strings.HasPrefix(source, "common_test.go:") {
+			t.Errorf("%s: expected this file as source, got %v", name, entry)
+		}
+	}
+}
+
+func TestCommonOptionsColorAlways(t *testing.T) {
+	for _, name := range []string{"psl", "zerolog", "zap", "phuslu", "charm"} {
+		logger, buf := openCommon(t, name, logport.CommonOptions{Color: logport.ColorAlways})
+		logger.Error("colored")
+		if !strings.Contains(buf.String(), "\x1b[") {
+			t.Errorf("%s: expected ANSI colors, got %q", name, buf.String())
+		}
+		logger, buf = openCommon(t, name, logport.CommonOptions{Color: logport.ColorNever})
+		logger.Error("plain")
+		if strings.Contains(buf.String(), "\x1b[") {
+			t.Errorf("%s: expected no ANSI colors, got %q", name, buf.String())
+		}
+	}
+}
+
+func TestCommonOptionsUnsupported(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		option string
+		opts   logport.CommonOptions
+	}{
+		{"phuslu", "DisableTimestamp", logport.CommonOptions{DisableTimestamp: true}},
+		{"phuslu", "MessageKey", logport.CommonOptions{Structured: true, MessageKey: "text"}},
+		{"zerolog", "LevelKey", logport.CommonOptions{Structured: true, LevelKey: "severity"}},
+		{"charm", "TimeKey", logport.CommonOptions{Structured: true, TimeKey: "at"}},
+		{"onelog", "Color", logport.CommonOptions{Color: logport.ColorAlways}},
+		{"psl", "TimeKey", logport.CommonOptions{Structured: true, TimeKey: "at"}},
+		{"slog", "Color", logport.CommonOptions{Color: logport.ColorAlways}},
+		{"zap", "Color", logport.CommonOptions{Structured: true, Color: logport.ColorAlways}},
+	} {
+		_, err := logport.Open(tc.name, &bytes.Buffer{}, tc.opts)
+		var unsupported *logport.UnsupportedOptionError
+		if !errors.Is(err, logport.ErrUnsupportedOption) || !errors.As(err, &unsupported) {
+			t.Errorf("%s: expected an unsupported %s error, got %v", tc.name, tc.option, err)
+			continue
+		}
+		if unsupported.Backend != tc.name || !strings.Contains(unsupported.Option, tc.option) {
+			t.Errorf("%s: unexpected error %+v", tc.name, unsupported)
+		}
+	}
+
+	if _, err := journald.NewWithCommonOptions(logport.CommonOptions{TimeFormat: time.Kitchen}); !errors.Is(err, logport.ErrUnsupportedOption) {
+		t.Errorf("journald: expected TimeFormat to be unsupported, got %v", err)
+	}
+}
+
+func TestCheckFixedKeys(t *testing.T) {
+	opts := logport.CommonOptions{Structured: true, LevelKey: "level", MessageKey: "text"}
+	if err := opts.CheckFixedKeys("x", "", "level", ""); err != nil {
+		t.Fatalf("repeating the fixed name should pass: %v", err)
+	}
+	if err := opts.CheckFixedKeys("x", "", "level", "message"); !errors.Is(err, logport.ErrUnsupportedOption) {
+		t.Fatalf("expected MessageKey to be rejected, got %v", err)
+	}
+	opts.Structured = false
+	if err := opts.CheckFixedKeys("x", "ts", "lvl", "msg"); err != nil {
+		t.Fatalf("console output ignores keys: %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"io"
+
+	logport "pkt.systems/logport"
+
+	// The adapters register themselves with logport for Build to open.
+	_ "pkt.systems/logport/adapters/charmlogger"
+	_ "pkt.systems/logport/adapters/onelogger"
+	_ "pkt.systems/logport/adapters/phuslu"
+	_ "pkt.systems/logport/adapters/psl"
+	_ "pkt.systems/logport/adapters/slogger"
+	_ "pkt.systems/logport/adapters/zaplogger"
+	_ "pkt.systems/logport/adapters/zerologger"
+)
+
+// newBackend opens the backend named by cfg.Backend writing to w. AddSource
+// is left to Build so it covers the handler sinks too.
+func newBackend(cfg Config, w io.Writer, level logport.Level) (logport.ForLogging, error) {
+	name := cfg.Backend
+	if name == "" {
+		name = logport.DefaultBackend
+	}
+	opts := logport.CommonOptions{
+		MinLevel:         &level,
+		TimeFormat:       cfg.TimeFormat,
+		UTC:              cfg.UTC,
+		DisableTimestamp: cfg.DisableTimestamp,
+		Structured:       cfg.Mode == "structured",
+		TimeKey:          cfg.TimeKey,
+		LevelKey:         cfg.LevelKey,
+		MessageKey:       cfg.MessageKey,
+	}
+	switch {
+	case cfg.NoColor || cfg.Color == "never":
+		opts.Color = logport.ColorNever
+	case cfg.Color == "always":
+		opts.Color = logport.ColorAlways
+	}
+	return logport.Open(name, w, opts)
+}
@@ -0,0 +1,392 @@
+// Package config builds a logport logger from a declarative document, so the
+// backend, level, outputs and middlewares can change without touching code:
+//
+//	logger, closeLogger, err := config.BuildFile("logging.yaml")
+//	if err != nil {
+//		return err
+//	}
+//	defer closeLogger()
+//
+// A document looks like
+//
+//	backend: zerolog
+//	mode: structured
+//	level: debug
+//	outputs:
+//	  - type: stderr
+//	  - type: file
+//	    path: /var/log/app.log
+//	  - type: syslog
+//	    address: logs.internal:514
+//	middlewares:
+//	  - type: redact
+//	    keys: [password, authorization]
+//	  - type: sample
+//	    first: 10
+//	    thereafter: 100
+//
+// and is read from JSON or YAML with Load, or from LOGPORT_* environment
+// variables with FromEnv. Invalid documents are rejected with errors that
+// name the offending path, such as "outputs[1].url".
+//
+// Watch keeps a logger in step with a document on disk, reloading its level
+// and middlewares when the file changes or the process receives SIGHUP.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	logport "pkt.systems/logport"
+)
+
+// Config describes one logger.
+type Config struct {
+	// Backend names a backend registered with logport.Register: "psl"
+	// (default), "zerolog", "slog", "zap", "charm", "phuslu", "onelog" or
+	// one registered by the program.
+	Backend string `json:"backend,omitempty" yaml:"backend,omitempty"`
+	// Mode is "console" (default) or "structured" for JSON lines. onelog
+	// only writes JSON.
+	Mode string `json:"mode,omitempty" yaml:"mode,omitempty"`
+	// Level is the minimum level written, by name ("trace" through
+	// "panic", or "disabled"). Defaults to "info".
+	Level string `json:"level,omitempty" yaml:"level,omitempty"`
+	// TimeFormat is a time.Time layout; empty keeps the backend's default.
+	TimeFormat string `json:"time_format,omitempty" yaml:"time_format,omitempty"`
+	// UTC writes timestamps in UTC.
+	UTC bool `json:"utc,omitempty" yaml:"utc,omitempty"`
+	// DisableTimestamp omits timestamps.
+	DisableTimestamp bool `json:"disable_timestamp,omitempty" yaml:"disable_timestamp,omitempty"`
+	// Color is "auto" (default), "always" or "never".
+	Color string `json:"color,omitempty" yaml:"color,omitempty"`
+	// NoColor is shorthand for Color "never".
+	NoColor bool `json:"no_color,omitempty" yaml:"no_color,omitempty"`
+	// AddSource adds the file:line of the logging call (logport.AddSource).
+	AddSource bool `json:"add_source,omitempty" yaml:"add_source,omitempty"`
+	// TimeKey, LevelKey and MessageKey rename the fields of structured
+	// output where the backend allows it.
+	TimeKey    string `json:"time_key,omitempty" yaml:"time_key,omitempty"`
+	LevelKey   string `json:"level_key,omitempty" yaml:"level_key,omitempty"`
+	MessageKey string `json:"message_key,omitempty" yaml:"message_key,omitempty"`
+
+	// Outputs receive every entry. Defaults to a single stdout output.
+	Outputs []Output `json:"outputs,omitempty" yaml:"outputs,omitempty"`
+	// Middlewares wrap the logger in order, the first one outermost.
+	Middlewares []Middleware `json:"middlewares,omitempty" yaml:"middlewares,omitempty"`
+}
+
+// Output selects and configures one destination. Only the fields of the
+// chosen Type are read.
+type Output struct {
+	// Type is "stdout", "stderr", "file", "http", "logportd", "syslog",
+	// "gelf" or "fluent".
+	Type string `json:"type" yaml:"type"`
+
+	// Path, MaxBytes and MaxBackups configure a rotating file.
+	Path       string `json:"path,omitempty" yaml:"path,omitempty"`
+	MaxBytes   int64  `json:"max_bytes,omitempty" yaml:"max_bytes,omitempty"`
+	MaxBackups int    `json:"max_backups,omitempty" yaml:"max_backups,omitempty"`
+
+	// URL, Encoder ("json", "loki" or "elasticsearch"), Labels, Index and
+	// Gzip configure an http shipper.
+	URL     string   `json:"url,omitempty" yaml:"url,omitempty"`
+	Encoder string   `json:"encoder,omitempty" yaml:"encoder,omitempty"`
+	Labels  []string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Index   string   `json:"index,omitempty" yaml:"index,omitempty"`
+	Gzip    bool     `json:"gzip,omitempty" yaml:"gzip,omitempty"`
+
+	// Socket and Name configure a logportd client.
+	Socket string `json:"socket,omitempty" yaml:"socket,omitempty"`
+	Name   string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// Network and Address locate a syslog, gelf or fluent collector; each
+	// sink has its own defaults.
+	Network string `json:"network,omitempty" yaml:"network,omitempty"`
+	Address string `json:"address,omitempty" yaml:"address,omitempty"`
+
+	// Format ("rfc5424" or "rfc3164"), Facility ("user", "daemon",
+	// "local0" and so on) and AppName configure syslog.
+	Format   string `json:"format,omitempty" yaml:"format,omitempty"`
+	Facility string `json:"facility,omitempty" yaml:"facility,omitempty"`
+	AppName  string `json:"app_name,omitempty" yaml:"app_name,omitempty"`
+
+	// Tag and RequireAck configure fluent.
+	Tag        string `json:"tag,omitempty" yaml:"tag,omitempty"`
+	RequireAck bool   `json:"require_ack,omitempty" yaml:"require_ack,omitempty"`
+}
+
+// Middleware selects and configures one wrapper around the logger.
+type Middleware struct {
+	// Type is "sample", "redact" or "dedup".
+	Type string `json:"type" yaml:"type"`
+
+	// Tick, First and Thereafter configure logport.Sample.
+	Tick       Duration `json:"tick,omitempty" yaml:"tick,omitempty"`
+	First      int      `json:"first,omitempty" yaml:"first,omitempty"`
+	Thereafter int      `json:"thereafter,omitempty" yaml:"thereafter,omitempty"`
+
+	// Keys and Replacement configure logport.Redact. Keys also selects the
+	// fingerprinted fields of dedup.
+	Keys        []string `json:"keys,omitempty" yaml:"keys,omitempty"`
+	Replacement string   `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+
+	// Window configures logport.DedupWithOptions.
+	Window Duration `json:"window,omitempty" yaml:"window,omitempty"`
+}
+
+// Duration is a time.Duration written as a string such as "1s" or "250ms".
+type Duration time.Duration
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// FieldError reports an invalid value at Path, written the way it appears in
+// the document ("outputs[1].url") or, for FromEnv, as the variable name.
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	return "config: " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+func fieldError(path, format string, args ...any) error {
+	return &FieldError{Path: path, Err: fmt.Errorf(format, args...)}
+}
+
+// Validate reports every invalid field of c, joined with errors.Join.
+func (c Config) Validate() error {
+	var errs []error
+	if backends := logport.Backends(); c.Backend != "" && !slices.Contains(backends, c.Backend) {
+		errs = append(errs, fieldError("backend", "unknown backend %q, want one of %s", c.Backend, strings.Join(backends, ", ")))
+	}
+	switch c.Mode {
+	case "", "console", "structured":
+	default:
+		errs = append(errs, fieldError("mode", "unknown mode %q, want console or structured", c.Mode))
+	}
+	switch c.Color {
+	case "", "auto", "never":
+	case "always":
+		if c.NoColor {
+			errs = append(errs, fieldError("no_color", "contradicts color %q", c.Color))
+		}
+	default:
+		errs = append(errs, fieldError("color", "unknown color mode %q, want auto, always or never", c.Color))
+	}
+	if c.Level != "" {
+		if _, ok := logport.ParseLevel(c.Level); !ok {
+			errs = append(errs, fieldError("level", "unknown level %q", c.Level))
+		}
+	}
+	for i, out := range c.Outputs {
+		errs = append(errs, out.validate(fmt.Sprintf("outputs[%d]", i)))
+	}
+	for i, mw := range c.Middlewares {
+		errs = append(errs, mw.validate(fmt.Sprintf("middlewares[%d]", i)))
+	}
+	return errors.Join(errs...)
+}
+
+func (o Output) validate(path string) error {
+	var errs []error
+	switch o.Type {
+	case "stdout", "stderr", "logportd", "gelf", "fluent":
+	case "file":
+		if o.Path == "" {
+			errs = append(errs, fieldError(path+".path", "required for a file output"))
+		}
+	case "http":
+		if o.URL == "" {
+			errs = append(errs, fieldError(path+".url", "required for an http output"))
+		}
+		switch o.Encoder {
+		case "", "json", "loki", "elasticsearch":
+		default:
+			errs = append(errs, fieldError(path+".encoder", "unknown encoder %q, want json, loki or elasticsearch", o.Encoder))
+		}
+	case "syslog":
+		if _, ok := syslogFormats[o.Format]; !ok {
+			errs = append(errs, fieldError(path+".format", "unknown syslog format %q, want rfc5424 or rfc3164", o.Format))
+		}
+		if _, ok := syslogFacilities[o.Facility]; !ok {
+			errs = append(errs, fieldError(path+".facility", "unknown syslog facility %q", o.Facility))
+		}
+	case "":
+		errs = append(errs, fieldError(path+".type", "required"))
+	default:
+		errs = append(errs, fieldError(path+".type", "unknown output type %q", o.Type))
+	}
+	return errors.Join(errs...)
+}
+
+func (m Middleware) validate(path string) error {
+	switch m.Type {
+	case "sample":
+		if m.First < 0 {
+			return fieldError(path+".first", "must not be negative")
+		}
+		if m.Thereafter < 0 {
+			return fieldError(path+".thereafter", "must not be negative")
+		}
+	case "redact":
+		if len(m.Keys) == 0 {
+			return fieldError(path+".keys", "required for redact")
+		}
+	case "dedup":
+	case "":
+		return fieldError(path+".type", "required")
+	default:
+		return fieldError(path+".type", "unknown middleware %q, want sample, redact or dedup", m.Type)
+	}
+	return nil
+}
+
+// Build validates cfg, opens its outputs and returns the logger with a
+// function that writes pending dedup summaries and closes the outputs. On
+// error every output opened so far is closed again.
+func Build(cfg Config) (logport.ForLogging, func() error, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	base, closeAll, err := openBase(cfg, cfg.level())
+	if err != nil {
+		return nil, nil, err
+	}
+	logger, dedups := cfg.applyMiddlewares(base)
+	closeLogger := func() error {
+		flushDedups(dedups)
+		return closeAll()
+	}
+	return logger, closeLogger, nil
+}
+
+func (c Config) level() logport.Level {
+	if c.Level == "" {
+		return logport.InfoLevel
+	}
+	level, _ := logport.ParseLevel(c.Level)
+	return level
+}
+
+// openBase opens the outputs of cfg and the backend writing to them, with
+// level as the minimum, and returns them as one logger together with the
+// function closing the outputs.
+func openBase(cfg Config, level logport.Level) (logport.ForLogging, func() error, error) {
+	outputs := cfg.Outputs
+	if len(outputs) == 0 {
+		outputs = []Output{{Type: "stdout"}}
+	}
+	var (
+		writers  []io.Writer
+		handlers []slog.Handler
+		closers  []io.Closer
+	)
+	closeAll := func() error {
+		var errs []error
+		for _, closer := range closers {
+			errs = append(errs, closer.Close())
+		}
+		return errors.Join(errs...)
+	}
+	for i, out := range outputs {
+		opened, err := openOutput(out)
+		if err != nil {
+			_ = closeAll()
+			return nil, nil, &FieldError{Path: fmt.Sprintf("outputs[%d]", i), Err: err}
+		}
+		if opened.writer != nil {
+			writers = append(writers, opened.writer)
+		}
+		if opened.handler != nil {
+			handlers = append(handlers, opened.handler)
+		}
+		if opened.closer != nil {
+			closers = append(closers, opened.closer)
+		}
+	}
+
+	var logger logport.ForLogging
+	if len(writers) > 0 {
+		w := writers[0]
+		if len(writers) > 1 {
+			w = fanoutWriter(writers)
+		}
+		backend, err := newBackend(cfg, w, level)
+		if err != nil {
+			_ = closeAll()
+			return nil, nil, &FieldError{Path: "backend", Err: err}
+		}
+		logger = backend
+	}
+	if len(handlers) > 0 {
+		logger = fanoutLogger(logger, handlers, level)
+	}
+	if cfg.AddSource {
+		logger = logport.AddSource(logger)
+	}
+	return logger, closeAll, nil
+}
+
+// applyMiddlewares wraps logger in the middlewares of c, the first one
+// outermost, and returns the dedup layers so their summaries can be flushed.
+func (c Config) applyMiddlewares(logger logport.ForLogging) (logport.ForLogging, []logport.ForLogging) {
+	var dedups []logport.ForLogging
+	for i := len(c.Middlewares) - 1; i >= 0; i-- {
+		logger = c.Middlewares[i].wrap(logger)
+		if c.Middlewares[i].Type == "dedup" {
+			dedups = append(dedups, logger)
+		}
+	}
+	return logger, dedups
+}
+
+func flushDedups(dedups []logport.ForLogging) {
+	for _, dedup := range dedups {
+		logport.FlushDedup(dedup)
+	}
+}
+
+// BuildFile loads the document at path with Load and builds it.
+func BuildFile(path string) (logport.ForLogging, func() error, error) {
+	cfg, err := Load(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return Build(cfg)
+}
+
+func (m Middleware) wrap(logger logport.ForLogging) logport.ForLogging {
+	switch m.Type {
+	case "sample":
+		return logport.Sample(logger, logport.SampleOptions{Tick: time.Duration(m.Tick), First: m.First, Thereafter: m.Thereafter})
+	case "redact":
+		return logport.Redact(logger, logport.RedactOptions{Keys: m.Keys, Replacement: m.Replacement})
+	case "dedup":
+		return logport.DedupWithOptions(logger, logport.DedupOptions{Window: time.Duration(m.Window), Keys: m.Keys})
+	}
+	return logger
+}
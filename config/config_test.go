@@ -0,0 +1,298 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	logport "pkt.systems/logport"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+	return path
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestBuildFromYAML(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "app.log")
+	path := writeFile(t, "logging.yaml", `
+backend: psl
+mode: structured
+level: debug
+disable_timestamp: true
+no_color: true
+outputs:
+  - type: file
+    path: `+logPath+`
+middlewares:
+  - type: redact
+    keys: [password]
+  - type: sample
+    tick: 1h
+    first: 2
+`)
+	logger, closeLogger, err := BuildFile(path)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	logger.Trace("hidden")
+	for i := 0; i < 5; i++ {
+		logger.Debug("login", "user", "ada", "password", "hunter2")
+	}
+	if err := closeLogger(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	lines := readLines(t, logPath)
+	if len(lines) != 2 {
+		t.Fatalf("expected two sampled debug entries, got %q", lines)
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("decode %q: %v", lines[0], err)
+	}
+	if entry["lvl"] != "debug" || entry["password"] != "[REDACTED]" || entry["user"] != "ada" {
+		t.Fatalf("unexpected entry %v", entry)
+	}
+}
+
+func TestBuildEveryBackendHonoursLevel(t *testing.T) {
+	for _, backend := range logport.Backends() {
+		for _, mode := range []string{"console", "structured"} {
+			t.Run(backend+"/"+mode, func(t *testing.T) {
+				logPath := filepath.Join(t.TempDir(), "app.log")
+				logger, closeLogger, err := Build(Config{
+					Backend: backend,
+					Mode:    mode,
+					Level:   "warn",
+					NoColor: true,
+					Outputs: []Output{{Type: "file", Path: logPath}},
+				})
+				if err != nil {
+					t.Fatalf("build: %v", err)
+				}
+				logger.Info("quiet-entry")
+				logger.Warn("loud-entry", "k", "v")
+				if err := closeLogger(); err != nil {
+					t.Fatalf("close: %v", err)
+				}
+				data, err := os.ReadFile(logPath)
+				if err != nil {
+					t.Fatalf("read: %v", err)
+				}
+				if strings.Contains(string(data), "quiet-entry") || !strings.Contains(string(data), "loud-entry") {
+					t.Fatalf("expected only the warn entry, got %q", data)
+				}
+			})
+		}
+	}
+}
+
+func TestBuildMapsCommonOptions(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "app.log")
+	logger, closeLogger, err := Build(Config{
+		Backend:    "zap",
+		Mode:       "structured",
+		UTC:        true,
+		TimeFormat: time.RFC3339,
+		AddSource:  true,
+		TimeKey:    "at",
+		MessageKey: "message",
+		Outputs:    []Output{{Type: "file", Path: logPath}},
+	})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	logger.Info("mapped")
+	if err := closeLogger(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(readLines(t, logPath)[0]), &entry); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	at, _ := entry["at"].(string)
+	if !strings.HasSuffix(at, "Z") || entry["message"] != "mapped" {
+		t.Fatalf("unexpected entry %v", entry)
+	}
+	if source, _ := entry[logport.SourceKey].(string); !strings.HasPrefix(source, "config_test.go:") {
+		t.Fatalf("expected the test as source, got %v", entry)
+	}
+}
+
+func TestBuildOnelogWithDefaultMode(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "app.log")
+	logger, closeLogger, err := Build(Config{Backend: "onelog", Outputs: []Output{{Type: "file", Path: logPath}}})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	logger.Info("implied-json")
+	if err := closeLogger(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if line := readLines(t, logPath)[0]; !strings.HasPrefix(line, "{") || !strings.Contains(line, "implied-json") {
+		t.Fatalf("expected a JSON entry, got %q", line)
+	}
+}
+
+func TestBuildKeepsWritingPastAFailingOutput(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	logger, closeLogger, err := Build(Config{
+		Mode: "structured",
+		Outputs: []Output{
+			{Type: "logportd", Socket: filepath.Join(dir, "missing.sock")},
+			{Type: "file", Path: logPath},
+		},
+	})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	logger.Info("still-written")
+	_ = closeLogger()
+	if lines := readLines(t, logPath); len(lines) != 1 || !strings.Contains(lines[0], "still-written") {
+		t.Fatalf("expected the file output to receive the entry, got %q", lines)
+	}
+}
+
+func TestBuildFansOutToHandlerSinks(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer pc.Close()
+	logPath := filepath.Join(t.TempDir(), "app.log")
+
+	logger, closeLogger, err := Build(Config{
+		Mode: "structured",
+		Outputs: []Output{
+			{Type: "file", Path: logPath},
+			{Type: "syslog", Network: "udp", Address: pc.LocalAddr().String(), AppName: "svc"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	logger.Debug("dropped")
+	logger.With("request_id", "r1").Error("write failed")
+	if err := closeLogger(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	_ = pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 2048)
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("read syslog: %v", err)
+	}
+	if msg := string(buf[:n]); !strings.Contains(msg, "svc") || !strings.Contains(msg, "write failed") || !strings.Contains(msg, "r1") {
+		t.Fatalf("unexpected syslog message %q", msg)
+	}
+	lines := readLines(t, logPath)
+	if len(lines) != 1 || !strings.Contains(lines[0], "write failed") || !strings.Contains(lines[0], "r1") {
+		t.Fatalf("unexpected file entries %q", lines)
+	}
+}
+
+func TestValidationNamesOffendingPath(t *testing.T) {
+	path := writeFile(t, "logging.json", `{
+		"backend": "log4j",
+		"level": "loud",
+		"outputs": [{"type": "stdout"}, {"type": "http"}, {"type": "syslog", "facility": "local9"}],
+		"middlewares": [{"type": "compress"}, {"type": "redact"}]
+	}`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	_, _, err = Build(cfg)
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{"backend", "level", "outputs[1].url", "outputs[2].facility", "middlewares[0].type", "middlewares[1].keys"} {
+		if !strings.Contains(err.Error(), "config: "+want+": ") {
+			t.Errorf("expected an error for %s, got %v", want, err)
+		}
+	}
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Path != "backend" {
+		t.Fatalf("expected a FieldError, got %#v", fieldErr)
+	}
+}
+
+func TestLoadRejectsUnknownFields(t *testing.T) {
+	for name, content := range map[string]string{
+		"logging.json": `{"levle": "debug"}`,
+		"logging.yml":  "outputs:\n  - type: stdout\n    pth: x\n",
+	} {
+		if _, err := Load(writeFile(t, name, content)); err == nil {
+			t.Errorf("%s: expected an unknown field error", name)
+		}
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	base := writeFile(t, "base.yaml", "backend: zap\nlevel: info\noutputs:\n  - type: stderr\n")
+	cfg, err := fromEnv([]string{
+		"HOME=/root",
+		EnvConfig + "=" + base,
+		"LOGPORT_LEVEL=debug",
+		"LOGPORT_NO_COLOR=true",
+		"LOGPORT_OUTPUTS_1_TYPE=file",
+		"LOGPORT_OUTPUTS_1_PATH=/var/log/app.log",
+		"LOGPORT_OUTPUTS_1_MAX_BYTES=1024",
+		"LOGPORT_MIDDLEWARES_0_TYPE=redact",
+		"LOGPORT_MIDDLEWARES_0_KEYS=password, token",
+		"LOGPORT_MIDDLEWARES_1_TYPE=dedup",
+		"LOGPORT_MIDDLEWARES_1_WINDOW=30s",
+	})
+	if err != nil {
+		t.Fatalf("from env: %v", err)
+	}
+	if cfg.Backend != "zap" || cfg.Level != "debug" || !cfg.NoColor {
+		t.Fatalf("unexpected top-level fields %+v", cfg)
+	}
+	if len(cfg.Outputs) != 2 || cfg.Outputs[0].Type != "stderr" || cfg.Outputs[1].Path != "/var/log/app.log" || cfg.Outputs[1].MaxBytes != 1024 {
+		t.Fatalf("unexpected outputs %+v", cfg.Outputs)
+	}
+	if len(cfg.Middlewares) != 2 || strings.Join(cfg.Middlewares[0].Keys, "|") != "password|token" || time.Duration(cfg.Middlewares[1].Window) != 30*time.Second {
+		t.Fatalf("unexpected middlewares %+v", cfg.Middlewares)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+}
+
+func TestFromEnvReportsBadVariables(t *testing.T) {
+	_, err := fromEnv([]string{
+		"LOGPORT_LEVLE=debug",
+		"LOGPORT_NO_COLOR=sometimes",
+		"LOGPORT_MIDDLEWARES_0_TICK=soon",
+	})
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	for _, want := range []string{"LOGPORT_LEVLE", "LOGPORT_NO_COLOR", "LOGPORT_MIDDLEWARES_0_TICK"} {
+		if !strings.Contains(err.Error(), "config: "+want+": ") {
+			t.Errorf("expected an error naming %s, got %v", want, err)
+		}
+	}
+}
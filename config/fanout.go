@@ -0,0 +1,75 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+
+	logport "pkt.systems/logport"
+	"pkt.systems/logport/adapters/slogger"
+)
+
+// fanoutLogger combines the backend, which may be nil, with the handler
+// sinks. Entries below level are dropped before reaching any of them.
+func fanoutLogger(backend logport.ForLogging, sinks []slog.Handler, level logport.Level) logport.ForLogging {
+	handlers := make(fanout, 0, len(sinks)+1)
+	if backend != nil {
+		handlers = append(handlers, backend)
+	}
+	handlers = append(handlers, sinks...)
+	return slogger.NewWithOptions(nil, slogger.Options{Handler: handlers, MinLevel: &level})
+}
+
+// fanout is a slog.Handler passing every record to each enabled handler.
+type fanout []slog.Handler
+
+func (f fanout) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range f {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f fanout) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for _, h := range f {
+		if h.Enabled(ctx, record.Level) {
+			errs = append(errs, h.Handle(ctx, record.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f fanout) WithAttrs(attrs []slog.Attr) slog.Handler {
+	derived := make(fanout, len(f))
+	for i, h := range f {
+		derived[i] = h.WithAttrs(attrs)
+	}
+	return derived
+}
+
+func (f fanout) WithGroup(name string) slog.Handler {
+	derived := make(fanout, len(f))
+	for i, h := range f {
+		derived[i] = h.WithGroup(name)
+	}
+	return derived
+}
+
+// fanoutWriter writes every entry to each writer, unlike io.MultiWriter,
+// which stops at the first failure: an unreachable network output must not
+// starve the file and console outputs listed after it.
+type fanoutWriter []io.Writer
+
+func (f fanoutWriter) Write(p []byte) (int, error) {
+	var errs []error
+	for _, w := range f {
+		if _, err := w.Write(p); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return len(p), errors.Join(errs...)
+}
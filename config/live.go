@@ -0,0 +1,153 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+
+	logport "pkt.systems/logport"
+	"pkt.systems/logport/adapters/slogger"
+)
+
+// liveState holds the backend opened once by a Watcher and the generation
+// built on it from the current document.
+type liveState struct {
+	base    logport.ForLogging
+	current atomic.Pointer[generation]
+}
+
+// generation is the base logger at one document's level, wrapped in that
+// document's middlewares.
+type generation struct {
+	root   logport.ForLogging
+	dedups []logport.ForLogging
+}
+
+func (s *liveState) generation(cfg Config) *generation {
+	root, dedups := cfg.applyMiddlewares(s.base.LogLevel(cfg.level()))
+	return &generation{root: root, dedups: dedups}
+}
+
+// liveLogger is the logger handed out by a Watcher. A derived liveLogger
+// records how it was derived from its parent and replays that on the
+// current generation, caching the result until the next reload, so With
+// fields, explicit levels and groups survive a reload.
+type liveLogger struct {
+	state  *liveState
+	parent *liveLogger
+	derive func(logport.ForLogging) logport.ForLogging
+	cache  atomic.Pointer[resolved]
+}
+
+type resolved struct {
+	gen    *generation
+	logger logport.ForLogging
+}
+
+func (l *liveLogger) resolve() logport.ForLogging {
+	gen := l.state.current.Load()
+	if l.parent == nil {
+		return gen.root
+	}
+	if cached := l.cache.Load(); cached != nil && cached.gen == gen {
+		return cached.logger
+	}
+	// The parent may resolve against a newer generation than gen; the
+	// entry is then rebuilt on the next call.
+	logger := l.derive(l.parent.resolve())
+	l.cache.Store(&resolved{gen: gen, logger: logger})
+	return logger
+}
+
+func (l *liveLogger) child(derive func(logport.ForLogging) logport.ForLogging) *liveLogger {
+	return &liveLogger{state: l.state, parent: l, derive: derive}
+}
+
+func (l *liveLogger) LogLevelFromEnv(key string) logport.ForLogging {
+	return l.child(func(logger logport.ForLogging) logport.ForLogging { return logger.LogLevelFromEnv(key) })
+}
+
+func (l *liveLogger) LogLevel(level logport.Level) logport.ForLogging {
+	return l.child(func(logger logport.ForLogging) logport.ForLogging { return logger.LogLevel(level) })
+}
+
+func (l *liveLogger) WithLogLevel() logport.ForLogging {
+	return l.child(logport.ForLogging.WithLogLevel)
+}
+
+func (l *liveLogger) With(keyvals ...any) logport.ForLogging {
+	if len(keyvals) == 0 {
+		return l
+	}
+	keyvals = append([]any(nil), keyvals...)
+	return l.child(func(logger logport.ForLogging) logport.ForLogging { return logger.With(keyvals...) })
+}
+
+func (l *liveLogger) WithTrace(ctx context.Context) logport.ForLogging {
+	return l.child(func(logger logport.ForLogging) logport.ForLogging { return logger.WithTrace(ctx) })
+}
+
+func (l *liveLogger) Logp(level logport.Level, msg string, keyvals ...any) {
+	l.resolve().Logp(level, msg, keyvals...)
+}
+
+func (l *liveLogger) Logf(level logport.Level, format string, v ...any) {
+	l.resolve().Logf(level, format, v...)
+}
+
+func (l *liveLogger) Logs(level string, msg string, keyvals ...any) {
+	l.resolve().Logs(level, msg, keyvals...)
+}
+
+func (l *liveLogger) Log(ctx context.Context, level slog.Level, msg string, keyvals ...any) {
+	l.resolve().Log(ctx, level, msg, keyvals...)
+}
+
+func (l *liveLogger) Trace(msg string, keyvals ...any) { l.resolve().Trace(msg, keyvals...) }
+func (l *liveLogger) Debug(msg string, keyvals ...any) { l.resolve().Debug(msg, keyvals...) }
+func (l *liveLogger) Info(msg string, keyvals ...any)  { l.resolve().Info(msg, keyvals...) }
+func (l *liveLogger) Warn(msg string, keyvals ...any)  { l.resolve().Warn(msg, keyvals...) }
+func (l *liveLogger) Error(msg string, keyvals ...any) { l.resolve().Error(msg, keyvals...) }
+func (l *liveLogger) Fatal(msg string, keyvals ...any) { l.resolve().Fatal(msg, keyvals...) }
+func (l *liveLogger) Panic(msg string, keyvals ...any) { l.resolve().Panic(msg, keyvals...) }
+
+func (l *liveLogger) Tracef(format string, v ...any) { l.resolve().Tracef(format, v...) }
+func (l *liveLogger) Debugf(format string, v ...any) { l.resolve().Debugf(format, v...) }
+func (l *liveLogger) Infof(format string, v ...any)  { l.resolve().Infof(format, v...) }
+func (l *liveLogger) Warnf(format string, v ...any)  { l.resolve().Warnf(format, v...) }
+func (l *liveLogger) Errorf(format string, v ...any) { l.resolve().Errorf(format, v...) }
+func (l *liveLogger) Fatalf(format string, v ...any) { l.resolve().Fatalf(format, v...) }
+func (l *liveLogger) Panicf(format string, v ...any) { l.resolve().Panicf(format, v...) }
+
+func (l *liveLogger) Write(p []byte) (int, error) {
+	return logport.WriteToLogger(l, p)
+}
+
+func (l *liveLogger) Enabled(ctx context.Context, level slog.Level) bool {
+	return l.resolve().Enabled(ctx, level)
+}
+
+func (l *liveLogger) Handle(ctx context.Context, record slog.Record) error {
+	return l.resolve().Handle(ctx, record)
+}
+
+func (l *liveLogger) WithAttrs(attrs []slog.Attr) slog.Handler {
+	attrs = append([]slog.Attr(nil), attrs...)
+	return l.child(func(logger logport.ForLogging) logport.ForLogging { return asLogger(logger.WithAttrs(attrs)) })
+}
+
+func (l *liveLogger) WithGroup(name string) slog.Handler {
+	return l.child(func(logger logport.ForLogging) logport.ForLogging { return asLogger(logger.WithGroup(name)) })
+}
+
+// asLogger returns handler as a ForLogging, which every logport adapter and
+// middleware already is, wrapping any other handler with slogger.
+func asLogger(handler slog.Handler) logport.ForLogging {
+	if logger, ok := handler.(logport.ForLogging); ok {
+		return logger
+	}
+	trace := logport.TraceLevel
+	return slogger.NewWithOptions(nil, slogger.Options{Handler: handler, MinLevel: &trace})
+}
+
+var _ logport.ForLogging = (*liveLogger)(nil)
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// EnvPrefix starts every variable read by FromEnv.
+	EnvPrefix = "LOGPORT_"
+	// EnvConfig names a JSON or YAML document FromEnv loads before applying
+	// the other variables on top of it.
+	EnvConfig = EnvPrefix + "CONFIG"
+)
+
+// Load reads the document at path, as YAML when the extension is .yaml or
+// .yml and as JSON otherwise. Unknown fields are rejected.
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	format := "json"
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		format = "yaml"
+	}
+	cfg, err := Parse(data, format)
+	if err != nil {
+		return cfg, fmt.Errorf("config: %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Parse decodes data as format, "json" or "yaml". Unknown fields are
+// rejected.
+func Parse(data []byte, format string) (Config, error) {
+	var cfg Config
+	switch format {
+	case "json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&cfg); err != nil {
+			return cfg, err
+		}
+	case "yaml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return cfg, err
+		}
+	default:
+		return cfg, fmt.Errorf("unknown format %q, want json or yaml", format)
+	}
+	return cfg, nil
+}
+
+// FromEnv reads a Config from LOGPORT_* variables. LOGPORT_CONFIG, when set,
+// names a document loaded first. Every other variable is the upper-cased
+// field path joined with underscores and overrides the document:
+//
+//	LOGPORT_BACKEND=zap
+//	LOGPORT_LEVEL=debug
+//	LOGPORT_OUTPUTS_0_TYPE=file
+//	LOGPORT_OUTPUTS_0_PATH=/var/log/app.log
+//	LOGPORT_MIDDLEWARES_0_TYPE=redact
+//	LOGPORT_MIDDLEWARES_0_KEYS=password,token
+//
+// List values are comma separated. Unknown LOGPORT_* variables are
+// reported, so a typo does not go unnoticed.
+func FromEnv() (Config, error) {
+	return fromEnv(os.Environ())
+}
+
+func fromEnv(environ []string) (Config, error) {
+	env := make(map[string]string)
+	for _, kv := range environ {
+		if key, value, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(key, EnvPrefix) {
+			env[key] = value
+		}
+	}
+	var cfg Config
+	if path := env[EnvConfig]; path != "" {
+		var err error
+		if cfg, err = Load(path); err != nil {
+			return cfg, err
+		}
+	}
+	delete(env, EnvConfig)
+	used := make(map[string]bool)
+	var errs []error
+	applyEnv(reflect.ValueOf(&cfg).Elem(), EnvPrefix, env, used, &errs)
+	var unknown []string
+	for key := range env {
+		if !used[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	slices.Sort(unknown)
+	for _, key := range unknown {
+		errs = append(errs, &FieldError{Path: key, Err: errors.New("unknown variable")})
+	}
+	return cfg, errors.Join(errs...)
+}
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// applyEnv sets the fields of the struct v from the variables named prefix
+// plus the field's upper-cased JSON name.
+func applyEnv(v reflect.Value, prefix string, env map[string]string, used map[string]bool, errs *[]error) {
+	t := v.Type()
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		key := prefix + strings.ToUpper(name)
+		field := v.Field(i)
+		if field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Struct {
+			for n := 0; ; n++ {
+				elemPrefix := key + "_" + strconv.Itoa(n) + "_"
+				if n >= field.Len() && !hasKeyPrefix(env, elemPrefix) {
+					break
+				}
+				if n >= field.Len() {
+					field.Set(reflect.Append(field, reflect.New(field.Type().Elem()).Elem()))
+				}
+				applyEnv(field.Index(n), elemPrefix, env, used, errs)
+			}
+			continue
+		}
+		value, ok := env[key]
+		if !ok {
+			continue
+		}
+		used[key] = true
+		if err := setValue(field, value); err != nil {
+			*errs = append(*errs, &FieldError{Path: key, Err: err})
+		}
+	}
+}
+
+func hasKeyPrefix(env map[string]string, prefix string) bool {
+	for key := range env {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func setValue(field reflect.Value, value string) error {
+	if field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		field.SetInt(n)
+	case reflect.Slice:
+		var items []string
+		for item := range strings.SplitSeq(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
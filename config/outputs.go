@@ -0,0 +1,112 @@
+package config
+
+import (
+	"io"
+	"log/slog"
+	"os"
+
+	"pkt.systems/logport/collector"
+	"pkt.systems/logport/sink/fluent"
+	"pkt.systems/logport/sink/gelf"
+	"pkt.systems/logport/sink/httpship"
+	"pkt.systems/logport/sink/logportd"
+	"pkt.systems/logport/sink/syslog"
+)
+
+var syslogFormats = map[string]syslog.Format{
+	"":        syslog.RFC5424,
+	"rfc5424": syslog.RFC5424,
+	"rfc3164": syslog.RFC3164,
+}
+
+var syslogFacilities = map[string]syslog.Facility{
+	"":         syslog.User,
+	"user":     syslog.User,
+	"mail":     syslog.Mail,
+	"daemon":   syslog.Daemon,
+	"auth":     syslog.Auth,
+	"syslog":   syslog.Syslog,
+	"lpr":      syslog.LPR,
+	"news":     syslog.News,
+	"uucp":     syslog.UUCP,
+	"cron":     syslog.Cron,
+	"authpriv": syslog.AuthPriv,
+	"ftp":      syslog.FTP,
+	"local0":   syslog.Local0,
+	"local1":   syslog.Local1,
+	"local2":   syslog.Local2,
+	"local3":   syslog.Local3,
+	"local4":   syslog.Local4,
+	"local5":   syslog.Local5,
+	"local6":   syslog.Local6,
+	"local7":   syslog.Local7,
+}
+
+// output is an opened Output: writers feed the backend, handlers are sinks
+// that format entries themselves.
+type output struct {
+	writer  io.Writer
+	handler slog.Handler
+	closer  io.Closer
+}
+
+// openOutput opens o, which has already been validated.
+func openOutput(o Output) (output, error) {
+	switch o.Type {
+	case "stdout":
+		return output{writer: os.Stdout}, nil
+	case "stderr":
+		return output{writer: os.Stderr}, nil
+	case "file":
+		file, err := collector.OpenRotatingFile(o.Path, o.MaxBytes, o.MaxBackups)
+		if err != nil {
+			return output{}, err
+		}
+		return output{writer: file, closer: file}, nil
+	case "http":
+		var encoder httpship.Encoder
+		switch o.Encoder {
+		case "loki":
+			encoder = httpship.Loki(httpship.LokiOptions{Labels: o.Labels})
+		case "elasticsearch":
+			encoder = httpship.Elasticsearch(httpship.ElasticsearchOptions{Index: o.Index})
+		default:
+			encoder = httpship.JSONArray()
+		}
+		shipper, err := httpship.New(httpship.Options{URL: o.URL, Encoder: encoder, Gzip: o.Gzip})
+		if err != nil {
+			return output{}, err
+		}
+		return output{writer: shipper, closer: shipper}, nil
+	case "logportd":
+		client, err := logportd.New(logportd.Options{Socket: o.Socket, Name: o.Name})
+		if err != nil {
+			return output{}, err
+		}
+		return output{writer: client, closer: client}, nil
+	case "syslog":
+		handler, err := syslog.New(syslog.Options{
+			Network:  o.Network,
+			Address:  o.Address,
+			Format:   syslogFormats[o.Format],
+			Facility: syslogFacilities[o.Facility],
+			AppName:  o.AppName,
+		})
+		if err != nil {
+			return output{}, err
+		}
+		return output{handler: handler, closer: handler}, nil
+	case "gelf":
+		handler, err := gelf.New(gelf.Options{Network: o.Network, Address: o.Address})
+		if err != nil {
+			return output{}, err
+		}
+		return output{handler: handler, closer: handler}, nil
+	default:
+		handler, err := fluent.New(fluent.Options{Network: o.Network, Address: o.Address, Tag: o.Tag, RequireAck: o.RequireAck})
+		if err != nil {
+			return output{}, err
+		}
+		return output{handler: handler, closer: handler}, nil
+	}
+}
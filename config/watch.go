@@ -0,0 +1,245 @@
+package config
+
+import (
+	"crypto/sha256"
+	"errors"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	logport "pkt.systems/logport"
+)
+
+// DefaultWatchInterval is how often a Watcher checks its file when
+// WatchOptions.Interval is zero.
+const DefaultWatchInterval = 2 * time.Second
+
+// WatchOptions configures WatchWithOptions.
+type WatchOptions struct {
+	// Interval between checks of the file's size, modification time and
+	// content hash. Defaults to DefaultWatchInterval.
+	Interval time.Duration
+	// OnChange, when set, is called with the new document after each
+	// successful reload.
+	OnChange func(Config)
+	// DisableSignal stops SIGHUP from triggering a reload.
+	DisableSignal bool
+}
+
+// Watcher keeps a logger in step with a configuration file. The level and
+// the middlewares (sampling, redaction and dedup) are swapped atomically on
+// the live logger and everything derived from it, so loggers made with With
+// or WithTrace before a reload keep their fields and follow the new
+// settings. The backend, mode, formatting fields and outputs are opened once;
+// a reload changing them is still applied for the rest, and a warning says
+// they need a restart.
+type Watcher struct {
+	path     string
+	onChange func(Config)
+
+	mu       sync.Mutex
+	cfg      Config
+	stamp    stamp
+	state    *liveState
+	root     *liveLogger
+	closeAll func() error
+
+	hup       chan os.Signal
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Watch loads and builds the document at path like BuildFile and reloads it
+// whenever the file changes or the process receives SIGHUP, calling onChange,
+// which may be nil, after each successful reload. An invalid document is
+// rejected with an error logged to the configured outputs whatever the
+// document's level, and the previous configuration stays active.
+func Watch(path string, onChange func(Config)) (*Watcher, error) {
+	return WatchWithOptions(path, WatchOptions{OnChange: onChange})
+}
+
+// WatchWithOptions is Watch with a custom poll interval and signal handling.
+func WatchWithOptions(path string, opts WatchOptions) (*Watcher, error) {
+	stamp, err := readStamp(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	// The backend is opened at the lowest level so a reload can lower the
+	// level as well as raise it.
+	base, closeAll, err := openBase(cfg, logport.TraceLevel)
+	if err != nil {
+		return nil, err
+	}
+	state := &liveState{base: base}
+	state.current.Store(state.generation(cfg))
+	w := &Watcher{
+		path:     path,
+		onChange: opts.OnChange,
+		cfg:      cfg,
+		stamp:    stamp,
+		state:    state,
+		root:     &liveLogger{state: state},
+		closeAll: closeAll,
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	if !opts.DisableSignal {
+		w.hup = make(chan os.Signal, 1)
+		signal.Notify(w.hup, syscall.SIGHUP)
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	go w.run(interval)
+	return w, nil
+}
+
+// Logger returns the live logger. It and every logger derived from it follow
+// each reload.
+func (w *Watcher) Logger() logport.ForLogging {
+	return w.root
+}
+
+// Config returns the document currently applied.
+func (w *Watcher) Config() Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.cfg
+}
+
+// Reload reads the file now, whether or not it changed. An invalid document
+// is returned as the error, rather than logged, and the previous
+// configuration stays active.
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.reloadLocked()
+}
+
+// Close stops watching, writes pending dedup summaries and closes the
+// outputs. The logger must not be used afterwards.
+func (w *Watcher) Close() error {
+	w.closeOnce.Do(func() {
+		if w.hup != nil {
+			signal.Stop(w.hup)
+		}
+		close(w.done)
+		<-w.stopped
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		flushDedups(w.state.current.Load().dedups)
+		w.closeErr = w.closeAll()
+	})
+	return w.closeErr
+}
+
+func (w *Watcher) run(interval time.Duration) {
+	defer close(w.stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.reload(false)
+		case <-w.hup:
+			w.reload(true)
+		}
+	}
+}
+
+// reload reloads the file, when it changed or force is set, and logs a
+// rejected document.
+func (w *Watcher) reload(force bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !force {
+		// A file that is briefly missing while an editor replaces it is
+		// picked up once it is back.
+		stamp, err := readStamp(w.path)
+		if err != nil || stamp == w.stamp {
+			return
+		}
+	}
+	if err := w.reloadLocked(); err != nil {
+		w.notice().Error("config: reload rejected, keeping the previous configuration", "path", w.path, "error", err)
+	}
+}
+
+// notice returns the backend without the document's level and middlewares,
+// so reload problems are reported even when the level would drop them.
+func (w *Watcher) notice() logport.ForLogging {
+	return w.state.base
+}
+
+func (w *Watcher) reloadLocked() error {
+	// Record the file as seen first so a rejected document is reported once
+	// rather than on every poll.
+	stamp, err := readStamp(w.path)
+	if err != nil {
+		return err
+	}
+	w.stamp = stamp
+	cfg, err := Load(w.path)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(cfg.fixed(), w.cfg.fixed()) {
+		w.notice().Warn("config: reload leaves backend, mode, formatting and outputs unchanged until restart", "path", w.path)
+	}
+	old := w.state.current.Swap(w.state.generation(cfg))
+	flushDedups(old.dedups)
+	w.cfg = cfg
+	if w.onChange != nil {
+		w.onChange(cfg)
+	}
+	return nil
+}
+
+// fixed returns c without the fields a Watcher applies on reload.
+func (c Config) fixed() Config {
+	c.Level = ""
+	c.Middlewares = nil
+	return c
+}
+
+// stamp identifies one version of the watched file. The content hash catches
+// rewrites that keep the size and land within the modification time's
+// granularity.
+type stamp struct {
+	modTime int64
+	size    int64
+	sum     [sha256.Size]byte
+}
+
+func readStamp(path string) (stamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return stamp{}, err
+	}
+	if info.IsDir() {
+		return stamp{}, errors.New("config: " + path + " is a directory")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return stamp{}, err
+	}
+	return stamp{modTime: info.ModTime().UnixNano(), size: info.Size(), sum: sha256.Sum256(data)}, nil
+}
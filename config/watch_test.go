@@ -0,0 +1,186 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func watchDocument(logPath, level, body string) string {
+	return "backend: psl\nmode: structured\ndisable_timestamp: true\nlevel: " + level + "\noutputs:\n  - type: file\n    path: " + logPath + "\n" + body
+}
+
+func waitForChange(t *testing.T, changes <-chan Config) Config {
+	t.Helper()
+	select {
+	case cfg := <-changes:
+		return cfg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a reload")
+		return Config{}
+	}
+}
+
+func TestWatchAppliesReloadToDerivedLoggers(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "app.log")
+	path := writeFile(t, "logging.yaml", watchDocument(logPath, "info", "middlewares:\n  - type: redact\n    keys: [password]\n"))
+	changes := make(chan Config, 1)
+	w, err := WatchWithOptions(path, WatchOptions{Interval: 10 * time.Millisecond, OnChange: func(cfg Config) { changes <- cfg }, DisableSignal: true})
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	child := w.Logger().With("request_id", "r1")
+	child.Debug("before-debug")
+	child.Info("before", "password", "p", "token", "t")
+
+	if err := os.WriteFile(path, []byte(watchDocument(logPath, "debug", "middlewares:\n  - type: redact\n    keys: [token, secret]\n  - type: sample\n    tick: 1h\n    first: 1\n")), 0o600); err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	if cfg := waitForChange(t, changes); cfg.Level != "debug" || w.Config().Level != "debug" {
+		t.Fatalf("unexpected reloaded config %+v", cfg)
+	}
+	child.Debug("after-debug")
+	for i := 0; i < 3; i++ {
+		child.Info("after", "password", "p", "token", "t")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	lines := readLines(t, logPath)
+	if len(lines) != 3 {
+		t.Fatalf("expected before, after-debug and one sampled after entry, got %q", lines)
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, `"request_id":"r1"`) {
+			t.Errorf("derived field lost in %s", line)
+		}
+	}
+	if !strings.Contains(lines[0], `"password":"[REDACTED]"`) || !strings.Contains(lines[0], `"token":"t"`) {
+		t.Errorf("expected the first redaction rules, got %s", lines[0])
+	}
+	if !strings.Contains(lines[1], "after-debug") {
+		t.Errorf("expected the lowered level to apply, got %s", lines[1])
+	}
+	if !strings.Contains(lines[2], `"password":"p"`) || !strings.Contains(lines[2], `"token":"[REDACTED]"`) {
+		t.Errorf("expected the reloaded redaction rules, got %s", lines[2])
+	}
+}
+
+func TestWatchKeepsPreviousConfigOnInvalidReload(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "app.log")
+	path := writeFile(t, "logging.yaml", watchDocument(logPath, "warn", ""))
+	w, err := WatchWithOptions(path, WatchOptions{Interval: 10 * time.Millisecond, DisableSignal: true})
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	defer w.Close()
+
+	if err := os.WriteFile(path, []byte(watchDocument(logPath, "loud", "")), 0o600); err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, _ := os.ReadFile(logPath)
+		if strings.Contains(string(data), "reload rejected") {
+			if !strings.Contains(string(data), "config: level: unknown level") {
+				t.Fatalf("expected the validation error in %q", data)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the rejection")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := w.Reload(); err == nil {
+		t.Fatal("expected Reload to return the validation error")
+	}
+	w.Logger().Info("still-quiet")
+	w.Logger().Warn("still-loud")
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	data, _ := os.ReadFile(logPath)
+	if strings.Contains(string(data), "still-quiet") || !strings.Contains(string(data), "still-loud") || w.Config().Level != "warn" {
+		t.Fatalf("expected the previous config to stay active, got %q", data)
+	}
+}
+
+func TestWatchReloadsOnSIGHUP(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no SIGHUP on windows")
+	}
+	logPath := filepath.Join(t.TempDir(), "app.log")
+	path := writeFile(t, "logging.yaml", watchDocument(logPath, "info", ""))
+	changes := make(chan Config, 1)
+	w, err := WatchWithOptions(path, WatchOptions{Interval: time.Hour, OnChange: func(cfg Config) { changes <- cfg }})
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	defer w.Close()
+
+	if err := os.WriteFile(path, []byte(watchDocument(logPath, "error", "")), 0o600); err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	self, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("find process: %v", err)
+	}
+	if err := self.Signal(syscall.SIGHUP); err != nil {
+		t.Fatalf("signal: %v", err)
+	}
+	if cfg := waitForChange(t, changes); cfg.Level != "error" {
+		t.Fatalf("unexpected reloaded config %+v", cfg)
+	}
+}
+
+func TestWatchDetectsSameSizeRewriteWithinMtimeGranularity(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "app.log")
+	path := writeFile(t, "logging.yaml", watchDocument(logPath, "info", ""))
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	changes := make(chan Config, 1)
+	w, err := WatchWithOptions(path, WatchOptions{Interval: 10 * time.Millisecond, OnChange: func(cfg Config) { changes <- cfg }, DisableSignal: true})
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	defer w.Close()
+
+	if err := os.WriteFile(path, []byte(watchDocument(logPath, "warn", "")), 0o600); err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	if cfg := waitForChange(t, changes); cfg.Level != "warn" {
+		t.Fatalf("unexpected reloaded config %+v", cfg)
+	}
+}
+
+func TestWatchReportsRejectionAboveTheLevel(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "app.log")
+	path := writeFile(t, "logging.yaml", watchDocument(logPath, "fatal", ""))
+	w, err := WatchWithOptions(path, WatchOptions{Interval: time.Hour, DisableSignal: true})
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(watchDocument(logPath, "loud", "")), 0o600); err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	w.reload(true)
+	w.Logger().Error("filtered")
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	data, _ := os.ReadFile(logPath)
+	if !strings.Contains(string(data), "reload rejected") || strings.Contains(string(data), "filtered") {
+		t.Fatalf("expected only the rejection in %q", data)
+	}
+}
@@ -0,0 +1,303 @@
+package logport
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDedupWindow is the suppression window used when Dedup is given a
+	// non-positive duration.
+	DefaultDedupWindow = 10 * time.Second
+	// DefaultDedupMaxEntries bounds how many distinct fingerprints a Dedup
+	// logger tracks at once.
+	DefaultDedupMaxEntries = 1024
+
+	// RepeatedKey is the structured logging key counting how many times a
+	// deduplicated entry was suppressed.
+	RepeatedKey = "repeated"
+	// FirstSeenKey is the structured logging key carrying the time of the
+	// first occurrence of a deduplicated entry.
+	FirstSeenKey = "first_seen"
+	// LastSeenKey is the structured logging key carrying the time of the last
+	// suppressed occurrence of a deduplicated entry.
+	LastSeenKey = "last_seen"
+)
+
+// DedupOptions configures DedupWithOptions.
+type DedupOptions struct {
+	// Window is how long identical entries are suppressed after the first
+	// one. Defaults to DefaultDedupWindow.
+	Window time.Duration
+
+	// Keys selects the fields, from the call or from With, that take part in
+	// the fingerprint next to the level and message. Entries that differ only
+	// in other fields are treated as repeats.
+	Keys []string
+
+	// MaxEntries bounds the number of fingerprints tracked at once; entries
+	// beyond it are written without suppression. Defaults to
+	// DefaultDedupMaxEntries.
+	MaxEntries int
+}
+
+// Dedup wraps logger so that identical entries (same level and message) are
+// written once per window. When the window closes and repeats were
+// suppressed, a single summary entry with the original message and fields
+// plus RepeatedKey, FirstSeenKey and LastSeenKey is written. Loggers derived
+// with With share the suppression state. Fatal and Panic are never
+// suppressed.
+func Dedup(logger ForLogging, window time.Duration) ForLogging {
+	return DedupWithOptions(logger, DedupOptions{Window: window})
+}
+
+// DedupWithOptions is like Dedup with explicit options.
+func DedupWithOptions(logger ForLogging, opts DedupOptions) ForLogging {
+	if logger == nil {
+		logger = noopLogger{}
+	}
+	if opts.Window <= 0 {
+		opts.Window = DefaultDedupWindow
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = DefaultDedupMaxEntries
+	}
+	state := &dedupState{
+		window:     opts.Window,
+		keys:       slices.Clone(opts.Keys),
+		maxEntries: opts.MaxEntries,
+		entries:    make(map[string]*dedupEntry),
+	}
+	return state.logger(logger, nil)
+}
+
+// FlushDedup writes the pending summaries of a logger returned by Dedup (or
+// derived from one) without waiting for their windows to close, for example
+// before the process exits. Other loggers are ignored.
+func FlushDedup(logger ForLogging) {
+	if d, ok := logger.(dedupLogger); ok {
+		d.state.flush()
+	}
+}
+
+type dedupEntry struct {
+	first time.Time
+	last  time.Time
+	count int
+	emit  func(extra ...any)
+	timer *time.Timer
+}
+
+type dedupState struct {
+	window     time.Duration
+	keys       []string
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*dedupEntry
+}
+
+// observe reports whether the entry with fingerprint key should be written.
+// For a new fingerprint newEmit is called to capture a function that writes
+// the entry with extra fields appended, used for the summary.
+func (s *dedupState) observe(key string, newEmit func() func(extra ...any)) bool {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, ok := s.entries[key]; ok {
+		entry.count++
+		entry.last = now
+		return false
+	}
+	if len(s.entries) >= s.maxEntries {
+		return true
+	}
+	entry := &dedupEntry{first: now, last: now, emit: newEmit()}
+	entry.timer = time.AfterFunc(s.window, func() { s.close(key, entry) })
+	s.entries[key] = entry
+	return true
+}
+
+func (s *dedupState) close(key string, entry *dedupEntry) {
+	s.mu.Lock()
+	if s.entries[key] != entry {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.entries, key)
+	count, first, last := entry.count, entry.first, entry.last
+	s.mu.Unlock()
+	if count > 0 {
+		entry.emit(RepeatedKey, count, FirstSeenKey, first, LastSeenKey, last)
+	}
+}
+
+func (s *dedupState) flush() {
+	s.mu.Lock()
+	pending := make(map[string]*dedupEntry, len(s.entries))
+	for key, entry := range s.entries {
+		entry.timer.Stop()
+		pending[key] = entry
+	}
+	s.mu.Unlock()
+	for key, entry := range pending {
+		s.close(key, entry)
+	}
+}
+
+// fingerprint combines level, message and the selected keys found in fields
+// and keyvals.
+func (s *dedupState) fingerprint(level Level, msg string, fields, keyvals []any) string {
+	var b strings.Builder
+	b.WriteString(LevelString(level))
+	b.WriteByte(0)
+	b.WriteString(msg)
+	if len(s.keys) == 0 {
+		return b.String()
+	}
+	for _, kvs := range [][]any{fields, keyvals} {
+		for i := 0; i < len(kvs); i++ {
+			switch kv := kvs[i].(type) {
+			case slog.Attr:
+				if slices.Contains(s.keys, kv.Key) {
+					fmt.Fprintf(&b, "\x00%s=%v", kv.Key, kv.Value)
+				}
+			case string:
+				if i+1 < len(kvs) && slices.Contains(s.keys, kv) {
+					fmt.Fprintf(&b, "\x00%s=%v", kv, kvs[i+1])
+				}
+				i++
+			default:
+				i++
+			}
+		}
+	}
+	return b.String()
+}
+
+// dedupLogger is the ForLogging returned by Dedup. fields holds the With
+// keyvals of derived loggers so selected keys can be fingerprinted.
+type dedupLogger struct {
+	middleware
+	state  *dedupState
+	fields []any
+}
+
+// logger wraps target; fields are the keyvals attached with With and
+// WithAttrs, which count towards the fingerprint.
+func (s *dedupState) logger(target ForLogging, fields []any) ForLogging {
+	wrap := func(target ForLogging) ForLogging { return s.logger(target, fields) }
+	return dedupLogger{middleware: middleware{target: target, wrap: wrap}, state: s, fields: fields}
+}
+
+func (l dedupLogger) With(keyvals ...any) ForLogging {
+	if len(keyvals) == 0 {
+		return l
+	}
+	fields := make([]any, 0, len(l.fields)+len(keyvals))
+	fields = append(append(fields, l.fields...), keyvals...)
+	return l.state.logger(l.target.With(keyvals...), fields)
+}
+
+func (l dedupLogger) Logp(level Level, msg string, keyvals ...any) {
+	if terminates(level) {
+		l.target.Logp(level, msg, keyvals...)
+		return
+	}
+	target := l.target
+	newEmit := func() func(extra ...any) {
+		keyvals := slices.Clone(keyvals)
+		return func(extra ...any) { target.Logp(level, msg, append(keyvals, extra...)...) }
+	}
+	if l.state.observe(l.state.fingerprint(level, msg, l.fields, keyvals), newEmit) {
+		target.Logp(level, msg, keyvals...)
+	}
+}
+
+func (l dedupLogger) Logf(level Level, format string, v ...any) {
+	l.Logp(level, fmt.Sprintf(format, v...))
+}
+
+func (l dedupLogger) Logs(level string, msg string, keyvals ...any) {
+	parsed, ok := ParseLevel(level)
+	if !ok {
+		parsed = NoLevel
+	}
+	l.Logp(parsed, msg, keyvals...)
+}
+
+func (l dedupLogger) Log(ctx context.Context, level slog.Level, msg string, keyvals ...any) {
+	if terminates(LevelFromSlog(level)) {
+		l.target.Log(ctx, level, msg, keyvals...)
+		return
+	}
+	target := l.target
+	newEmit := func() func(extra ...any) {
+		keyvals := slices.Clone(keyvals)
+		return func(extra ...any) { target.Log(ctx, level, msg, append(keyvals, extra...)...) }
+	}
+	if l.state.observe(l.state.fingerprint(LevelFromSlog(level), msg, l.fields, keyvals), newEmit) {
+		target.Log(ctx, level, msg, keyvals...)
+	}
+}
+
+func (l dedupLogger) Trace(msg string, keyvals ...any) { l.Logp(TraceLevel, msg, keyvals...) }
+func (l dedupLogger) Debug(msg string, keyvals ...any) { l.Logp(DebugLevel, msg, keyvals...) }
+func (l dedupLogger) Info(msg string, keyvals ...any)  { l.Logp(InfoLevel, msg, keyvals...) }
+func (l dedupLogger) Warn(msg string, keyvals ...any)  { l.Logp(WarnLevel, msg, keyvals...) }
+func (l dedupLogger) Error(msg string, keyvals ...any) { l.Logp(ErrorLevel, msg, keyvals...) }
+func (l dedupLogger) Fatal(msg string, keyvals ...any) { l.target.Fatal(msg, keyvals...) }
+func (l dedupLogger) Panic(msg string, keyvals ...any) { l.target.Panic(msg, keyvals...) }
+
+func (l dedupLogger) Tracef(format string, v ...any) { l.Logf(TraceLevel, format, v...) }
+func (l dedupLogger) Debugf(format string, v ...any) { l.Logf(DebugLevel, format, v...) }
+func (l dedupLogger) Infof(format string, v ...any)  { l.Logf(InfoLevel, format, v...) }
+func (l dedupLogger) Warnf(format string, v ...any)  { l.Logf(WarnLevel, format, v...) }
+func (l dedupLogger) Errorf(format string, v ...any) { l.Logf(ErrorLevel, format, v...) }
+func (l dedupLogger) Fatalf(format string, v ...any) { l.target.Fatalf(format, v...) }
+func (l dedupLogger) Panicf(format string, v ...any) { l.target.Panicf(format, v...) }
+
+func (l dedupLogger) Write(p []byte) (int, error) {
+	return WriteToLogger(l, p)
+}
+
+func (l dedupLogger) Handle(ctx context.Context, record slog.Record) error {
+	if terminates(LevelFromSlog(record.Level)) {
+		return l.target.Handle(ctx, record)
+	}
+	var keyvals []any
+	if len(l.state.keys) > 0 {
+		record.Attrs(func(attr slog.Attr) bool {
+			keyvals = append(keyvals, attr.Key, attr.Value.Any())
+			return true
+		})
+	}
+	target := l.target
+	newEmit := func() func(extra ...any) {
+		summary := record.Clone()
+		return func(extra ...any) {
+			summary.Time = time.Now()
+			summary.Add(extra...)
+			_ = target.Handle(ctx, summary)
+		}
+	}
+	if l.state.observe(l.state.fingerprint(LevelFromSlog(record.Level), record.Message, l.fields, keyvals), newEmit) {
+		return target.Handle(ctx, record)
+	}
+	return nil
+}
+
+func (l dedupLogger) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := slices.Clone(l.fields)
+	for _, attr := range attrs {
+		fields = append(fields, attr.Key, attr.Value.Any())
+	}
+	return l.rewrap(l.target.WithAttrs(attrs), func(target ForLogging) ForLogging {
+		return l.state.logger(target, fields)
+	})
+}
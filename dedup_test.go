@@ -0,0 +1,114 @@
+package logport_test
+
+import (
+	"testing"
+	"time"
+
+	logport "pkt.systems/logport"
+	psladapter "pkt.systems/logport/adapters/psl"
+)
+
+func TestDedupSuppressesRepeatsAndSummarises(t *testing.T) {
+	buf := &lockedBuffer{}
+	logger := logport.Dedup(psladapter.NewWithOptions(buf, psladapter.Options{Mode: psladapter.ModeStructured, DisableTimestamp: true, NoColor: true}), time.Hour)
+
+	for i := 0; i < 1000; i++ {
+		logger.Error("storage.load_meta.error", "attempt", i)
+	}
+	logger.Warn("storage.load_meta.error")
+	if records := decodeRecords(t, buf.Bytes()); len(records) != 2 {
+		t.Fatalf("expected first occurrence per level only, got %s", buf.String())
+	}
+
+	logport.FlushDedup(logger)
+	records := decodeRecords(t, buf.Bytes())
+	if len(records) != 3 {
+		t.Fatalf("expected one summary, got %s", buf.String())
+	}
+	first, summary := records[0], records[2]
+	if first["attempt"] != float64(0) || first[logport.RepeatedKey] != nil {
+		t.Fatalf("unexpected first entry %v", first)
+	}
+	if summary["msg"] != "storage.load_meta.error" || summary["lvl"] != "error" || summary[logport.RepeatedKey] != float64(999) {
+		t.Fatalf("unexpected summary %v", summary)
+	}
+	if summary[logport.FirstSeenKey] == nil || summary[logport.LastSeenKey] == nil {
+		t.Fatalf("expected first_seen and last_seen, got %v", summary)
+	}
+
+	logger.Error("storage.load_meta.error")
+	if records := decodeRecords(t, buf.Bytes()); len(records) != 4 {
+		t.Fatalf("expected a new window after the summary, got %s", buf.String())
+	}
+}
+
+func TestDedupWindowClosesOnItsOwn(t *testing.T) {
+	buf := &lockedBuffer{}
+	logger := logport.Dedup(psladapter.NewWithOptions(buf, psladapter.Options{Mode: psladapter.ModeStructured, DisableTimestamp: true, NoColor: true}), 20*time.Millisecond)
+
+	logger.Info("tick")
+	logger.Info("tick")
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		records := decodeRecords(t, buf.Bytes())
+		if len(records) == 2 {
+			if records[1][logport.RepeatedKey] != float64(1) {
+				t.Fatalf("unexpected summary %v", records[1])
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("summary not written, got %s", buf.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDedupKeysAndWithDerivedLoggers(t *testing.T) {
+	buf := &lockedBuffer{}
+	base := psladapter.NewWithOptions(buf, psladapter.Options{Mode: psladapter.ModeStructured, DisableTimestamp: true, NoColor: true})
+	logger := logport.DedupWithOptions(base, logport.DedupOptions{Window: time.Hour, Keys: []string{"bucket"}})
+
+	a := logger.With("bucket", "a")
+	b := logger.With("bucket", "b", "component", "store")
+	a.Error("load failed")
+	b.Error("load failed")
+	a.Error("load failed", "attempt", 2)
+	logger.Error("load failed", "bucket", "b")
+	logport.FlushDedup(b)
+
+	records := decodeRecords(t, buf.Bytes())
+	if len(records) != 4 {
+		t.Fatalf("expected two firsts and two summaries, got %s", buf.String())
+	}
+	if records[0]["bucket"] != "a" || records[1]["bucket"] != "b" || records[1]["component"] != "store" {
+		t.Fatalf("expected derived fields on first occurrences, got %v and %v", records[0], records[1])
+	}
+	for _, summary := range records[2:] {
+		if summary[logport.RepeatedKey] != float64(1) || summary["bucket"] == nil {
+			t.Fatalf("unexpected summary %v", summary)
+		}
+	}
+}
+
+func TestDedupNeverSuppressesFatal(t *testing.T) {
+	buf := &lockedBuffer{}
+	exits := 0
+	logger := logport.Dedup(psladapter.NewWithOptions(buf, psladapter.Options{
+		Mode:             psladapter.ModeStructured,
+		DisableTimestamp: true,
+		NoColor:          true,
+		ExitFunc:         func(int) { exits++ },
+	}), time.Hour)
+
+	logger.Logp(logport.FatalLevel, "disk gone")
+	logger.Logs("fatal", "disk gone")
+	logger.Logf(logport.FatalLevel, "disk %s", "gone")
+	if exits != 3 {
+		t.Fatalf("expected every fatal entry to exit on the caller's goroutine, got %d exits", exits)
+	}
+	logport.FlushDedup(logger)
+	if records := decodeRecords(t, buf.Bytes()); len(records) != 3 || exits != 3 {
+		t.Fatalf("expected three entries and no summary, got %d exits and %s", exits, buf.String())
+	}
+}
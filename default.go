@@ -0,0 +1,152 @@
+package logport
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+type defaultLogger struct {
+	logger ForLogging
+}
+
+var (
+	defaultHolder          atomic.Pointer[defaultLogger]
+	contextFallbackDefault atomic.Bool
+)
+
+// SetDefault installs logger as the process-wide default used by the
+// package-level logging functions (Info, Errorf, …) and, when enabled with
+// SetContextFallback, by LoggerFromContext. A nil logger restores the silent
+// NoopLogger. It returns the previous default.
+//
+// The package-level functions pass their caller's location to logger, which
+// slog handlers with AddSource and the AddSource middleware report. Caller
+// reporting native to a backend (zap.AddCaller, zerolog's Caller) counts
+// stack frames instead and points into logport; wrap such a logger with
+// AddSource rather than relying on it.
+func SetDefault(logger ForLogging) (previous ForLogging) {
+	var next *defaultLogger
+	if logger != nil {
+		next = &defaultLogger{logger: logger}
+	}
+	if prev := defaultHolder.Swap(next); prev != nil {
+		return prev.logger
+	}
+	return noopLogger{}
+}
+
+// Default returns the logger installed with SetDefault, or a NoopLogger when
+// none has been set.
+func Default() ForLogging {
+	if holder := defaultHolder.Load(); holder != nil {
+		return holder.logger
+	}
+	return noopLogger{}
+}
+
+// SetContextFallback controls what LoggerFromContext returns for a context
+// without a logger: Default() when enabled, a NoopLogger otherwise (the
+// initial behaviour). It returns the previous setting.
+func SetContextFallback(useDefault bool) (previous bool) {
+	return contextFallbackDefault.Swap(useDefault)
+}
+
+// Trace logs msg at TraceLevel through Default().
+func Trace(msg string, keyvals ...any) { logDefault(TraceLevel, msg, keyvals) }
+
+// Debug logs msg at DebugLevel through Default().
+func Debug(msg string, keyvals ...any) { logDefault(DebugLevel, msg, keyvals) }
+
+// Info logs msg at InfoLevel through Default().
+func Info(msg string, keyvals ...any) { logDefault(InfoLevel, msg, keyvals) }
+
+// Warn logs msg at WarnLevel through Default().
+func Warn(msg string, keyvals ...any) { logDefault(WarnLevel, msg, keyvals) }
+
+// Error logs msg at ErrorLevel through Default().
+func Error(msg string, keyvals ...any) { logDefault(ErrorLevel, msg, keyvals) }
+
+// Fatal logs msg at FatalLevel through Default() and terminates the process.
+func Fatal(msg string, keyvals ...any) { Default().Fatal(msg, keyvals...) }
+
+// Panic logs msg at PanicLevel through Default() and panics, subject to the
+// logger's PanicPolicy.
+func Panic(msg string, keyvals ...any) { Default().Panic(msg, keyvals...) }
+
+// Tracef logs a formatted message at TraceLevel through Default().
+func Tracef(format string, v ...any) { logDefaultf(TraceLevel, format, v) }
+
+// Debugf logs a formatted message at DebugLevel through Default().
+func Debugf(format string, v ...any) { logDefaultf(DebugLevel, format, v) }
+
+// Infof logs a formatted message at InfoLevel through Default().
+func Infof(format string, v ...any) { logDefaultf(InfoLevel, format, v) }
+
+// Warnf logs a formatted message at WarnLevel through Default().
+func Warnf(format string, v ...any) { logDefaultf(WarnLevel, format, v) }
+
+// Errorf logs a formatted message at ErrorLevel through Default().
+func Errorf(format string, v ...any) { logDefaultf(ErrorLevel, format, v) }
+
+// Fatalf logs a formatted message at FatalLevel through Default() and
+// terminates the process.
+func Fatalf(format string, v ...any) { Default().Fatalf(format, v...) }
+
+// Panicf logs a formatted message at PanicLevel through Default() and panics,
+// subject to the logger's PanicPolicy.
+func Panicf(format string, v ...any) { Default().Panicf(format, v...) }
+
+// logDefault hands the entry to Default() as a slog.Record whose PC is the
+// caller of the package-level function, so handlers reporting the source
+// location from the record (slog's AddSource, the AddSource middleware) point
+// at user code rather than at this file. It must be called directly from the
+// exported function.
+func logDefault(level Level, msg string, keyvals []any) {
+	logger := Default()
+	ctx := context.Background()
+	slevel := slogLevel(level)
+	if !logger.Enabled(ctx, slevel) {
+		return
+	}
+	var pcs [1]uintptr
+	// Skip runtime.Callers, logDefault and the exported function.
+	runtime.Callers(3, pcs[:])
+	record := slog.NewRecord(time.Now(), slevel, msg, pcs[0])
+	record.Add(keyvals...)
+	_ = logger.Handle(ctx, record)
+}
+
+// logDefaultf is logDefault for the formatting variants.
+func logDefaultf(level Level, format string, v []any) {
+	logger := Default()
+	ctx := context.Background()
+	slevel := slogLevel(level)
+	if !logger.Enabled(ctx, slevel) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:])
+	record := slog.NewRecord(time.Now(), slevel, fmt.Sprintf(format, v...), pcs[0])
+	_ = logger.Handle(ctx, record)
+}
+
+// slogLevel maps the levels below FatalLevel onto slog levels that
+// LevelFromSlog maps back unchanged.
+func slogLevel(level Level) slog.Level {
+	switch level {
+	case TraceLevel:
+		return slog.LevelDebug - 4
+	case DebugLevel:
+		return slog.LevelDebug
+	case WarnLevel:
+		return slog.LevelWarn
+	case ErrorLevel:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
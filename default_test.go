@@ -0,0 +1,126 @@
+package logport_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	logport "pkt.systems/logport"
+	psladapter "pkt.systems/logport/adapters/psl"
+	sloggeradapter "pkt.systems/logport/adapters/slogger"
+)
+
+func TestPackageLevelFunctionsUseDefault(t *testing.T) {
+	var buf bytes.Buffer
+	minLevel := logport.DebugLevel
+	logger := sloggeradapter.NewWithOptions(&buf, sloggeradapter.Options{
+		JSON:           true,
+		MinLevel:       &minLevel,
+		HandlerOptions: slog.HandlerOptions{AddSource: true, Level: slog.LevelDebug - 4},
+	})
+	previous := logport.SetDefault(logger)
+	defer logport.SetDefault(previous)
+
+	logport.Info("hello", "k", 1)
+	logport.Errorf("failed %d times", 3)
+	logport.Trace("filtered out")
+
+	var records []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var record map[string]any
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("decode %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 entries, got %s", buf.String())
+	}
+	if records[0]["msg"] != "hello" || records[0]["level"] != "INFO" || records[0]["k"] != float64(1) {
+		t.Fatalf("unexpected first entry %v", records[0])
+	}
+	if records[1]["msg"] != "failed 3 times" || records[1]["level"] != "ERROR" {
+		t.Fatalf("unexpected second entry %v", records[1])
+	}
+	for _, record := range records {
+		source, _ := record["source"].(map[string]any)
+		file, _ := source["file"].(string)
+		if filepath.Base(file) != "default_test.go" {
+			t.Fatalf("expected caller in default_test.go, got %v", record["source"])
+		}
+	}
+}
+
+func TestDefaultIsNoopUntilSet(t *testing.T) {
+	previous := logport.SetDefault(nil)
+	defer logport.SetDefault(previous)
+
+	if logport.Default() != logport.NoopLogger() {
+		t.Fatalf("expected NoopLogger default")
+	}
+	logport.Info("dropped")
+	logport.Warnf("dropped %d", 1)
+}
+
+func TestLoggerFromContextFallsBackToDefault(t *testing.T) {
+	var buf bytes.Buffer
+	logger := psladapter.NewWithOptions(&buf, psladapter.Options{Mode: psladapter.ModeStructured, DisableTimestamp: true, NoColor: true})
+	previous := logport.SetDefault(logger)
+	defer logport.SetDefault(previous)
+
+	logport.LoggerFromContext(context.Background()).Info("dropped")
+	if buf.Len() != 0 {
+		t.Fatalf("expected noop fallback by default, got %q", buf.String())
+	}
+
+	prevFallback := logport.SetContextFallback(true)
+	defer logport.SetContextFallback(prevFallback)
+	logport.LoggerFromContext(context.Background()).Info("via default")
+	if !strings.Contains(buf.String(), `"via default"`) {
+		t.Fatalf("expected entry through default, got %q", buf.String())
+	}
+}
+
+func TestPackageLevelFatalUsesExitPolicy(t *testing.T) {
+	var buf bytes.Buffer
+	var code int
+	logger := psladapter.NewWithOptions(&buf, psladapter.Options{
+		Mode: psladapter.ModeStructured, DisableTimestamp: true, NoColor: true,
+		ExitFunc: func(c int) { code = c },
+	})
+	previous := logport.SetDefault(logger)
+	defer logport.SetDefault(previous)
+
+	logport.Fatalf("giving up after %d", 2)
+	if code != 1 || !strings.Contains(buf.String(), "giving up after 2") {
+		t.Fatalf("expected fatal entry and exit 1, got code=%d out=%q", code, buf.String())
+	}
+}
+
+func TestPackageLevelFunctionsReportCallerOnEveryBackend(t *testing.T) {
+	for _, name := range logport.Backends() {
+		var buf bytes.Buffer
+		logger, err := logport.Open(name, &buf, logport.CommonOptions{Structured: true, AddSource: true})
+		if err != nil {
+			t.Fatalf("%s: open: %v", name, err)
+		}
+		previous := logport.SetDefault(logger)
+		logport.Info("plain")
+		logport.Warnf("formatted %d", 1)
+		logport.SetDefault(previous)
+
+		records := decodeRecords(t, buf.Bytes())
+		if len(records) != 2 {
+			t.Fatalf("%s: expected 2 entries, got %q", name, buf.String())
+		}
+		for _, record := range records {
+			if source, _ := record[logport.SourceKey].(string); !strings.HasPrefix(source, "default_test.go:") {
+				t.Errorf("%s: expected this file as source, got %v", name, record)
+			}
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package logport
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultExitHookTimeout bounds how long Exit waits for registered exit hooks
+// before terminating the process anyway.
+const DefaultExitHookTimeout = 5 * time.Second
+
+// ExitFunc terminates the process with the supplied status code. os.Exit is
+// the canonical implementation; tests substitute functions that record the
+// code instead.
+type ExitFunc func(code int)
+
+// PanicPolicy decides what an adapter's Panic and Panicf do once the entry has
+// been written.
+type PanicPolicy uint8
+
+const (
+	// PanicDefault defers to the process-wide policy installed with
+	// SetPanicPolicy, which is PanicRaise unless changed.
+	PanicDefault PanicPolicy = iota
+	// PanicRaise logs at PanicLevel and then panics with the message, matching
+	// every backend's native behaviour.
+	PanicRaise
+	// PanicContinue logs the entry at ErrorLevel and returns, turning Panic
+	// into error-and-continue. Intended for production builds where a stray
+	// Panic call must not take the process down.
+	PanicContinue
+)
+
+// ExitPolicy carries the Fatal and Panic behaviour of an adapter. Adapters
+// store one built from their ExitFunc and PanicPolicy options; the zero value
+// exits through the process-wide exit function and follows the process-wide
+// panic policy.
+type ExitPolicy struct {
+	// ExitFunc, when non-nil, replaces the process-wide exit function.
+	ExitFunc ExitFunc
+	// PanicPolicy overrides the process-wide panic policy unless it is
+	// PanicDefault.
+	PanicPolicy PanicPolicy
+}
+
+// Exit runs the registered exit hooks and then terminates through p.ExitFunc,
+// falling back to the process-wide exit function.
+func (p ExitPolicy) Exit(code int) {
+	exitWith(p.ExitFunc, code)
+}
+
+// PanicContinues reports whether Panic should log at ErrorLevel and return
+// instead of panicking.
+func (p ExitPolicy) PanicContinues() bool {
+	policy := p.PanicPolicy
+	if policy == PanicDefault {
+		exitMu.Lock()
+		policy = panicPolicy
+		exitMu.Unlock()
+	}
+	return policy == PanicContinue
+}
+
+type exitHook struct {
+	id int
+	fn func()
+}
+
+var (
+	exitMu          sync.Mutex
+	exitHooks       []exitHook
+	exitHookSeq     int
+	exitHookTimeout          = DefaultExitHookTimeout
+	exitFunc        ExitFunc = os.Exit
+	panicPolicy              = PanicRaise
+)
+
+// RegisterExitHook registers hook to run before the process terminates via
+// Exit, a Fatal call or RecoverExit. Hooks run in reverse registration order,
+// like deferred calls, typically to flush sinks or close listeners. A panicking
+// hook does not prevent the remaining hooks from running. The returned
+// function removes the hook again.
+func RegisterExitHook(hook func()) (unregister func()) {
+	if hook == nil {
+		return func() {}
+	}
+	exitMu.Lock()
+	exitHookSeq++
+	id := exitHookSeq
+	exitHooks = append(exitHooks, exitHook{id: id, fn: hook})
+	exitMu.Unlock()
+	return func() {
+		exitMu.Lock()
+		defer exitMu.Unlock()
+		for i, h := range exitHooks {
+			if h.id == id {
+				exitHooks = append(exitHooks[:i:i], exitHooks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// SetExitHookTimeout bounds the total time Exit waits for exit hooks. A zero
+// or negative timeout restores DefaultExitHookTimeout.
+func SetExitHookTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultExitHookTimeout
+	}
+	exitMu.Lock()
+	exitHookTimeout = timeout
+	exitMu.Unlock()
+}
+
+// SetExitFunc replaces the process-wide exit function used by Exit and by
+// adapters without their own ExitFunc option. Passing nil restores os.Exit.
+// The previous function is returned so tests can restore it.
+func SetExitFunc(fn ExitFunc) (previous ExitFunc) {
+	if fn == nil {
+		fn = os.Exit
+	}
+	exitMu.Lock()
+	defer exitMu.Unlock()
+	previous, exitFunc = exitFunc, fn
+	return previous
+}
+
+// SetPanicPolicy installs the process-wide PanicPolicy consulted by adapters
+// whose own policy is PanicDefault. PanicDefault restores PanicRaise.
+func SetPanicPolicy(policy PanicPolicy) {
+	if policy == PanicDefault {
+		policy = PanicRaise
+	}
+	exitMu.Lock()
+	panicPolicy = policy
+	exitMu.Unlock()
+}
+
+// Exit runs the registered exit hooks, waiting at most the exit hook timeout,
+// and then terminates the process through the process-wide exit function.
+func Exit(code int) {
+	exitWith(nil, code)
+}
+
+func exitWith(fn ExitFunc, code int) {
+	exitMu.Lock()
+	hooks := make([]exitHook, len(exitHooks))
+	copy(hooks, exitHooks)
+	timeout := exitHookTimeout
+	if fn == nil {
+		fn = exitFunc
+	}
+	exitMu.Unlock()
+
+	runExitHooks(hooks, timeout)
+	fn(code)
+}
+
+func runExitHooks(hooks []exitHook, timeout time.Duration) {
+	if len(hooks) == 0 {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := len(hooks) - 1; i >= 0; i-- {
+			func() {
+				defer func() { _ = recover() }()
+				hooks[i].fn()
+			}()
+		}
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,86 @@
+package logport
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestExitRunsHooksInReverseOrderBeforeExiting(t *testing.T) {
+	var calls []string
+	defer SetExitFunc(SetExitFunc(func(code int) { calls = append(calls, "exit") }))
+
+	defer RegisterExitHook(func() { calls = append(calls, "first") })()
+	defer RegisterExitHook(func() { panic("hook failure") })()
+	defer RegisterExitHook(func() { calls = append(calls, "last") })()
+	unregister := RegisterExitHook(func() { calls = append(calls, "removed") })
+	unregister()
+
+	Exit(3)
+
+	want := []string{"last", "first", "exit"}
+	if !slices.Equal(calls, want) {
+		t.Fatalf("expected %v, got %v", want, calls)
+	}
+}
+
+func TestExitHookTimeoutBoundsSlowHooks(t *testing.T) {
+	exited := false
+	defer SetExitFunc(SetExitFunc(func(int) { exited = true }))
+	SetExitHookTimeout(20 * time.Millisecond)
+	defer SetExitHookTimeout(0)
+
+	release := make(chan struct{})
+	defer close(release)
+	defer RegisterExitHook(func() { <-release })()
+
+	start := time.Now()
+	Exit(1)
+	if !exited {
+		t.Fatalf("expected exit function to run after the timeout")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected hooks to be abandoned after the timeout, took %s", elapsed)
+	}
+}
+
+func TestExitPolicyPrefersOwnExitFunc(t *testing.T) {
+	global, own := 0, 0
+	defer SetExitFunc(SetExitFunc(func(code int) { global = code }))
+
+	ExitPolicy{ExitFunc: func(code int) { own = code }}.Exit(4)
+	if own != 4 || global != 0 {
+		t.Fatalf("expected policy exit func to be used, got own=%d global=%d", own, global)
+	}
+	ExitPolicy{}.Exit(5)
+	if global != 5 {
+		t.Fatalf("expected process-wide exit func, got %d", global)
+	}
+}
+
+func TestPanicPolicyResolution(t *testing.T) {
+	defer SetPanicPolicy(PanicDefault)
+
+	if (ExitPolicy{}).PanicContinues() {
+		t.Fatalf("expected PanicRaise by default")
+	}
+	SetPanicPolicy(PanicContinue)
+	if !(ExitPolicy{}).PanicContinues() {
+		t.Fatalf("expected process-wide PanicContinue to apply")
+	}
+	if (ExitPolicy{PanicPolicy: PanicRaise}).PanicContinues() {
+		t.Fatalf("expected adapter policy to override the process-wide one")
+	}
+
+	// noopLogger follows the process-wide policy as well.
+	NoopLogger().Panic("ignored")
+}
+
+func TestNoopLoggerFatalUsesExitFunc(t *testing.T) {
+	code := 0
+	defer SetExitFunc(SetExitFunc(func(c int) { code = c }))
+	NoopLogger().Fatal("bye")
+	if code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+}
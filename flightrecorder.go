@@ -0,0 +1,284 @@
+package logport
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultFlightRecorderCapacity is the number of entries a flight recorder
+	// scope keeps before overwriting the oldest.
+	DefaultFlightRecorderCapacity = 256
+
+	// BufferedKey is the structured logging key marking entries a flight
+	// recorder held back and flushed after a trigger.
+	BufferedKey = "buffered"
+)
+
+// FlightRecorderOptions configures StartFlightRecorder.
+type FlightRecorderOptions struct {
+	// Threshold is the lowest level passed straight through; entries below it
+	// are buffered unless the logger would write them anyway. Defaults to
+	// InfoLevel, so trace and debug entries are held back from a logger
+	// running at InfoLevel.
+	Threshold *Level
+
+	// Trigger is the level at which buffered entries are flushed ahead of the
+	// triggering entry. Defaults to ErrorLevel.
+	Trigger *Level
+
+	// Capacity bounds the ring of buffered entries. Defaults to
+	// DefaultFlightRecorderCapacity.
+	Capacity int
+}
+
+// StartFlightRecorder returns a logger scoped to one unit of work (a request,
+// a job) together with the function that ends the scope. Entries below
+// Threshold that logger would not write are kept in a bounded ring instead;
+// those it would write, such as debug entries at DebugLevel, go straight
+// through. The first
+// entry at or above Trigger flushes the ring through logger in order, each
+// entry marked with BufferedKey and its original time as OriginalTimeKey, and
+// then writes the triggering entry; buffering continues afterwards. Calling
+// end discards whatever is still buffered; from then on the scoped logger
+// writes everything straight through. Flushed entries bypass the minimum
+// level set with LogLevel, which is what makes the debug context visible when
+// running at InfoLevel. A level configured inside the backend itself (zap's
+// core level, slog's HandlerOptions.Level) still applies, so leave those at
+// their most verbose and select InfoLevel through LogLevel instead:
+//
+//	scoped, end := logport.StartFlightRecorder(logger.With("request_id", id), logport.FlightRecorderOptions{})
+//	defer end()
+func StartFlightRecorder(logger ForLogging, opts FlightRecorderOptions) (scoped ForLogging, end func()) {
+	if logger == nil {
+		logger = noopLogger{}
+	}
+	scope := &flightScope{threshold: InfoLevel, trigger: ErrorLevel}
+	if opts.Threshold != nil {
+		scope.threshold = *opts.Threshold
+	}
+	if opts.Trigger != nil {
+		scope.trigger = *opts.Trigger
+	}
+	capacity := opts.Capacity
+	if capacity <= 0 {
+		capacity = DefaultFlightRecorderCapacity
+	}
+	scope.ring = make([]bufferedEntry, capacity)
+	return scope.logger(logger), scope.end
+}
+
+// ContextWithFlightRecorder starts a flight recorder scope around logger and
+// stores the scoped logger in the returned context, so LoggerFromContext
+// picks it up for the rest of the request.
+func ContextWithFlightRecorder(ctx context.Context, logger ForLogging, opts FlightRecorderOptions) (context.Context, func()) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	scoped, end := StartFlightRecorder(logger, opts)
+	return ContextWithLogger(ctx, scoped), end
+}
+
+type bufferedEntry struct {
+	target  ForLogging
+	time    time.Time
+	level   Level
+	msg     string
+	keyvals []any
+	ctx     context.Context
+	record  *slog.Record
+}
+
+// flightScope is the ring shared by every logger derived from one
+// StartFlightRecorder call.
+type flightScope struct {
+	threshold Level
+	trigger   Level
+
+	mu    sync.Mutex
+	ring  []bufferedEntry
+	start int
+	count int
+	ended bool
+}
+
+func (s *flightScope) end() {
+	s.mu.Lock()
+	s.ended = true
+	s.ring = nil
+	s.start, s.count = 0, 0
+	s.mu.Unlock()
+}
+
+// buffers reports whether an entry at level should be held back: it is below
+// the threshold and target would not write it.
+func (s *flightScope) buffers(ctx context.Context, target ForLogging, level Level) bool {
+	return s.below(level) && !target.Enabled(ctx, slogLevel(level))
+}
+
+// below reports whether level is under the threshold.
+func (s *flightScope) below(level Level) bool {
+	return level < s.threshold && level != NoLevel && level != Disabled
+}
+
+// triggers reports whether an entry at level flushes the ring.
+func (s *flightScope) triggers(level Level) bool {
+	return level >= s.trigger && level != NoLevel && level != Disabled
+}
+
+// push stores entry and reports whether it was buffered; it is not once the
+// scope has ended.
+func (s *flightScope) push(entry bufferedEntry) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return false
+	}
+	idx := (s.start + s.count) % len(s.ring)
+	s.ring[idx] = entry
+	if s.count < len(s.ring) {
+		s.count++
+	} else {
+		s.start = (s.start + 1) % len(s.ring)
+	}
+	return true
+}
+
+// flush writes the buffered entries in order and empties the ring.
+func (s *flightScope) flush() {
+	s.mu.Lock()
+	if s.count == 0 {
+		s.mu.Unlock()
+		return
+	}
+	entries := make([]bufferedEntry, 0, s.count)
+	for i := 0; i < s.count; i++ {
+		idx := (s.start + i) % len(s.ring)
+		entries = append(entries, s.ring[idx])
+		s.ring[idx] = bufferedEntry{}
+	}
+	s.start, s.count = 0, 0
+	s.mu.Unlock()
+
+	for _, entry := range entries {
+		target := entry.target.LogLevel(TraceLevel)
+		if entry.record != nil {
+			record := *entry.record
+			record.AddAttrs(slog.Bool(BufferedKey, true), slog.Time(OriginalTimeKey, entry.time))
+			_ = target.Handle(entry.ctx, record)
+			continue
+		}
+		keyvals := append(entry.keyvals, BufferedKey, true, OriginalTimeKey, entry.time)
+		target.Logp(entry.level, entry.msg, keyvals...)
+	}
+}
+
+// flightLogger is the ForLogging returned by StartFlightRecorder. Derived
+// loggers share the scope and remember their own target so flushed entries
+// keep the fields they were logged with.
+type flightLogger struct {
+	middleware
+	scope *flightScope
+}
+
+func (s *flightScope) logger(target ForLogging) ForLogging {
+	return flightLogger{middleware: middleware{target: target, wrap: s.logger}, scope: s}
+}
+
+func (l flightLogger) Logp(level Level, msg string, keyvals ...any) {
+	if l.scope.buffers(context.Background(), l.target, level) {
+		entry := bufferedEntry{target: l.target, time: time.Now(), level: level, msg: msg}
+		if len(keyvals) > 0 {
+			entry.keyvals = append([]any(nil), keyvals...)
+		}
+		if l.scope.push(entry) {
+			return
+		}
+	} else if l.scope.triggers(level) {
+		l.scope.flush()
+	}
+	l.target.Logp(level, msg, keyvals...)
+}
+
+func (l flightLogger) Logf(level Level, format string, v ...any) {
+	l.Logp(level, fmt.Sprintf(format, v...))
+}
+
+func (l flightLogger) Logs(level string, msg string, keyvals ...any) {
+	parsed, ok := ParseLevel(level)
+	if !ok {
+		parsed = NoLevel
+	}
+	l.Logp(parsed, msg, keyvals...)
+}
+
+func (l flightLogger) Log(ctx context.Context, level slog.Level, msg string, keyvals ...any) {
+	lvl := LevelFromSlog(level)
+	if l.scope.buffers(ctx, l.target, lvl) {
+		l.Logp(lvl, msg, keyvals...)
+		return
+	}
+	if l.scope.triggers(lvl) {
+		l.scope.flush()
+	}
+	l.target.Log(ctx, level, msg, keyvals...)
+}
+
+func (l flightLogger) Trace(msg string, keyvals ...any) { l.Logp(TraceLevel, msg, keyvals...) }
+func (l flightLogger) Debug(msg string, keyvals ...any) { l.Logp(DebugLevel, msg, keyvals...) }
+func (l flightLogger) Info(msg string, keyvals ...any)  { l.Logp(InfoLevel, msg, keyvals...) }
+func (l flightLogger) Warn(msg string, keyvals ...any)  { l.Logp(WarnLevel, msg, keyvals...) }
+func (l flightLogger) Error(msg string, keyvals ...any) { l.Logp(ErrorLevel, msg, keyvals...) }
+
+func (l flightLogger) Fatal(msg string, keyvals ...any) {
+	l.scope.flush()
+	l.target.Fatal(msg, keyvals...)
+}
+
+func (l flightLogger) Panic(msg string, keyvals ...any) {
+	l.scope.flush()
+	l.target.Panic(msg, keyvals...)
+}
+
+func (l flightLogger) Tracef(format string, v ...any) { l.Logf(TraceLevel, format, v...) }
+func (l flightLogger) Debugf(format string, v ...any) { l.Logf(DebugLevel, format, v...) }
+func (l flightLogger) Infof(format string, v ...any)  { l.Logf(InfoLevel, format, v...) }
+func (l flightLogger) Warnf(format string, v ...any)  { l.Logf(WarnLevel, format, v...) }
+func (l flightLogger) Errorf(format string, v ...any) { l.Logf(ErrorLevel, format, v...) }
+
+func (l flightLogger) Fatalf(format string, v ...any) {
+	l.scope.flush()
+	l.target.Fatalf(format, v...)
+}
+
+func (l flightLogger) Panicf(format string, v ...any) {
+	l.scope.flush()
+	l.target.Panicf(format, v...)
+}
+
+func (l flightLogger) Write(p []byte) (int, error) {
+	return WriteToLogger(l, p)
+}
+
+func (l flightLogger) Enabled(ctx context.Context, level slog.Level) bool {
+	return l.scope.below(LevelFromSlog(level)) || l.target.Enabled(ctx, level)
+}
+
+func (l flightLogger) Handle(ctx context.Context, record slog.Record) error {
+	level := LevelFromSlog(record.Level)
+	if l.scope.buffers(ctx, l.target, level) {
+		clone := record.Clone()
+		if l.scope.push(bufferedEntry{target: l.target, time: record.Time, ctx: ctx, record: &clone}) {
+			return nil
+		}
+		if !l.target.Enabled(ctx, record.Level) {
+			return nil
+		}
+	} else if l.scope.triggers(level) {
+		l.scope.flush()
+	}
+	return l.target.Handle(ctx, record)
+}
@@ -0,0 +1,141 @@
+package logport_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	logport "pkt.systems/logport"
+	psladapter "pkt.systems/logport/adapters/psl"
+)
+
+func decodeRecords(t *testing.T, data []byte) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("decode %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestFlightRecorderFlushesOnError(t *testing.T) {
+	var buf bytes.Buffer
+	logger := psladapter.NewWithOptions(&buf, psladapter.Options{Mode: psladapter.ModeStructured, DisableTimestamp: true, NoColor: true}).LogLevel(logport.InfoLevel)
+
+	scoped, end := logport.StartFlightRecorder(logger.With("request_id", "r1"), logport.FlightRecorderOptions{})
+	defer end()
+
+	scoped.Debug("loaded config", "n", 1)
+	scoped.With("step", "db").Trace("query planned")
+	slog.New(scoped).Debug("via slog")
+	scoped.Info("accepted")
+	if records := decodeRecords(t, buf.Bytes()); len(records) != 1 || records[0]["msg"] != "accepted" {
+		t.Fatalf("expected only the info entry before the trigger, got %s", buf.String())
+	}
+
+	scoped.Error("query failed")
+	records := decodeRecords(t, buf.Bytes())
+	want := []struct{ msg, lvl string }{
+		{"accepted", "info"},
+		{"loaded config", "debug"},
+		{"query planned", "trace"},
+		{"via slog", "debug"},
+		{"query failed", "error"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("expected %d entries, got %s", len(want), buf.String())
+	}
+	for i, w := range want {
+		record := records[i]
+		if record["msg"] != w.msg || record["lvl"] != w.lvl || record["request_id"] != "r1" {
+			t.Fatalf("entry %d: expected %v, got %v", i, w, record)
+		}
+		buffered := i >= 1 && i <= 3
+		if (record[logport.BufferedKey] == true) != buffered {
+			t.Fatalf("entry %d: expected buffered=%v, got %v", i, buffered, record)
+		}
+		if _, ok := record[logport.OriginalTimeKey]; ok != buffered {
+			t.Fatalf("entry %d: expected original time only on buffered entries, got %v", i, record)
+		}
+	}
+	if records[2]["step"] != "db" {
+		t.Fatalf("expected derived fields on flushed entry, got %v", records[2])
+	}
+}
+
+func TestFlightRecorderWritesWhatTheTargetWouldWrite(t *testing.T) {
+	var buf bytes.Buffer
+	logger := psladapter.NewWithOptions(&buf, psladapter.Options{Mode: psladapter.ModeStructured, DisableTimestamp: true, NoColor: true}).LogLevel(logport.DebugLevel)
+
+	scoped, end := logport.StartFlightRecorder(logger, logport.FlightRecorderOptions{})
+	scoped.Debug("loaded config")
+	slog.New(scoped).Debug("via slog")
+	scoped.Trace("query planned")
+	end()
+
+	records := decodeRecords(t, buf.Bytes())
+	if len(records) != 2 || records[0]["msg"] != "loaded config" || records[1]["msg"] != "via slog" {
+		t.Fatalf("expected the debug entries written straight through, got %s", buf.String())
+	}
+	for _, record := range records {
+		if _, ok := record[logport.BufferedKey]; ok {
+			t.Fatalf("expected debug entries not to be buffered, got %v", record)
+		}
+	}
+}
+
+func TestFlightRecorderDiscardsOnEnd(t *testing.T) {
+	var buf bytes.Buffer
+	logger := psladapter.NewWithOptions(&buf, psladapter.Options{Mode: psladapter.ModeStructured, DisableTimestamp: true, NoColor: true}).LogLevel(logport.InfoLevel)
+
+	ctx, end := logport.ContextWithFlightRecorder(context.Background(), logger, logport.FlightRecorderOptions{Capacity: 2})
+	scoped := logport.LoggerFromContext(ctx)
+	scoped.Debug("one")
+	scoped.Debug("two")
+	scoped.Debug("three")
+	scoped.Warn("still fine")
+	end()
+	scoped.Error("after end")
+
+	records := decodeRecords(t, buf.Bytes())
+	if len(records) != 2 || records[0]["msg"] != "still fine" || records[1]["msg"] != "after end" {
+		t.Fatalf("expected buffered entries to be discarded, got %s", buf.String())
+	}
+}
+
+func TestFlightRecorderRingKeepsNewest(t *testing.T) {
+	var buf bytes.Buffer
+	logger := psladapter.NewWithOptions(&buf, psladapter.Options{Mode: psladapter.ModeStructured, DisableTimestamp: true, NoColor: true}).LogLevel(logport.InfoLevel)
+
+	scoped, end := logport.StartFlightRecorder(logger, logport.FlightRecorderOptions{Capacity: 2})
+	defer end()
+	scoped.Debug("one")
+	scoped.Debug("two")
+	scoped.Debug("three")
+	scoped.Errorf("failed %d", 1)
+	scoped.Error("again")
+
+	records := decodeRecords(t, buf.Bytes())
+	var msgs []any
+	for _, record := range records {
+		msgs = append(msgs, record["msg"])
+	}
+	want := []any{"two", "three", "failed 1", "again"}
+	if len(msgs) != len(want) {
+		t.Fatalf("expected %v, got %v", want, msgs)
+	}
+	for i := range want {
+		if msgs[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, msgs)
+		}
+	}
+}
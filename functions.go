@@ -5,23 +5,18 @@ import "strings"
 const httpTLSHandshakePrefix = "http: TLS handshake error"
 
 func classifyLogLine(raw string) (Level, string) {
-	trimmed := strings.TrimSpace(raw)
-	if trimmed == "" {
-		return NoLevel, ""
-	}
-
-	if strings.HasPrefix(trimmed, httpTLSHandshakePrefix) {
-		msg := strings.TrimSpace(trimmed[len(httpTLSHandshakePrefix):])
-		msg = strings.TrimLeft(msg, ": ")
-		return ErrorLevel, msg
-	}
+	return defaultClassifier.Classify(raw)
+}
 
+// classifyLevelToken detects a bracketed ("[WARN] msg"), leading ("warn msg")
+// or delimited ("warn:msg") level token at the start of trimmed.
+func classifyLevelToken(trimmed string) (Level, string, bool) {
 	if strings.HasPrefix(trimmed, "[") {
 		if idx := strings.Index(trimmed, "]"); idx > 1 {
 			token := trimmed[1:idx]
 			if level, ok := levelFromToken(token); ok {
 				msg := strings.TrimSpace(trimmed[idx+1:])
-				return level, msg
+				return level, msg, true
 			}
 		}
 	}
@@ -31,30 +26,17 @@ func classifyLogLine(raw string) (Level, string) {
 		msg := strings.TrimLeft(rest, " \t")
 		msg = strings.TrimPrefix(msg, ":")
 		msg = strings.TrimLeft(msg, " \t-|:")
-		return level, strings.TrimSpace(msg)
+		return level, strings.TrimSpace(msg), true
 	}
 
 	if idx := strings.IndexAny(trimmed, ":|-"); idx > 0 {
 		token := trimmed[:idx]
 		if level, ok := levelFromToken(token); ok {
 			msg := strings.TrimSpace(trimmed[idx+1:])
-			return level, msg
+			return level, msg, true
 		}
 	}
-
-	if idx := indexErrorSubstring(trimmed); idx >= 0 {
-		msg := ""
-		if idx+len(errorNeedle) < len(trimmed) {
-			msg = strings.TrimSpace(trimmed[idx+len(errorNeedle):])
-			msg = strings.TrimLeft(msg, ": ")
-		}
-		if msg == "" {
-			msg = trimmed
-		}
-		return ErrorLevel, msg
-	}
-
-	return NoLevel, trimmed
+	return NoLevel, "", false
 }
 
 func splitLeadingToken(s string) (string, string) {
@@ -5,12 +5,14 @@ go 1.25.2
 require (
 	github.com/charmbracelet/log v0.4.2
 	github.com/francoispqt/onelog v0.0.0-20190306043706-8c2bb31b10a4
+	github.com/muesli/termenv v0.16.0
 	github.com/phuslu/log v1.0.120
 	github.com/rs/zerolog v1.34.0
 	go.opentelemetry.io/otel/trace v1.38.0
 	go.uber.org/zap v1.27.0
 	golang.org/x/sys v0.37.0
 	golang.org/x/term v0.36.0
+	gopkg.in/yaml.v3 v3.0.1
 	pkt.systems/pslog v0.3.0
 )
 
@@ -29,7 +31,6 @@ require (
 	github.com/mattn/go-colorable v0.1.14 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/mattn/go-runewidth v0.0.19 // indirect
-	github.com/muesli/termenv v0.16.0 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
 	github.com/xo/terminfo v0.0.0-20220910002029-abceb7e1c41e // indirect
 	go.opentelemetry.io/otel v1.38.0 // indirect
@@ -0,0 +1,21 @@
+module pkt.systems/logport/grpclog
+
+go 1.25.2
+
+require (
+	google.golang.org/grpc v1.75.1
+	pkt.systems/logport v0.16.0
+)
+
+require (
+	go.opentelemetry.io/otel v1.38.0 // indirect
+	go.opentelemetry.io/otel/trace v1.38.0 // indirect
+	golang.org/x/net v0.41.0 // indirect
+	golang.org/x/sys v0.37.0 // indirect
+	golang.org/x/term v0.36.0 // indirect
+	golang.org/x/text v0.26.0 // indirect
+	google.golang.org/genproto v0.0.0-20250707201910-8d1bb00bc6a7 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250707201910-8d1bb00bc6a7 // indirect
+	google.golang.org/protobuf v1.36.6 // indirect
+	pkt.systems/pslog v0.3.0 // indirect
+)
@@ -0,0 +1,217 @@
+// Package grpclog wires logport into gRPC. The server and client
+// interceptors derive a per-call logger carrying the method, peer and
+// OpenTelemetry trace ids, store it in the call context via
+// logport.ContextWithLogger and log the outcome with the status code and
+// elapsed time:
+//
+//	logger := psl.NewStructured(os.Stderr)
+//	srv := grpc.NewServer(
+//		grpc.ChainUnaryInterceptor(grpclog.UnaryServerInterceptor(logger, grpclog.Options{})),
+//		grpc.ChainStreamInterceptor(grpclog.StreamServerInterceptor(logger, grpclog.Options{})),
+//	)
+//
+// NewLoggerV2 routes grpc-go's internal logging through any adapter:
+//
+//	import grpcinternal "google.golang.org/grpc/grpclog"
+//
+//	grpcinternal.SetLoggerV2(grpclog.NewLoggerV2(logger.With("sys", "grpc")))
+//
+// The package is its own module so that logport itself does not depend on
+// gRPC. It requires a tagged logport release, which must be published before
+// the grpclog tag that depends on it.
+package grpclog
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	logport "pkt.systems/logport"
+)
+
+const (
+	// MethodKey is the structured logging key carrying the full gRPC method.
+	MethodKey = "method"
+	// PeerKey is the structured logging key carrying the remote address.
+	PeerKey = "peer"
+	// CodeKey is the structured logging key carrying the gRPC status code.
+	CodeKey = "code"
+	// ElapsedKey is the structured logging key carrying the call duration.
+	ElapsedKey = "elapsed"
+	// ErrorKey is the structured logging key carrying the status message of
+	// failed calls.
+	ErrorKey = "error"
+
+	// StartMessage is logged (at TraceLevel) when Options.LogStart is set.
+	StartMessage = "grpc.request.start"
+	// CompleteMessage is logged by the server interceptors once a call ends.
+	CompleteMessage = "grpc.request.complete"
+	// ClientCompleteMessage is logged by the client interceptors once a call
+	// ends.
+	ClientCompleteMessage = "client.grpc.complete"
+)
+
+// Options configures the interceptors.
+type Options struct {
+	// Exclude, when non-nil, reports whether calls to fullMethod (e.g.
+	// "/grpc.health.v1.Health/Check") are served without start and completion
+	// entries. The derived logger is still attached to the context.
+	Exclude func(fullMethod string) bool
+
+	// LogStart emits a StartMessage entry at TraceLevel before the call runs.
+	LogStart bool
+
+	// Level, when non-nil, selects the level of the completion entry from the
+	// status code. Defaults to LevelForCode.
+	Level func(code codes.Code) logport.Level
+}
+
+// LevelForCode maps a gRPC status code to a log level: OK logs at InfoLevel,
+// codes caused by the caller (InvalidArgument, NotFound, PermissionDenied, …)
+// at WarnLevel and server-side failures (Internal, Unavailable, DataLoss, …)
+// at ErrorLevel.
+func LevelForCode(code codes.Code) logport.Level {
+	switch code {
+	case codes.OK:
+		return logport.InfoLevel
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.Unauthenticated, codes.ResourceExhausted,
+		codes.FailedPrecondition, codes.Aborted, codes.OutOfRange:
+		return logport.WarnLevel
+	default:
+		return logport.ErrorLevel
+	}
+}
+
+// UnaryServerInterceptor returns an interceptor that attaches a derived logger
+// to the handler context and logs CompleteMessage when the handler returns.
+func UnaryServerInterceptor(logger logport.ForLogging, opts Options) grpc.UnaryServerInterceptor {
+	c := newCallLogger(logger, opts)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		callLogger, skip := c.begin(ctx, c.logger, info.FullMethod)
+		start := time.Now()
+		resp, err := handler(logport.ContextWithLogger(ctx, callLogger), req)
+		if !skip {
+			c.complete(callLogger, CompleteMessage, err, time.Since(start))
+		}
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns an interceptor that exposes a derived logger
+// through the stream context and logs CompleteMessage when the handler
+// returns.
+func StreamServerInterceptor(logger logport.ForLogging, opts Options) grpc.StreamServerInterceptor {
+	c := newCallLogger(logger, opts)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := ss.Context()
+		callLogger, skip := c.begin(ctx, c.logger, info.FullMethod)
+		start := time.Now()
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: logport.ContextWithLogger(ctx, callLogger)})
+		if !skip {
+			c.complete(callLogger, CompleteMessage, err, time.Since(start))
+		}
+		return err
+	}
+}
+
+// UnaryClientInterceptor returns an interceptor that logs
+// ClientCompleteMessage for every outgoing call. Entries are written to the
+// logger found in the call context, falling back to logger.
+func UnaryClientInterceptor(logger logport.ForLogging, opts Options) grpc.UnaryClientInterceptor {
+	c := newCallLogger(logger, opts)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		callLogger, skip := c.begin(ctx, c.contextLogger(ctx), method)
+		start := time.Now()
+		err := invoker(logport.ContextWithLogger(ctx, callLogger), method, req, reply, cc, callOpts...)
+		if !skip {
+			c.complete(callLogger, ClientCompleteMessage, err, time.Since(start))
+		}
+		return err
+	}
+}
+
+// StreamClientInterceptor returns an interceptor that logs
+// ClientCompleteMessage once an outgoing stream finishes, however it ends:
+// RecvMsg reporting io.EOF or an error, the call context being cancelled or
+// reaching its deadline, or the stream failing to be established. A stream
+// that is neither drained nor cancelled never finishes, in gRPC as in the log.
+func StreamClientInterceptor(logger logport.ForLogging, opts Options) grpc.StreamClientInterceptor {
+	c := newCallLogger(logger, opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		callLogger, skip := c.begin(ctx, c.contextLogger(ctx), method)
+		if skip {
+			return streamer(logport.ContextWithLogger(ctx, callLogger), desc, cc, method, callOpts...)
+		}
+		start := time.Now()
+		var once sync.Once
+		done := func(err error) {
+			once.Do(func() { c.complete(callLogger, ClientCompleteMessage, err, time.Since(start)) })
+		}
+		callOpts = append(callOpts[:len(callOpts):len(callOpts)], grpc.OnFinish(done))
+		cs, err := streamer(logport.ContextWithLogger(ctx, callLogger), desc, cc, method, callOpts...)
+		if err != nil {
+			done(err)
+		}
+		return cs, err
+	}
+}
+
+type callLogger struct {
+	logger logport.ForLogging
+	opts   Options
+}
+
+func newCallLogger(logger logport.ForLogging, opts Options) callLogger {
+	if logger == nil {
+		logger = logport.NoopLogger()
+	}
+	if opts.Level == nil {
+		opts.Level = LevelForCode
+	}
+	return callLogger{logger: logger, opts: opts}
+}
+
+func (c callLogger) contextLogger(ctx context.Context) logport.ForLogging {
+	if logger, ok := logport.LoggerFromContextOK(ctx); ok {
+		return logger
+	}
+	return c.logger
+}
+
+// begin derives the per-call logger and reports whether the call is excluded
+// from start and completion entries.
+func (c callLogger) begin(ctx context.Context, base logport.ForLogging, method string) (logport.ForLogging, bool) {
+	keyvals := []any{MethodKey, method}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		keyvals = append(keyvals, PeerKey, p.Addr.String())
+	}
+	derived := base.With(keyvals...).WithTrace(ctx)
+	skip := c.opts.Exclude != nil && c.opts.Exclude(method)
+	if !skip && c.opts.LogStart {
+		derived.Trace(StartMessage)
+	}
+	return derived, skip
+}
+
+func (c callLogger) complete(logger logport.ForLogging, msg string, err error, elapsed time.Duration) {
+	st := status.Convert(err)
+	keyvals := []any{CodeKey, st.Code().String(), ElapsedKey, elapsed}
+	if st.Code() != codes.OK {
+		keyvals = append(keyvals, ErrorKey, st.Message())
+	}
+	logger.Logp(c.opts.Level(st.Code()), msg, keyvals...)
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
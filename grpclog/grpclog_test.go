@@ -0,0 +1,252 @@
+package grpclog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	logport "pkt.systems/logport"
+	psl "pkt.systems/logport/adapters/psl"
+)
+
+func TestUnaryInterceptorsLogCompletion(t *testing.T) {
+	serverBuf := &syncBuffer{}
+	clientBuf := &syncBuffer{}
+	var handlerSawLogger bool
+
+	conn := startServer(t, newTestLogger(serverBuf), newTestLogger(clientBuf), func(ctx context.Context) {
+		_, handlerSawLogger = logport.LoggerFromContextOK(ctx)
+	})
+
+	client := healthpb.NewHealthClient(conn)
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+
+	if !handlerSawLogger {
+		t.Fatalf("expected handler context to carry a logger")
+	}
+
+	server := decodeJSONLines(t, serverBuf.Bytes())
+	if len(server) != 2 {
+		t.Fatalf("expected 2 server entries, got %d: %s", len(server), serverBuf.String())
+	}
+	ok := server[0]
+	if ok["msg"] != CompleteMessage || ok["lvl"] != "info" || ok[CodeKey] != "OK" {
+		t.Fatalf("unexpected success entry %v", ok)
+	}
+	if ok[MethodKey] != "/grpc.health.v1.Health/Check" {
+		t.Fatalf("expected method field, got %v", ok[MethodKey])
+	}
+	if _, has := ok[PeerKey]; !has {
+		t.Fatalf("expected peer field, got %v", ok)
+	}
+	if _, has := ok[ElapsedKey]; !has {
+		t.Fatalf("expected elapsed field, got %v", ok)
+	}
+	notFound := server[1]
+	if notFound["lvl"] != "warn" || notFound[CodeKey] != "NotFound" || notFound[ErrorKey] == nil {
+		t.Fatalf("unexpected failure entry %v", notFound)
+	}
+
+	client1 := decodeJSONLines(t, clientBuf.Bytes())
+	if len(client1) != 2 || client1[0]["msg"] != ClientCompleteMessage {
+		t.Fatalf("expected 2 client entries, got %s", clientBuf.String())
+	}
+}
+
+func TestStreamInterceptorsLogCompletion(t *testing.T) {
+	serverBuf := &syncBuffer{}
+	clientBuf := &syncBuffer{}
+	conn := startServer(t, newTestLogger(serverBuf), newTestLogger(clientBuf), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := healthpb.NewHealthClient(conn).Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("watch failed: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("recv failed: %v", err)
+	}
+	cancel()
+	if _, err := stream.Recv(); status.Code(err) != codes.Canceled {
+		t.Fatalf("expected Canceled after cancel, got %v", err)
+	}
+
+	client := decodeJSONLines(t, clientBuf.Bytes())
+	if len(client) != 1 || client[0][CodeKey] != "Canceled" || client[0][MethodKey] != "/grpc.health.v1.Health/Watch" {
+		t.Fatalf("unexpected client stream entries %s", clientBuf.String())
+	}
+}
+
+func TestStreamClientInterceptorLogsAbandonedStreams(t *testing.T) {
+	clientBuf := &syncBuffer{}
+	conn := startServer(t, newTestLogger(&syncBuffer{}), newTestLogger(clientBuf), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := healthpb.NewHealthClient(conn).Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("watch failed: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("recv failed: %v", err)
+	}
+	// The stream is cancelled and never read again.
+	cancel()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(clientBuf.String(), ClientCompleteMessage) {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the completion entry")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	client := decodeJSONLines(t, clientBuf.Bytes())
+	if len(client) != 1 || client[0][CodeKey] != "Canceled" {
+		t.Fatalf("unexpected client stream entries %s", clientBuf.String())
+	}
+}
+
+func TestExcludeSkipsEntries(t *testing.T) {
+	buf := &syncBuffer{}
+	interceptor := UnaryServerInterceptor(newTestLogger(buf), Options{
+		Exclude: func(method string) bool { return strings.HasPrefix(method, "/grpc.health.v1.") },
+	})
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"},
+		func(ctx context.Context, req any) (any, error) {
+			logport.LoggerFromContext(ctx).Debug("probe")
+			return nil, nil
+		})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 1 || records[0]["msg"] != "probe" {
+		t.Fatalf("expected only the handler entry, got %s", buf.String())
+	}
+}
+
+func TestLoggerV2ForwardsToAdapter(t *testing.T) {
+	buf := &syncBuffer{}
+	debug := logport.DebugLevel
+	logger := NewLoggerV2WithOptions(newTestLogger(buf), LoggerV2Options{Verbosity: 2, InfoLevel: &debug})
+
+	logger.Infoln("[core]", "channel created")
+	logger.Warningf("retrying %s", "dial")
+	logger.Error("transport", " closed")
+
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 3 {
+		t.Fatalf("expected 3 entries, got %s", buf.String())
+	}
+	if records[0]["lvl"] != "debug" || records[0]["msg"] != "[core] channel created" {
+		t.Fatalf("unexpected info entry %v", records[0])
+	}
+	if records[1]["lvl"] != "warn" || records[1]["msg"] != "retrying dial" {
+		t.Fatalf("unexpected warning entry %v", records[1])
+	}
+	if records[2]["lvl"] != "error" || records[2]["msg"] != "transport closed" {
+		t.Fatalf("unexpected error entry %v", records[2])
+	}
+	if !logger.V(2) || logger.V(3) {
+		t.Fatalf("expected verbosity 2")
+	}
+}
+
+// --- helpers ---
+
+type checkHook func(context.Context)
+
+type hookedHealth struct {
+	*health.Server
+	hook checkHook
+}
+
+func (h hookedHealth) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if h.hook != nil {
+		h.hook(ctx)
+	}
+	return h.Server.Check(ctx, req)
+}
+
+func startServer(t *testing.T, serverLogger, clientLogger logport.ForLogging, hook checkHook) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(UnaryServerInterceptor(serverLogger, Options{})),
+		grpc.ChainStreamInterceptor(StreamServerInterceptor(serverLogger, Options{})),
+	)
+	healthpb.RegisterHealthServer(srv, hookedHealth{Server: health.NewServer(), hook: hook})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(UnaryClientInterceptor(clientLogger, Options{})),
+		grpc.WithChainStreamInterceptor(StreamClientInterceptor(clientLogger, Options{})),
+	)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func newTestLogger(w *syncBuffer) logport.ForLogging {
+	return psl.NewWithOptions(w, psl.Options{Mode: psl.ModeStructured, DisableTimestamp: true, NoColor: true})
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}
+
+func (b *syncBuffer) String() string {
+	return string(b.Bytes())
+}
+
+func decodeJSONLines(t *testing.T, data []byte) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("failed decoding json %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
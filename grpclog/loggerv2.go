@@ -0,0 +1,73 @@
+package grpclog
+
+import (
+	"fmt"
+	"strings"
+
+	grpcinternal "google.golang.org/grpc/grpclog"
+	logport "pkt.systems/logport"
+)
+
+// LoggerV2Options configures the grpclog.LoggerV2 returned by
+// NewLoggerV2WithOptions.
+type LoggerV2Options struct {
+	// Verbosity is the maximum verbosity reported by V, mirroring
+	// GRPC_GO_LOG_VERBOSITY_LEVEL. Defaults to 0.
+	Verbosity int
+
+	// InfoLevel, when non-nil, is the logport level used for grpc-go's Info
+	// output, which is chatty; DebugLevel is a common choice. Defaults to
+	// InfoLevel.
+	InfoLevel *logport.Level
+}
+
+// NewLoggerV2 returns a grpclog.LoggerV2 that forwards grpc-go's internal
+// logs to logger. Warning, Error and Fatal map onto the matching logport
+// levels; Fatal terminates the process through the adapter.
+func NewLoggerV2(logger logport.ForLogging) grpcinternal.LoggerV2 {
+	return NewLoggerV2WithOptions(logger, LoggerV2Options{})
+}
+
+// NewLoggerV2WithOptions is like NewLoggerV2 but applies opts.
+func NewLoggerV2WithOptions(logger logport.ForLogging, opts LoggerV2Options) grpcinternal.LoggerV2 {
+	if logger == nil {
+		logger = logport.NoopLogger()
+	}
+	infoLevel := logport.InfoLevel
+	if opts.InfoLevel != nil {
+		infoLevel = *opts.InfoLevel
+	}
+	return loggerV2{logger: logger, verbosity: opts.Verbosity, infoLevel: infoLevel}
+}
+
+type loggerV2 struct {
+	logger    logport.ForLogging
+	verbosity int
+	infoLevel logport.Level
+}
+
+func (l loggerV2) Info(args ...any)                 { l.logger.Logp(l.infoLevel, fmt.Sprint(args...)) }
+func (l loggerV2) Infoln(args ...any)               { l.logger.Logp(l.infoLevel, sprintln(args...)) }
+func (l loggerV2) Infof(format string, args ...any) { l.logger.Logf(l.infoLevel, format, args...) }
+
+func (l loggerV2) Warning(args ...any)                 { l.logger.Warn(fmt.Sprint(args...)) }
+func (l loggerV2) Warningln(args ...any)               { l.logger.Warn(sprintln(args...)) }
+func (l loggerV2) Warningf(format string, args ...any) { l.logger.Warnf(format, args...) }
+
+func (l loggerV2) Error(args ...any)                 { l.logger.Error(fmt.Sprint(args...)) }
+func (l loggerV2) Errorln(args ...any)               { l.logger.Error(sprintln(args...)) }
+func (l loggerV2) Errorf(format string, args ...any) { l.logger.Errorf(format, args...) }
+
+func (l loggerV2) Fatal(args ...any)                 { l.logger.Fatal(fmt.Sprint(args...)) }
+func (l loggerV2) Fatalln(args ...any)               { l.logger.Fatal(sprintln(args...)) }
+func (l loggerV2) Fatalf(format string, args ...any) { l.logger.Fatalf(format, args...) }
+
+func (l loggerV2) V(level int) bool {
+	return level <= l.verbosity
+}
+
+func sprintln(args ...any) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
+
+var _ grpcinternal.LoggerV2 = loggerV2{}
@@ -0,0 +1,289 @@
+package logport
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SourceKey is the structured logging key carrying the file:line found in
+	// a klog/glog or log.Lshortfile header, or added by AddSource.
+	SourceKey = "source"
+	// HostKey is the structured logging key carrying a syslog HOSTNAME.
+	HostKey = "host"
+	// AppKey is the structured logging key carrying a syslog APP-NAME.
+	AppKey = "app"
+	// MsgIDKey is the structured logging key carrying a syslog MSGID.
+	MsgIDKey = "msgid"
+	// StructuredDataKey is the structured logging key carrying the raw
+	// STRUCTURED-DATA of an RFC 5424 syslog message.
+	StructuredDataKey = "structured_data"
+)
+
+// timeNow is replaced in tests to pin the year klog headers are placed in.
+var timeNow = time.Now
+
+// parseLineHeader strips a recognised header from line: RFC 5424 (and bare
+// RFC 3164 priority) syslog, klog/glog, the log package's LstdFlags layouts
+// and RFC 3339 or "2006-01-02 15:04:05" timestamps. level is NoLevel when the
+// header carries no severity.
+func parseLineHeader(line string) (level Level, rest string, keyvals []any, ok bool) {
+	if level, rest, keyvals, ok := parseSyslogHeader(line); ok {
+		return level, rest, keyvals, true
+	}
+	if level, rest, keyvals, ok := parseKlogHeader(line); ok {
+		return level, rest, keyvals, true
+	}
+	if rest, keyvals, ok := parseStdlibHeader(line); ok {
+		return NoLevel, rest, keyvals, true
+	}
+	if ts, rest, ok := parseTimestampPrefix(line); ok {
+		return NoLevel, rest, []any{OriginalTimeKey, ts}, true
+	}
+	return NoLevel, line, nil, false
+}
+
+// parseSyslogHeader handles "<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID
+// STRUCTURED-DATA MSG" and, for anything else starting with "<PRI>", takes
+// the severity and leaves the remainder as the message.
+func parseSyslogHeader(line string) (Level, string, []any, bool) {
+	if len(line) < 3 || line[0] != '<' {
+		return NoLevel, "", nil, false
+	}
+	end := strings.IndexByte(line, '>')
+	if end < 2 || end > 4 {
+		return NoLevel, "", nil, false
+	}
+	pri, err := strconv.Atoi(line[1:end])
+	if err != nil || pri < 0 || pri > 191 {
+		return NoLevel, "", nil, false
+	}
+	level := syslogSeverityLevel(pri & 7)
+	rest := line[end+1:]
+	if !strings.HasPrefix(rest, "1 ") {
+		return level, strings.TrimSpace(rest), nil, true
+	}
+
+	fields := strings.SplitN(rest[2:], " ", 6)
+	if len(fields) < 5 {
+		return level, strings.TrimSpace(rest[2:]), nil, true
+	}
+	var keyvals []any
+	if ts := fields[0]; ts != "-" {
+		if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+			keyvals = append(keyvals, OriginalTimeKey, t)
+		} else {
+			keyvals = append(keyvals, OriginalTimeKey, ts)
+		}
+	}
+	for i, key := range []string{HostKey, AppKey, PidKey, MsgIDKey} {
+		value := fields[i+1]
+		if value == "-" {
+			continue
+		}
+		if key == PidKey {
+			if pid, err := strconv.Atoi(value); err == nil {
+				keyvals = append(keyvals, key, pid)
+				continue
+			}
+		}
+		keyvals = append(keyvals, key, value)
+	}
+	msg := ""
+	if len(fields) == 6 {
+		msg = fields[5]
+	}
+	if strings.HasPrefix(msg, "-") {
+		msg = msg[1:]
+	} else if strings.HasPrefix(msg, "[") {
+		sdEnd := structuredDataEnd(msg)
+		if sdEnd < 0 {
+			return NoLevel, "", nil, false
+		}
+		keyvals = append(keyvals, StructuredDataKey, msg[:sdEnd])
+		msg = msg[sdEnd:]
+	}
+	msg = strings.TrimPrefix(strings.TrimSpace(msg), "\ufeff")
+	return level, msg, keyvals, true
+}
+
+// structuredDataEnd returns the index just past the last SD-ELEMENT at the
+// start of s, honouring escaped characters inside PARAM-VALUEs.
+func structuredDataEnd(s string) int {
+	i := 0
+	for i < len(s) && s[i] == '[' {
+		quoted := false
+		for i++; i < len(s); i++ {
+			c := s[i]
+			if quoted && c == '\\' {
+				i++
+				continue
+			}
+			if c == '"' {
+				quoted = !quoted
+				continue
+			}
+			if c == ']' && !quoted {
+				break
+			}
+		}
+		if i >= len(s) {
+			return -1
+		}
+		i++
+	}
+	return i
+}
+
+// syslogSeverityLevel maps RFC 5424 severities onto Level, consistent with
+// the "crit" and "alert" level tokens.
+func syslogSeverityLevel(severity int) Level {
+	switch severity {
+	case 0, 1:
+		return PanicLevel
+	case 2:
+		return FatalLevel
+	case 3:
+		return ErrorLevel
+	case 4:
+		return WarnLevel
+	case 5, 6:
+		return InfoLevel
+	default:
+		return DebugLevel
+	}
+}
+
+// parseKlogHeader handles "Lmmdd hh:mm:ss.uuuuuu threadid file:line] msg".
+// klog omits the year; the current one is assumed unless that places the
+// entry more than a day in the future.
+func parseKlogHeader(line string) (Level, string, []any, bool) {
+	if len(line) < 22 || line[5] != ' ' {
+		return NoLevel, "", nil, false
+	}
+	level, ok := levelFromLetter(line[:1])
+	if !ok || level == TraceLevel || level == DebugLevel || !isDigits(line[1:5]) {
+		return NoLevel, "", nil, false
+	}
+	clockEnd := strings.IndexByte(line[6:], ' ')
+	if clockEnd < 0 {
+		return NoLevel, "", nil, false
+	}
+	clockEnd += 6
+	now := timeNow()
+	ts, err := time.ParseInLocation("0102 15:04:05.999999", line[1:clockEnd], time.Local)
+	if err != nil {
+		return NoLevel, "", nil, false
+	}
+	ts = ts.AddDate(now.Year(), 0, 0)
+	if ts.After(now.Add(24 * time.Hour)) {
+		ts = ts.AddDate(-1, 0, 0)
+	}
+
+	rest := strings.TrimLeft(line[clockEnd:], " ")
+	threadEnd := strings.IndexByte(rest, ' ')
+	if threadEnd <= 0 || !isDigits(rest[:threadEnd]) {
+		return NoLevel, "", nil, false
+	}
+	rest = rest[threadEnd+1:]
+	sourceEnd := strings.Index(rest, "] ")
+	if sourceEnd < 0 {
+		if !strings.HasSuffix(rest, "]") {
+			return NoLevel, "", nil, false
+		}
+		sourceEnd = len(rest) - 1
+	}
+	source := rest[:sourceEnd]
+	if !isSourceLocation(source) {
+		return NoLevel, "", nil, false
+	}
+	msg := strings.TrimSpace(rest[sourceEnd+1:])
+	return level, msg, []any{OriginalTimeKey, ts, SourceKey, source}, true
+}
+
+// parseStdlibHeader handles the log package's "2009/11/10 23:00:00" header,
+// with optional Lmicroseconds and an Lshortfile or Llongfile location.
+func parseStdlibHeader(line string) (string, []any, bool) {
+	if len(line) < 19 || line[4] != '/' || line[7] != '/' || line[10] != ' ' {
+		return "", nil, false
+	}
+	end := 19
+	if len(line) > end && line[end] == '.' {
+		end++
+		for end < len(line) && line[end] >= '0' && line[end] <= '9' {
+			end++
+		}
+	}
+	ts, err := time.ParseInLocation("2006/01/02 15:04:05.999999", line[:end], time.Local)
+	if err != nil {
+		return "", nil, false
+	}
+	keyvals := []any{OriginalTimeKey, ts}
+	rest := strings.TrimLeft(line[end:], " ")
+	if idx := strings.Index(rest, ": "); idx > 0 && isSourceLocation(rest[:idx]) {
+		keyvals = append(keyvals, SourceKey, rest[:idx])
+		rest = rest[idx+2:]
+	}
+	return strings.TrimSpace(rest), keyvals, true
+}
+
+// parseTimestampPrefix handles a leading RFC 3339 timestamp or its
+// space-separated "2006-01-02 15:04:05[.fff][Z07:00]" variant.
+func parseTimestampPrefix(line string) (time.Time, string, bool) {
+	if len(line) < 19 || line[4] != '-' || line[7] != '-' || (line[10] != 'T' && line[10] != ' ') {
+		return time.Time{}, "", false
+	}
+	end := 19
+	if len(line) > end && (line[end] == '.' || line[end] == ',') {
+		end++
+		for end < len(line) && line[end] >= '0' && line[end] <= '9' {
+			end++
+		}
+	}
+	stamp := line[:end]
+	if stamp[19:] != "" && stamp[19] == ',' {
+		stamp = stamp[:19] + "." + stamp[20:]
+	}
+	stamp = stamp[:10] + "T" + stamp[11:]
+	zoneEnd := end
+	if zoneEnd < len(line) {
+		switch c := line[zoneEnd]; {
+		case c == 'Z':
+			zoneEnd++
+		case (c == '+' || c == '-') && zoneEnd+6 <= len(line) && line[zoneEnd+3] == ':':
+			zoneEnd += 6
+		}
+	}
+	var (
+		ts  time.Time
+		err error
+	)
+	if zoneEnd > end {
+		ts, err = time.Parse(time.RFC3339Nano, stamp+line[end:zoneEnd])
+	} else {
+		ts, err = time.ParseInLocation("2006-01-02T15:04:05.999999999", stamp, time.Local)
+	}
+	if err != nil || (zoneEnd < len(line) && line[zoneEnd] != ' ') {
+		return time.Time{}, "", false
+	}
+	return ts, strings.TrimSpace(line[zoneEnd:]), true
+}
+
+// isSourceLocation reports whether s looks like "file.go:42".
+func isSourceLocation(s string) bool {
+	idx := strings.LastIndexByte(s, ':')
+	return idx > 0 && idx < len(s)-1 && isDigits(s[idx+1:]) && !strings.ContainsAny(s[:idx], " \t")
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
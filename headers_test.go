@@ -0,0 +1,167 @@
+package logport
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClassifyFieldsParsesHeaders(t *testing.T) {
+	previous := timeNow
+	timeNow = func() time.Time { return time.Date(2024, 10, 20, 12, 0, 0, 0, time.Local) }
+	defer func() { timeNow = previous }()
+
+	classifier := DefaultClassifier().(FieldClassifier)
+	cases := []struct {
+		name   string
+		input  string
+		level  Level
+		msg    string
+		time   time.Time
+		fields map[string]any
+	}{
+		{
+			name:  "stdlib",
+			input: "2009/11/10 23:00:00 server started",
+			level: NoLevel,
+			msg:   "server started",
+			time:  time.Date(2009, 11, 10, 23, 0, 0, 0, time.Local),
+		},
+		{
+			name:   "stdlib with micros, shortfile and level token",
+			input:  "2009/11/10 23:00:00.123456 main.go:12: ERROR: boom",
+			level:  ErrorLevel,
+			msg:    "boom",
+			time:   time.Date(2009, 11, 10, 23, 0, 0, 123456000, time.Local),
+			fields: map[string]any{SourceKey: "main.go:12"},
+		},
+		{
+			name:  "net/http error log",
+			input: "2009/11/10 23:00:00 http: TLS handshake error from 1.2.3.4:5: EOF",
+			level: ErrorLevel,
+			msg:   "from 1.2.3.4:5: EOF",
+			time:  time.Date(2009, 11, 10, 23, 0, 0, 0, time.Local),
+		},
+		{
+			name:   "klog",
+			input:  "W1016 15:04:05.123456    1234 reflector.go:42] watch closed",
+			level:  WarnLevel,
+			msg:    "watch closed",
+			time:   time.Date(2024, 10, 16, 15, 4, 5, 123456000, time.Local),
+			fields: map[string]any{SourceKey: "reflector.go:42"},
+		},
+		{
+			name:  "klog from last year",
+			input: "E1231 23:59:59.000000 7 main.go:1] late",
+			level: ErrorLevel,
+			msg:   "late",
+			time:  time.Date(2023, 12, 31, 23, 59, 59, 0, time.Local),
+		},
+		{
+			name:  "rfc5424",
+			input: `<134>1 2024-05-01T10:00:00.5Z web01 api 4242 ID47 [origin ip="10.0.0.1"][meta x="a\]b"] request served`,
+			level: InfoLevel,
+			msg:   "request served",
+			time:  time.Date(2024, 5, 1, 10, 0, 0, 500000000, time.UTC),
+			fields: map[string]any{
+				HostKey:           "web01",
+				AppKey:            "api",
+				PidKey:            4242,
+				MsgIDKey:          "ID47",
+				StructuredDataKey: `[origin ip="10.0.0.1"][meta x="a\]b"]`,
+			},
+		},
+		{
+			name:   "rfc5424 without structured data",
+			input:  "<11>1 2024-05-01T10:00:00Z host app - - - disk failed",
+			level:  ErrorLevel,
+			msg:    "disk failed",
+			time:   time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+			fields: map[string]any{HostKey: "host", AppKey: "app"},
+		},
+		{
+			name:  "rfc3339",
+			input: "2024-05-01T10:00:00+02:00 WARN cache cold",
+			level: WarnLevel,
+			msg:   "cache cold",
+			time:  time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "stdlib header before logfmt",
+			input:  "2009/11/10 23:00:00 level=info msg=hello port=8080",
+			level:  InfoLevel,
+			msg:    "hello",
+			time:   time.Date(2009, 11, 10, 23, 0, 0, 0, time.Local),
+			fields: map[string]any{"port": int64(8080)},
+		},
+		{
+			name:   "shortfile header before JSON",
+			input:  `2009/11/10 23:00:00 main.go:12: {"level":"warn","msg":"slow","ms":250}`,
+			level:  WarnLevel,
+			msg:    "slow",
+			time:   time.Date(2009, 11, 10, 23, 0, 0, 0, time.Local),
+			fields: map[string]any{SourceKey: "main.go:12", "ms": int64(250)},
+		},
+		{
+			name:  "syslog priority kept when the record has no level",
+			input: "<11>1 2024-05-01T10:00:00Z host app - - - msg=\"disk failed\"",
+			level: ErrorLevel,
+			msg:   "disk failed",
+			time:  time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "record time replaces the header time",
+			input: "2009/11/10 23:00:00 ts=2024-05-01T10:00:00Z level=info msg=hello",
+			level: InfoLevel,
+			msg:   "hello",
+			time:  time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "space separated timestamp",
+			input: "2024-05-01 10:00:00,250 [INFO] ready",
+			level: InfoLevel,
+			msg:   "ready",
+			time:  time.Date(2024, 5, 1, 10, 0, 0, 250000000, time.Local),
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			level, msg, keyvals := classifier.ClassifyFields(tc.input)
+			if level != tc.level || msg != tc.msg {
+				t.Fatalf("ClassifyFields(%q) = %v %q, want %v %q", tc.input, level, msg, tc.level, tc.msg)
+			}
+			entry := logEntry{keyvals: keyvals}
+			got, ok := entry.field(OriginalTimeKey)
+			if !ok || !got.(time.Time).Equal(tc.time) {
+				t.Fatalf("expected %s=%v, got %v", OriginalTimeKey, tc.time, got)
+			}
+			for key, want := range tc.fields {
+				if got, ok := entry.field(key); !ok || got != want {
+					t.Fatalf("expected %s=%v, got %v (keyvals %v)", key, want, got, keyvals)
+				}
+			}
+		})
+	}
+}
+
+func TestClassifyFieldsLeavesLookalikesAlone(t *testing.T) {
+	classifier := DefaultClassifier().(FieldClassifier)
+	for _, line := range []string{
+		"<html> page",
+		"I1016 is not a klog line",
+		"2009/11/10 not a time",
+		"2024-05-01Tnonsense here",
+	} {
+		if level, msg, keyvals := classifier.ClassifyFields(line); level != NoLevel || msg != line || keyvals != nil {
+			t.Fatalf("ClassifyFields(%q) = %v %q %v, want it untouched", line, level, msg, keyvals)
+		}
+	}
+}
+
+func TestDisableHeadersKeepsTimestamps(t *testing.T) {
+	classifier := NewClassifierBuilder().DisableHeaders().Build()
+	line := "2009/11/10 23:00:00 WARN: slow"
+	level, msg, keyvals := classifier.ClassifyFields(line)
+	if level != NoLevel || msg != line || keyvals != nil {
+		t.Fatalf("expected header to be kept, got %v %q %v", level, msg, keyvals)
+	}
+}
@@ -0,0 +1,267 @@
+// Package httplog wires logport into net/http. Middleware derives a
+// per-request logger, stores it in the request context and emits an access
+// log entry once the handler returns:
+//
+//	logger := psl.NewStructured(os.Stderr)
+//	mux := http.NewServeMux()
+//	mux.HandleFunc("/v1/acquire", func(w http.ResponseWriter, r *http.Request) {
+//		logport.LoggerFromContext(r.Context()).Info("lease.acquire.begin")
+//	})
+//	http.ListenAndServe(":8080", httplog.Middleware(logger, httplog.Options{
+//		ExcludePaths: []string{"/healthz"},
+//	})(mux))
+//
+// Transport does the same for outgoing requests, logging attempts and their
+// outcome through the logger found in the request context.
+package httplog
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+
+	logport "pkt.systems/logport"
+)
+
+const (
+	// RequestIDKey is the structured logging key carrying the request id.
+	RequestIDKey = "req_id"
+	// MethodKey is the structured logging key carrying the HTTP method.
+	MethodKey = "method"
+	// PathKey is the structured logging key carrying the request path.
+	PathKey = "path"
+	// RemoteAddrKey is the structured logging key carrying the peer address.
+	RemoteAddrKey = "remote_addr"
+	// StatusKey is the structured logging key carrying the response status.
+	StatusKey = "status"
+	// BytesKey is the structured logging key carrying the response body size.
+	BytesKey = "bytes"
+	// ElapsedKey is the structured logging key carrying the request duration.
+	ElapsedKey = "elapsed"
+
+	// DefaultRequestIDHeader is the header used to read and propagate request
+	// ids when Options.RequestIDHeader is empty.
+	DefaultRequestIDHeader = "X-Request-Id"
+	// MaxRequestIDLength bounds an incoming request id. Longer ids are
+	// replaced by a generated one.
+	MaxRequestIDLength = 128
+
+	// StartMessage is logged (at TraceLevel) when Options.LogStart is set.
+	StartMessage = "http.request.start"
+	// CompleteMessage is logged once the wrapped handler returns.
+	CompleteMessage = "http.request.complete"
+)
+
+// Options configures Middleware.
+type Options struct {
+	// RequestIDHeader names the header used to read an incoming request id and
+	// to echo the effective id on the response. Defaults to
+	// DefaultRequestIDHeader.
+	RequestIDHeader string
+
+	// IgnoreIncomingID always generates a fresh UUIDv7 instead of propagating
+	// the id supplied by the client. Incoming ids longer than
+	// MaxRequestIDLength or containing anything but ASCII letters, digits and
+	// "-", "_", ".", ":" are replaced regardless.
+	IgnoreIncomingID bool
+
+	// ExcludePaths lists request paths (exact matches, e.g. "/healthz") that
+	// are served without access log entries. The derived logger is still
+	// attached to the request context.
+	ExcludePaths []string
+
+	// Exclude, when non-nil, reports whether r should be served without access
+	// log entries. It is consulted in addition to ExcludePaths.
+	Exclude func(r *http.Request) bool
+
+	// LogStart emits a StartMessage entry at TraceLevel before the handler runs.
+	LogStart bool
+
+	// Level, when non-nil, selects the level of the completion entry from the
+	// response status. Defaults to LevelForStatus.
+	Level func(status int) logport.Level
+}
+
+// Middleware returns net/http middleware that generates or propagates a
+// request id, attaches a derived logger (request id, method, path, remote
+// address and OpenTelemetry trace ids) to the request context via
+// logport.ContextWithLogger, and logs CompleteMessage with status, bytes and
+// elapsed time when the handler returns. A nil logger is replaced by
+// logport.NoopLogger.
+func Middleware(logger logport.ForLogging, opts Options) func(http.Handler) http.Handler {
+	if logger == nil {
+		logger = logport.NoopLogger()
+	}
+	header := opts.RequestIDHeader
+	if header == "" {
+		header = DefaultRequestIDHeader
+	}
+	levelFor := opts.Level
+	if levelFor == nil {
+		levelFor = LevelForStatus
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			reqID := ""
+			if !opts.IgnoreIncomingID {
+				reqID = r.Header.Get(header)
+			}
+			if !validRequestID(reqID) {
+				reqID = newRequestID()
+			}
+			w.Header().Set(header, reqID)
+
+			ctx := r.Context()
+			reqLogger := logger.With(
+				RequestIDKey, reqID,
+				MethodKey, r.Method,
+				PathKey, r.URL.Path,
+				RemoteAddrKey, r.RemoteAddr,
+			).WithTrace(ctx)
+			r = r.WithContext(logport.ContextWithLogger(ctx, reqLogger))
+
+			if excluded(r, opts) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if opts.LogStart {
+				reqLogger.Trace(StartMessage)
+			}
+
+			rec := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+
+			status := rec.statusCode()
+			reqLogger.Logp(levelFor(status), CompleteMessage,
+				StatusKey, status,
+				BytesKey, rec.bytes,
+				ElapsedKey, time.Since(start),
+			)
+		})
+	}
+}
+
+// LevelForStatus maps an HTTP status class to a log level: 5xx responses log
+// at ErrorLevel, 4xx at WarnLevel and everything else at InfoLevel.
+func LevelForStatus(status int) logport.Level {
+	switch {
+	case status >= 500:
+		return logport.ErrorLevel
+	case status >= 400:
+		return logport.WarnLevel
+	default:
+		return logport.InfoLevel
+	}
+}
+
+// validRequestID reports whether id is safe to echo in a response header and
+// log as is.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > MaxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		switch c := id[i]; {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func excluded(r *http.Request, opts Options) bool {
+	if slices.Contains(opts.ExcludePaths, r.URL.Path) {
+		return true
+	}
+	return opts.Exclude != nil && opts.Exclude(r)
+}
+
+// responseRecorder captures the status code and body size written by the
+// wrapped handler.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if !r.wroteHeader {
+		r.status = http.StatusOK
+		r.wroteHeader = true
+	}
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher when the underlying writer supports it.
+func (r *responseRecorder) Flush() {
+	if !r.wroteHeader {
+		r.status = http.StatusOK
+		r.wroteHeader = true
+	}
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker when the underlying writer supports it, so
+// websocket upgrades work behind Middleware. A hijacked response is logged
+// with status 101 Switching Protocols unless a status was already written.
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil && !r.wroteHeader {
+		r.status = http.StatusSwitchingProtocols
+		r.wroteHeader = true
+	}
+	return conn, rw, err
+}
+
+// ReadFrom implements io.ReaderFrom so responses served from files keep using
+// sendfile when the underlying writer supports it.
+func (r *responseRecorder) ReadFrom(src io.Reader) (int64, error) {
+	if !r.wroteHeader {
+		r.status = http.StatusOK
+		r.wroteHeader = true
+	}
+	var n int64
+	var err error
+	if rf, ok := r.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		n, err = io.Copy(struct{ io.Writer }{r.ResponseWriter}, src)
+	}
+	r.bytes += n
+	return n, err
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *responseRecorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
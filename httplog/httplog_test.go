@@ -0,0 +1,255 @@
+package httplog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	logport "pkt.systems/logport"
+	psl "pkt.systems/logport/adapters/psl"
+)
+
+var uuidV7Pattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestMiddlewareGeneratesRequestIDAndLogsCompletion(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := newTestLogger(buf)
+
+	handler := Middleware(logger, Options{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logport.LoggerFromContext(r.Context()).Info("lease.acquire.begin")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/acquire", nil)
+	req.RemoteAddr = "127.0.0.1:49588"
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	reqID := rr.Header().Get(DefaultRequestIDHeader)
+	if !uuidV7Pattern.MatchString(reqID) {
+		t.Fatalf("expected UUIDv7 request id, got %q", reqID)
+	}
+
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 2 {
+		t.Fatalf("expected 2 entries, got %d: %s", len(records), buf.String())
+	}
+	inner := records[0]
+	if inner["msg"] != "lease.acquire.begin" {
+		t.Fatalf("expected handler entry first, got %v", inner["msg"])
+	}
+	if inner[RequestIDKey] != reqID || inner[MethodKey] != "POST" || inner[PathKey] != "/v1/acquire" || inner[RemoteAddrKey] != "127.0.0.1:49588" {
+		t.Fatalf("expected request fields on handler entry, got %v", inner)
+	}
+
+	done := records[1]
+	if done["msg"] != CompleteMessage {
+		t.Fatalf("expected completion entry, got %v", done["msg"])
+	}
+	if done["lvl"] != "info" {
+		t.Fatalf("expected lvl=info, got %v", done["lvl"])
+	}
+	if done[StatusKey] != float64(http.StatusCreated) {
+		t.Fatalf("expected status=201, got %v", done[StatusKey])
+	}
+	if done[BytesKey] != float64(5) {
+		t.Fatalf("expected bytes=5, got %v", done[BytesKey])
+	}
+	if _, ok := done[ElapsedKey]; !ok {
+		t.Fatalf("expected elapsed field, got %v", done)
+	}
+}
+
+func TestMiddlewarePropagatesIncomingRequestID(t *testing.T) {
+	buf := &bytes.Buffer{}
+	handler := Middleware(newTestLogger(buf), Options{RequestIDHeader: "X-Correlation-Id"})(http.NotFoundHandler())
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req.Header.Set("X-Correlation-Id", "abc-123")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if got := rr.Header().Get("X-Correlation-Id"); got != "abc-123" {
+		t.Fatalf("expected propagated id, got %q", got)
+	}
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(records))
+	}
+	if records[0][RequestIDKey] != "abc-123" {
+		t.Fatalf("expected req_id=abc-123, got %v", records[0][RequestIDKey])
+	}
+	if records[0]["lvl"] != "warn" {
+		t.Fatalf("expected 404 to log at warn, got %v", records[0]["lvl"])
+	}
+}
+
+func TestMiddlewareReplacesInvalidRequestIDs(t *testing.T) {
+	for _, incoming := range []string{
+		strings.Repeat("a", MaxRequestIDLength+1),
+		"abc 123",
+		"abc\x1b[31m",
+		"<script>",
+		"id-ünicode",
+	} {
+		buf := &bytes.Buffer{}
+		handler := Middleware(newTestLogger(buf), Options{})(http.NotFoundHandler())
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(DefaultRequestIDHeader, incoming)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		got := rr.Header().Get(DefaultRequestIDHeader)
+		if !uuidV7Pattern.MatchString(got) {
+			t.Fatalf("expected %q to be replaced by a UUIDv7, got %q", incoming, got)
+		}
+		if records := decodeJSONLines(t, buf.Bytes()); records[0][RequestIDKey] != got {
+			t.Fatalf("expected req_id=%s, got %v", got, records[0])
+		}
+	}
+}
+
+func TestMiddlewareForwardsHijackAndReadFrom(t *testing.T) {
+	buf := &lockedBuffer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			t.Errorf("expected the wrapped writer to implement http.Hijacker")
+			return
+		}
+		conn, rw, err := hj.Hijack()
+		if err != nil {
+			t.Errorf("hijack: %v", err)
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: close\r\n\r\n")
+		_ = rw.Flush()
+	})
+	mux.HandleFunc("/file", func(w http.ResponseWriter, r *http.Request) {
+		rf, ok := w.(io.ReaderFrom)
+		if !ok {
+			t.Errorf("expected the wrapped writer to implement io.ReaderFrom")
+			return
+		}
+		if _, err := rf.ReadFrom(strings.NewReader("file body")); err != nil {
+			t.Errorf("ReadFrom: %v", err)
+		}
+	})
+	srv := httptest.NewServer(Middleware(psl.NewWithOptions(buf, psl.Options{Mode: psl.ModeStructured, DisableTimestamp: true, NoColor: true}), Options{})(mux))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/ws")
+	if err != nil {
+		t.Fatalf("GET /ws: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected 101 from the hijacked connection, got %d", resp.StatusCode)
+	}
+	resp, err = http.Get(srv.URL + "/file")
+	if err != nil {
+		t.Fatalf("GET /file: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "file body" {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for bytes.Count(buf.Bytes(), []byte("\n")) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 2 {
+		t.Fatalf("expected two completion entries, got %s", buf.Bytes())
+	}
+	if records[0][PathKey] != "/ws" || records[0][StatusKey] != float64(http.StatusSwitchingProtocols) {
+		t.Fatalf("expected the hijacked request logged with 101, got %v", records[0])
+	}
+	if records[1][PathKey] != "/file" || records[1][BytesKey] != float64(len("file body")) {
+		t.Fatalf("expected ReadFrom bytes counted, got %v", records[1])
+	}
+}
+
+func TestMiddlewareExcludesPaths(t *testing.T) {
+	buf := &bytes.Buffer{}
+	handler := Middleware(newTestLogger(buf), Options{ExcludePaths: []string{"/healthz"}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logport.LoggerFromContext(r.Context()).Debug("probe")
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 1 {
+		t.Fatalf("expected only the handler entry for excluded path, got %q", buf.String())
+	}
+	if records[0]["msg"] != "probe" || records[0][PathKey] != "/healthz" {
+		t.Fatalf("expected derived logger in excluded handler, got %v", records[0])
+	}
+}
+
+func TestLevelForStatus(t *testing.T) {
+	cases := map[int]logport.Level{
+		http.StatusOK:                  logport.InfoLevel,
+		http.StatusMovedPermanently:    logport.InfoLevel,
+		http.StatusBadRequest:          logport.WarnLevel,
+		http.StatusInternalServerError: logport.ErrorLevel,
+	}
+	for status, want := range cases {
+		if got := LevelForStatus(status); got != want {
+			t.Fatalf("LevelForStatus(%d) = %v, want %v", status, got, want)
+		}
+	}
+}
+
+// --- helpers ---
+
+// lockedBuffer is a bytes.Buffer safe for the server goroutines of
+// httptest.Server.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}
+
+func newTestLogger(buf *bytes.Buffer) logport.ForLogging {
+	return psl.NewWithOptions(buf, psl.Options{Mode: psl.ModeStructured, DisableTimestamp: true, NoColor: true})
+}
+
+func decodeJSONLines(t *testing.T, data []byte) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("failed decoding json %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
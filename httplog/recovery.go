@@ -0,0 +1,55 @@
+package httplog
+
+import (
+	"net/http"
+
+	logport "pkt.systems/logport"
+)
+
+// Recovery returns net/http middleware that recovers panics raised by the
+// wrapped handler, logs them with their stack through logport.HandlePanic and
+// answers 500 Internal Server Error. Entries go to the request logger
+// installed by Middleware, falling back to logger, so place Recovery inside
+// Middleware to get request ids on panic entries and a 500 in the access log:
+//
+//	handler := httplog.Middleware(logger, httplog.Options{})(httplog.Recovery(logger)(mux))
+//
+// The request logger already carries the trace ids; with the fallback they are
+// taken from the request context.
+//
+// http.ErrAbortHandler is re-panicked so net/http can abort the response
+// silently as documented.
+func Recovery(logger logport.ForLogging) func(http.Handler) http.Handler {
+	if logger == nil {
+		logger = logport.NoopLogger()
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				value := recover()
+				if value == nil {
+					return
+				}
+				if value == http.ErrAbortHandler {
+					panic(value)
+				}
+				opts := logport.RecoverOptions{Action: logport.RecoverSwallow}
+				reqLogger, ok := logport.LoggerFromContextOK(r.Context())
+				if !ok {
+					// Middleware already added the trace ids to the request
+					// logger; only the fallback needs them.
+					reqLogger = logger
+					opts.Context = r.Context()
+				}
+				logport.HandlePanic(reqLogger, value, opts)
+				if rec, ok := w.(*responseRecorder); ok && rec.wroteHeader {
+					// Too late to change the status; the client sees a
+					// truncated response.
+					return
+				}
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package httplog
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
+	logport "pkt.systems/logport"
+)
+
+func TestRecoveryLogsPanicAndReturns500(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := newTestLogger(buf)
+
+	handler := Middleware(logger, Options{})(Recovery(logger)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("handler exploded")
+	})))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/boom", nil))
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rr.Code)
+	}
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 2 {
+		t.Fatalf("expected panic and completion entries, got %s", buf.String())
+	}
+	panicked := records[0]
+	if panicked["msg"] != logport.PanicMessage || panicked["lvl"] != "panic" {
+		t.Fatalf("unexpected panic entry %v", panicked)
+	}
+	if panicked[logport.PanicKey] != "handler exploded" || panicked[RequestIDKey] == nil {
+		t.Fatalf("expected panic value and request id, got %v", panicked)
+	}
+	if stack, _ := panicked[logport.StackKey].(string); !strings.Contains(stack, "goroutine") {
+		t.Fatalf("expected stack trace, got %v", panicked[logport.StackKey])
+	}
+	if records[1]["msg"] != CompleteMessage || records[1][StatusKey] != float64(http.StatusInternalServerError) {
+		t.Fatalf("expected 500 completion entry, got %v", records[1])
+	}
+}
+
+func TestRecoveryRepanicsErrAbortHandler(t *testing.T) {
+	handler := Recovery(logport.NoopLogger())(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Fatalf("expected ErrAbortHandler to propagate, got %v", v)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestRecoveryWritesTraceIDsOnce(t *testing.T) {
+	spanCtx := oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    oteltrace.TraceID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10},
+		SpanID:     oteltrace.SpanID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+		TraceFlags: oteltrace.FlagsSampled,
+	})
+	panicking := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("handler exploded")
+	})
+	for name, wrap := range map[string]func(logport.ForLogging) http.Handler{
+		"request logger": func(logger logport.ForLogging) http.Handler {
+			return Middleware(logger, Options{})(Recovery(logger)(panicking))
+		},
+		"fallback logger": func(logger logport.ForLogging) http.Handler {
+			return Recovery(logger)(panicking)
+		},
+	} {
+		buf := &bytes.Buffer{}
+		req := httptest.NewRequest(http.MethodGet, "/boom", nil)
+		req = req.WithContext(oteltrace.ContextWithSpanContext(req.Context(), spanCtx))
+		wrap(newTestLogger(buf)).ServeHTTP(httptest.NewRecorder(), req)
+
+		line, _, _ := strings.Cut(buf.String(), "\n")
+		if got := strings.Count(line, `"`+logport.TraceIDKey+`"`); got != 1 {
+			t.Fatalf("%s: expected one trace id on the panic entry, got %d: %s", name, got, line)
+		}
+		if got := strings.Count(line, `"`+logport.SpanIDKey+`"`); got != 1 {
+			t.Fatalf("%s: expected one span id on the panic entry, got %d: %s", name, got, line)
+		}
+	}
+}
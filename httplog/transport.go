@@ -0,0 +1,277 @@
+package httplog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	logport "pkt.systems/logport"
+)
+
+const (
+	// EndpointKey is the structured logging key carrying scheme://host of an
+	// outgoing request.
+	EndpointKey = "endpoint"
+	// AttemptKey is the structured logging key carrying the attempt number set
+	// via ContextWithAttempt.
+	AttemptKey = "attempt"
+	// DurationKey is the structured logging key carrying the round trip time.
+	DurationKey = "duration"
+	// ErrorKey is the structured logging key carrying transport errors.
+	ErrorKey = "error"
+	// RequestHeadersKey carries the (redacted) outgoing request headers.
+	RequestHeadersKey = "request_headers"
+	// ResponseHeadersKey carries the (redacted) response headers.
+	ResponseHeadersKey = "response_headers"
+	// RequestBodyKey carries the captured request body prefix.
+	RequestBodyKey = "request_body"
+	// ResponseBodyKey carries the captured response body prefix.
+	ResponseBodyKey = "response_body"
+
+	// ClientAttemptMessage is logged at TraceLevel before a request is sent.
+	ClientAttemptMessage = "client.http.attempt"
+	// ClientSuccessMessage is logged when a response with status < 400 arrives.
+	ClientSuccessMessage = "client.http.success"
+	// ClientFailureMessage is logged when the round trip fails or the response
+	// status is 400 or above.
+	ClientFailureMessage = "client.http.failure"
+
+	// DefaultMaxBodyBytes bounds captured bodies when
+	// TransportOptions.MaxBodyBytes is zero.
+	DefaultMaxBodyBytes = 4096
+
+	redactedValue = "[REDACTED]"
+)
+
+// DefaultRedactedHeaders lists the headers whose values are replaced when
+// TransportOptions.RedactHeaders is nil.
+var DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// TransportOptions configures the logging RoundTripper returned by
+// TransportWithOptions.
+type TransportOptions struct {
+	// LogHeaders includes request and response headers in the completion entry.
+	// Values of RedactHeaders are replaced before logging.
+	LogHeaders bool
+
+	// RedactHeaders lists header names (case insensitive) whose values are
+	// never logged. Defaults to DefaultRedactedHeaders.
+	RedactHeaders []string
+
+	// LogBodies captures up to MaxBodyBytes of the request and response bodies.
+	// The bodies seen by the caller and the server are left intact. The
+	// response body is captured as the caller reads it, so streaming
+	// responses are not held up, and the completion entry is written once the
+	// body reaches EOF or is closed.
+	LogBodies bool
+
+	// MaxBodyBytes bounds captured bodies. Defaults to DefaultMaxBodyBytes.
+	MaxBodyBytes int
+
+	// RedactBody, when non-nil, receives every captured body and returns the
+	// bytes to log.
+	RedactBody func(body []byte) []byte
+
+	// Level, when non-nil, selects the level of the completion entry from the
+	// response status. Defaults to LevelForStatus. Transport errors always log
+	// at ErrorLevel.
+	Level func(status int) logport.Level
+}
+
+type attemptContextKey struct{}
+
+// ContextWithAttempt records the retry attempt number so the logging
+// transport can include it in its entries.
+func ContextWithAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptContextKey{}, attempt)
+}
+
+// Transport wraps base (http.DefaultTransport when nil) in a RoundTripper
+// that logs every request through the port. Entries are written to the logger
+// found in the request context via logport.LoggerFromContext, falling back to
+// logger when the context carries none.
+func Transport(base http.RoundTripper, logger logport.ForLogging) http.RoundTripper {
+	return TransportWithOptions(base, logger, TransportOptions{})
+}
+
+// TransportWithOptions is like Transport but applies opts, e.g. to opt into
+// header or body logging.
+func TransportWithOptions(base http.RoundTripper, logger logport.ForLogging, opts TransportOptions) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if logger == nil {
+		logger = logport.NoopLogger()
+	}
+	if opts.RedactHeaders == nil {
+		opts.RedactHeaders = DefaultRedactedHeaders
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if opts.Level == nil {
+		opts.Level = LevelForStatus
+	}
+	return &transport{base: base, logger: logger, opts: opts}
+}
+
+type transport struct {
+	base   http.RoundTripper
+	logger logport.ForLogging
+	opts   TransportOptions
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	logger, ok := logport.LoggerFromContextOK(ctx)
+	if !ok {
+		logger = t.logger.WithTrace(ctx)
+	}
+	keyvals := []any{
+		EndpointKey, req.URL.Scheme + "://" + req.URL.Host,
+		MethodKey, req.Method,
+		PathKey, req.URL.Path,
+	}
+	if attempt, ok := ctx.Value(attemptContextKey{}).(int); ok {
+		keyvals = append(keyvals, AttemptKey, attempt)
+	}
+	logger = logger.With(keyvals...)
+	logger.Trace(ClientAttemptMessage)
+
+	var fields []any
+	if t.opts.LogHeaders {
+		fields = append(fields, RequestHeadersKey, t.redactHeaders(req.Header))
+	}
+	if t.opts.LogBodies && req.Body != nil && req.Body != http.NoBody {
+		var body []byte
+		req, body = t.captureRequestBody(req)
+		fields = append(fields, RequestBodyKey, t.redactBody(body))
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	elapsed := time.Since(start)
+	if err != nil {
+		fields = append(fields, ErrorKey, err, DurationKey, elapsed)
+		logger.Error(ClientFailureMessage, fields...)
+		return resp, err
+	}
+
+	fields = append(fields, StatusKey, resp.StatusCode, DurationKey, elapsed)
+	if t.opts.LogHeaders {
+		fields = append(fields, ResponseHeadersKey, t.redactHeaders(resp.Header))
+	}
+	msg := ClientSuccessMessage
+	if resp.StatusCode >= 400 {
+		msg = ClientFailureMessage
+	}
+	level := t.opts.Level(resp.StatusCode)
+	if t.opts.LogBodies && resp.Body != nil && resp.Body != http.NoBody {
+		resp.Body = &loggedBody{ReadCloser: resp.Body, limit: t.opts.MaxBodyBytes, log: func(body []byte) {
+			logger.Logp(level, msg, append(fields, ResponseBodyKey, t.redactBody(body))...)
+		}}
+		return resp, nil
+	}
+	logger.Logp(level, msg, fields...)
+	return resp, nil
+}
+
+// captureRequestBody returns a shallow clone of req whose body replays the
+// captured prefix followed by the remainder of the original body.
+func (t *transport) captureRequestBody(req *http.Request) (*http.Request, []byte) {
+	if req.GetBody != nil {
+		if rc, err := req.GetBody(); err == nil {
+			prefix, _ := io.ReadAll(io.LimitReader(rc, int64(t.opts.MaxBodyBytes)))
+			_ = rc.Close()
+			return req, prefix
+		}
+	}
+	prefix, _ := io.ReadAll(io.LimitReader(req.Body, int64(t.opts.MaxBodyBytes)))
+	clone := req.Clone(req.Context())
+	clone.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(prefix), req.Body), Closer: req.Body}
+	return clone, prefix
+}
+
+// loggedBody hands a response body to the caller, keeping the first limit
+// bytes read, and calls log with them once the body reaches EOF, fails or is
+// closed, whichever comes first. Close may run concurrently with a blocked
+// Read to abort it, so prefix and logged are guarded by mu.
+type loggedBody struct {
+	io.ReadCloser
+	limit int
+	log   func(prefix []byte)
+
+	mu     sync.Mutex
+	prefix []byte
+	logged bool
+}
+
+func (b *loggedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.mu.Lock()
+	if keep := min(n, b.limit-len(b.prefix)); keep > 0 && !b.logged {
+		b.prefix = append(b.prefix, p[:keep]...)
+	}
+	b.mu.Unlock()
+	if err != nil {
+		b.done()
+	}
+	return n, err
+}
+
+func (b *loggedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.done()
+	return err
+}
+
+// done logs the captured prefix the first time it is called. prefix is no
+// longer appended to once logged is set, so it is passed on without a copy.
+func (b *loggedBody) done() {
+	b.mu.Lock()
+	if b.logged {
+		b.mu.Unlock()
+		return
+	}
+	b.logged = true
+	prefix := b.prefix
+	b.mu.Unlock()
+	b.log(prefix)
+}
+
+func (t *transport) redactHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		if t.redacted(name) {
+			out[name] = redactedValue
+			continue
+		}
+		out[name] = strings.Join(values, ", ")
+	}
+	return out
+}
+
+func (t *transport) redacted(name string) bool {
+	for _, candidate := range t.opts.RedactHeaders {
+		if strings.EqualFold(candidate, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *transport) redactBody(body []byte) string {
+	if t.opts.RedactBody != nil {
+		body = t.opts.RedactBody(body)
+	}
+	return string(body)
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,229 @@
+package httplog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	logport "pkt.systems/logport"
+	psl "pkt.systems/logport/adapters/psl"
+)
+
+func TestTransportLogsSuccess(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	buf := &bytes.Buffer{}
+	client := &http.Client{Transport: Transport(nil, newTestLogger(buf))}
+
+	req, _ := http.NewRequestWithContext(ContextWithAttempt(context.Background(), 2), http.MethodGet, srv.URL+"/v1/acquire", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 2 {
+		t.Fatalf("expected attempt and success entries, got %d: %s", len(records), buf.String())
+	}
+	if records[0]["msg"] != ClientAttemptMessage || records[0]["lvl"] != "trace" {
+		t.Fatalf("expected trace attempt entry, got %v", records[0])
+	}
+	done := records[1]
+	if done["msg"] != ClientSuccessMessage || done["lvl"] != "info" {
+		t.Fatalf("expected info success entry, got %v", done)
+	}
+	if done[EndpointKey] != srv.URL || done[PathKey] != "/v1/acquire" || done[MethodKey] != "GET" {
+		t.Fatalf("expected request fields, got %v", done)
+	}
+	if done[AttemptKey] != float64(2) {
+		t.Fatalf("expected attempt=2, got %v", done[AttemptKey])
+	}
+	if done[StatusKey] != float64(200) {
+		t.Fatalf("expected status=200, got %v", done[StatusKey])
+	}
+	if _, ok := done[DurationKey]; !ok {
+		t.Fatalf("expected duration field, got %v", done)
+	}
+	if _, ok := done[RequestHeadersKey]; ok {
+		t.Fatalf("expected headers to be opt-in, got %v", done)
+	}
+}
+
+func TestTransportPrefersContextLogger(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	fallback := &bytes.Buffer{}
+	scoped := &bytes.Buffer{}
+	client := &http.Client{Transport: Transport(nil, newTestLogger(fallback))}
+
+	ctx := logport.ContextWithLogger(context.Background(), newTestLogger(scoped).With("req_id", "abc"))
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if fallback.Len() != 0 {
+		t.Fatalf("expected fallback logger to stay silent, got %q", fallback.String())
+	}
+	records := decodeJSONLines(t, scoped.Bytes())
+	done := records[len(records)-1]
+	if done["msg"] != ClientFailureMessage || done["lvl"] != "error" {
+		t.Fatalf("expected error failure entry, got %v", done)
+	}
+	if done["req_id"] != "abc" {
+		t.Fatalf("expected context logger fields, got %v", done)
+	}
+}
+
+func TestTransportLogsRedactedHeadersAndBodies(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Set-Cookie", "session=secret")
+		_, _ = w.Write(append([]byte("echo:"), body...))
+	}))
+	defer srv.Close()
+
+	buf := &bytes.Buffer{}
+	client := &http.Client{Transport: TransportWithOptions(nil, newTestLogger(buf), TransportOptions{
+		LogHeaders: true,
+		LogBodies:  true,
+		RedactBody: func(b []byte) []byte { return bytes.ReplaceAll(b, []byte("hunter2"), []byte("***")) },
+	})}
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, io.NopCloser(strings.NewReader("password=hunter2")))
+	req.Header.Set("Authorization", "Bearer token")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(got) != "echo:password=hunter2" {
+		t.Fatalf("expected bodies to reach server and caller intact, got %q", got)
+	}
+
+	records := decodeJSONLines(t, buf.Bytes())
+	done := records[len(records)-1]
+	reqHeaders, _ := done[RequestHeadersKey].(map[string]any)
+	if reqHeaders["Authorization"] != redactedValue {
+		t.Fatalf("expected Authorization to be redacted, got %v", done[RequestHeadersKey])
+	}
+	respHeaders, _ := done[ResponseHeadersKey].(map[string]any)
+	if respHeaders["Set-Cookie"] != redactedValue {
+		t.Fatalf("expected Set-Cookie to be redacted, got %v", done[ResponseHeadersKey])
+	}
+	if done[RequestBodyKey] != "password=***" {
+		t.Fatalf("expected redacted request body, got %v", done[RequestBodyKey])
+	}
+	if done[ResponseBodyKey] != "echo:password=***" {
+		t.Fatalf("expected redacted response body, got %v", done[ResponseBodyKey])
+	}
+}
+
+func TestTransportDoesNotHoldUpStreamingResponses(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "first")
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	buf := &bytes.Buffer{}
+	client := &http.Client{Transport: TransportWithOptions(nil, newTestLogger(buf), TransportOptions{LogBodies: true, MaxBodyBytes: 3})}
+	done := make(chan *http.Response, 1)
+	go func() {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Errorf("request failed: %v", err)
+		}
+		done <- resp
+	}()
+	var resp *http.Response
+	select {
+	case resp = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the transport waited for the streaming body")
+	}
+	if resp == nil {
+		return
+	}
+	chunk := make([]byte, 5)
+	if _, err := io.ReadFull(resp.Body, chunk); err != nil || string(chunk) != "first" {
+		t.Fatalf("expected the first chunk, got %q, %v", chunk, err)
+	}
+	if records := decodeJSONLines(t, buf.Bytes()); len(records) != 1 {
+		t.Fatalf("expected only the attempt entry before the body is closed, got %s", buf.String())
+	}
+	_ = resp.Body.Close()
+	records := decodeJSONLines(t, buf.Bytes())
+	if len(records) != 2 || records[1][ResponseBodyKey] != "fir" {
+		t.Fatalf("expected the completion entry with the captured prefix, got %s", buf.String())
+	}
+}
+
+func TestTransportBodyCloseDuringRead(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		pr, pw := io.Pipe()
+		buf := &lockedBuffer{}
+		rt := TransportWithOptions(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: pr, Request: r}, nil
+		}), psl.NewWithOptions(buf, psl.Options{Mode: psl.ModeStructured, DisableTimestamp: true, NoColor: true}), TransportOptions{LogBodies: true, MaxBodyBytes: 64})
+		resp, err := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://example.test/", nil))
+		if err != nil {
+			t.Fatalf("RoundTrip failed: %v", err)
+		}
+
+		read := make(chan struct{})
+		go func() {
+			defer close(read)
+			_, _ = io.Copy(io.Discard, resp.Body)
+		}()
+		// Write returns once the reader has the bytes, so Close races with
+		// the reader recording them and with its next, blocked Read.
+		_, _ = pw.Write([]byte("partial body"))
+		_ = resp.Body.Close()
+		<-read
+		_ = pw.Close()
+
+		if records := decodeJSONLines(t, buf.Bytes()); len(records) != 2 {
+			t.Fatalf("expected one completion entry after Close, got %s", buf.Bytes())
+		}
+	}
+}
+
+func TestTransportLogsErrors(t *testing.T) {
+	buf := &bytes.Buffer{}
+	boom := errors.New("dial failed")
+	rt := Transport(roundTripFunc(func(*http.Request) (*http.Response, error) { return nil, boom }), newTestLogger(buf))
+
+	req, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1:1/", nil)
+	if _, err := rt.RoundTrip(req); !errors.Is(err, boom) {
+		t.Fatalf("expected transport error, got %v", err)
+	}
+	records := decodeJSONLines(t, buf.Bytes())
+	done := records[len(records)-1]
+	if done["lvl"] != "error" || done[ErrorKey] != "dial failed" {
+		t.Fatalf("expected error entry, got %v", done)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
@@ -0,0 +1,128 @@
+// Package netsink holds the connection handling shared by the network sinks:
+// a lazily dialed connection that is retried once after a failed write and
+// re-dialed with exponential backoff after repeated failures.
+package netsink
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"time"
+)
+
+// Defaults applied by ApplyDefaults.
+const (
+	DefaultDialTimeout  = 5 * time.Second
+	DefaultWriteTimeout = 5 * time.Second
+	DefaultMinBackoff   = 100 * time.Millisecond
+	DefaultMaxBackoff   = 30 * time.Second
+)
+
+// ErrBackoff is returned while a Conn waits before reconnecting; the entry
+// is dropped. Each sink re-exports it as its own ErrBackoff.
+var ErrBackoff = errors.New("logport: reconnect backoff in effect")
+
+// ApplyDefaults fills the zero timeouts and backoff bounds of a sink's
+// options, keeping maxBackoff at least minBackoff.
+func ApplyDefaults(dialTimeout, writeTimeout, minBackoff, maxBackoff *time.Duration) {
+	if *dialTimeout <= 0 {
+		*dialTimeout = DefaultDialTimeout
+	}
+	if *writeTimeout <= 0 {
+		*writeTimeout = DefaultWriteTimeout
+	}
+	if *minBackoff <= 0 {
+		*minBackoff = DefaultMinBackoff
+	}
+	if *maxBackoff < *minBackoff {
+		*maxBackoff = max(DefaultMaxBackoff, *minBackoff)
+	}
+}
+
+// Dial connects to address, treating the "tls" network as TLS over tcp.
+func Dial(network, address string, config *tls.Config, timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	if network == "tls" {
+		return (&tls.Dialer{NetDialer: dialer, Config: config}).Dial("tcp", address)
+	}
+	return dialer.Dial(network, address)
+}
+
+// Conn is a connection dialed on first use. It is not safe for concurrent
+// use; sinks call it with their own lock held.
+type Conn struct {
+	// Dial opens a new connection, including any handshake the protocol
+	// needs before entries can be written.
+	Dial func() (net.Conn, error)
+	// MinBackoff and MaxBackoff bound the delay between reconnect attempts.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Retryable, when set, reports whether a failed send may be repeated on
+	// a fresh connection. By default every failure is retried once.
+	Retryable func(error) bool
+
+	conn    net.Conn
+	backoff time.Duration
+	retryAt time.Time
+}
+
+// Send runs send on the connection, dialing it first when needed. A failed
+// send closes the connection and is repeated once on a new one, which covers
+// a receiver that closed the connection or restarted since the last write.
+// While backing off after a failure Send returns ErrBackoff without dialing.
+func (c *Conn) Send(send func(net.Conn) error) error {
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if err = c.connect(); err != nil {
+			return err
+		}
+		if err = send(c.conn); err == nil {
+			c.backoff = 0
+			return nil
+		}
+		_ = c.conn.Close()
+		c.conn = nil
+		if c.Retryable != nil && !c.Retryable(err) {
+			break
+		}
+	}
+	c.fail()
+	return err
+}
+
+// Close closes the connection. A later Send dials again.
+func (c *Conn) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+func (c *Conn) connect() error {
+	if c.conn != nil {
+		return nil
+	}
+	if !c.retryAt.IsZero() && time.Now().Before(c.retryAt) {
+		return ErrBackoff
+	}
+	conn, err := c.Dial()
+	if err != nil {
+		c.fail()
+		return err
+	}
+	c.conn = conn
+	c.retryAt = time.Time{}
+	return nil
+}
+
+// fail schedules the next reconnect attempt, doubling the delay each time.
+func (c *Conn) fail() {
+	if c.backoff == 0 {
+		c.backoff = c.MinBackoff
+	} else {
+		c.backoff = min(c.backoff*2, c.MaxBackoff)
+	}
+	c.retryAt = time.Now().Add(c.backoff)
+}
@@ -0,0 +1,25 @@
+// Package severity maps logport levels onto the syslog severities (RFC 5424
+// section 6.2.1) used by the syslog, GELF, fluent and journald outputs.
+package severity
+
+import logport "pkt.systems/logport"
+
+// FromLevel returns the syslog severity of level: 7 (debug) for trace and
+// debug, 6 (informational) for info and anything unknown, 4 (warning),
+// 3 (error), 2 (critical) for fatal and 1 (alert) for panic.
+func FromLevel(level logport.Level) int {
+	switch level {
+	case logport.TraceLevel, logport.DebugLevel:
+		return 7
+	case logport.WarnLevel:
+		return 4
+	case logport.ErrorLevel:
+		return 3
+	case logport.FatalLevel:
+		return 2
+	case logport.PanicLevel:
+		return 1
+	default:
+		return 6
+	}
+}
@@ -0,0 +1,254 @@
+package logport
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultLineWriterMaxLineBytes caps a buffered partial line when
+	// LineWriterOptions.MaxLineBytes is zero.
+	DefaultLineWriterMaxLineBytes = 64 << 10
+	// DefaultLineWriterMaxRecordBytes caps the continuation lines grouped into
+	// one entry when LineWriterOptions.MaxRecordBytes is zero.
+	DefaultLineWriterMaxRecordBytes = 1 << 20
+	// DefaultLineWriterIdleTimeout is how long a LineWriter waits for more
+	// input before flushing buffered data when LineWriterOptions.IdleTimeout is
+	// zero.
+	DefaultLineWriterIdleTimeout = 100 * time.Millisecond
+)
+
+// LineWriterOptions configures NewLineWriter.
+type LineWriterOptions struct {
+	// Level, when non-nil, pins every entry to this level, FatalLevel and
+	// PanicLevel included. Level prefixes are still stripped from messages. When nil the level is detected per line
+	// like WriteToLogger does.
+	Level *Level
+
+	// DefaultLevel, when non-nil, is used for lines whose level cannot be
+	// detected instead of NoLevel. Ignored when Level is set.
+	DefaultLevel *Level
+
+	// Classifier derives levels and messages from lines. Defaults to
+	// DefaultClassifier.
+	Classifier Classifier
+
+	// MaxLineBytes caps how much of an unterminated line is buffered; once
+	// exceeded the buffered bytes are logged as a line of their own. Defaults
+	// to DefaultLineWriterMaxLineBytes.
+	MaxLineBytes int
+
+	// MaxRecordBytes caps the size of the stack collected for a single entry.
+	// Continuation lines beyond the cap start a new entry. Defaults to
+	// DefaultLineWriterMaxRecordBytes.
+	MaxRecordBytes int
+
+	// IdleTimeout flushes a buffered partial line or a pending multi-line
+	// record when no Write arrives for this long. Defaults to
+	// DefaultLineWriterIdleTimeout; a negative value disables the timer so
+	// data is only flushed by Flush and Close.
+	IdleTimeout time.Duration
+
+	// DisableGrouping logs every line as its own entry instead of folding
+	// continuation lines into the stack field of the preceding entry.
+	DisableGrouping bool
+}
+
+// LineWriter is an io.WriteCloser that turns a byte stream into log entries.
+// Unlike WriteToLogger it tolerates lines split across Write calls, and it
+// groups continuation lines (Go panic traces, Java stack traces and indented
+// lines) into a single entry whose StackKey field holds the continuation.
+//
+// Detected FatalLevel and PanicLevel lines are logged at ErrorLevel: text
+// read from a pipe must not terminate or panic the reading process. A level
+// pinned with LineWriterOptions.Level is logged as given.
+type LineWriter struct {
+	logger ForLogging
+	opts   LineWriterOptions
+
+	mu      sync.Mutex
+	partial []byte
+	pending *lineRecord
+	timer   *time.Timer
+	closed  bool
+}
+
+type lineRecord struct {
+	head       string
+	stack      []string
+	stackBytes int
+	goTrace    bool
+}
+
+// NewLineWriter returns a LineWriter emitting entries to logger. Close it to
+// flush any buffered data.
+func NewLineWriter(logger ForLogging, opts LineWriterOptions) *LineWriter {
+	if logger == nil {
+		logger = noopLogger{}
+	}
+	if opts.MaxLineBytes <= 0 {
+		opts.MaxLineBytes = DefaultLineWriterMaxLineBytes
+	}
+	if opts.MaxRecordBytes <= 0 {
+		opts.MaxRecordBytes = DefaultLineWriterMaxRecordBytes
+	}
+	if opts.IdleTimeout == 0 {
+		opts.IdleTimeout = DefaultLineWriterIdleTimeout
+	}
+	if opts.Classifier == nil {
+		opts.Classifier = defaultClassifier
+	}
+	return &LineWriter{logger: logger, opts: opts}
+}
+
+// Write buffers p, logging every complete line. It always reports len(p)
+// unless the writer has been closed, in which case it returns os.ErrClosed.
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	rest := p
+	for len(rest) > 0 {
+		idx := bytes.IndexByte(rest, '\n')
+		if idx < 0 {
+			w.partial = append(w.partial, rest...)
+			for len(w.partial) > w.opts.MaxLineBytes {
+				w.line(string(w.partial[:w.opts.MaxLineBytes]))
+				w.partial = append(w.partial[:0], w.partial[w.opts.MaxLineBytes:]...)
+			}
+			break
+		}
+		if len(w.partial) > 0 {
+			w.partial = append(w.partial, rest[:idx]...)
+			w.line(string(w.partial))
+			w.partial = w.partial[:0]
+		} else {
+			w.line(string(rest[:idx]))
+		}
+		rest = rest[idx+1:]
+	}
+	w.armTimer()
+	return len(p), nil
+}
+
+// Flush logs any buffered partial line and pending multi-line record.
+func (w *LineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flushLocked()
+}
+
+// Close flushes buffered data and stops the idle timer. Subsequent writes
+// fail with os.ErrClosed.
+func (w *LineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.flushLocked()
+	return nil
+}
+
+func (w *LineWriter) armTimer() {
+	if w.opts.IdleTimeout < 0 || (len(w.partial) == 0 && w.pending == nil) {
+		return
+	}
+	if w.timer == nil {
+		w.timer = time.AfterFunc(w.opts.IdleTimeout, w.Flush)
+		return
+	}
+	w.timer.Reset(w.opts.IdleTimeout)
+}
+
+func (w *LineWriter) flushLocked() {
+	if len(w.partial) > 0 {
+		w.line(string(w.partial))
+		w.partial = w.partial[:0]
+	}
+	w.emit()
+}
+
+// line processes one complete line, folding it into the pending record when
+// it continues it.
+func (w *LineWriter) line(raw string) {
+	raw = strings.TrimRight(raw, "\r")
+	if strings.TrimSpace(raw) == "" {
+		// Blank lines separate the header of a Go panic from its goroutine
+		// dump, so they never terminate a pending record.
+		return
+	}
+	if w.opts.DisableGrouping {
+		w.pending = &lineRecord{head: raw}
+		w.emit()
+		return
+	}
+	if rec := w.pending; rec != nil && isContinuationLine(raw, rec.goTrace) && rec.stackBytes+len(raw) <= w.opts.MaxRecordBytes {
+		rec.stack = append(rec.stack, raw)
+		rec.stackBytes += len(raw) + 1
+		if strings.HasPrefix(raw, "goroutine ") {
+			rec.goTrace = true
+		}
+		return
+	}
+	w.emit()
+	w.pending = &lineRecord{head: raw, goTrace: isGoPanicHeader(raw)}
+}
+
+func (w *LineWriter) emit() {
+	rec := w.pending
+	if rec == nil {
+		return
+	}
+	w.pending = nil
+	level, msg, keyvals := classifyLine(w.opts.Classifier, rec.head)
+	if w.opts.Level != nil {
+		level = *w.opts.Level
+	} else {
+		if level == NoLevel && w.opts.DefaultLevel != nil {
+			level = *w.opts.DefaultLevel
+		}
+		level = bridgedLevel(level)
+	}
+	if msg == "" && len(keyvals) == 0 {
+		msg = strings.TrimSpace(rec.head)
+	}
+	if len(rec.stack) > 0 {
+		keyvals = append(keyvals, StackKey, strings.Join(rec.stack, "\n"))
+	}
+	w.logger.Logp(level, msg, keyvals...)
+}
+
+// isGoPanicHeader reports whether line opens a Go runtime crash report.
+func isGoPanicHeader(line string) bool {
+	return strings.HasPrefix(line, "panic: ") || strings.HasPrefix(line, "fatal error: ")
+}
+
+// isContinuationLine reports whether line belongs to the record before it:
+// indented lines, Java "Caused by:" chains and, inside Go crash reports, the
+// goroutine headers and function frames.
+func isContinuationLine(line string, goTrace bool) bool {
+	switch line[0] {
+	case ' ', '\t':
+		return true
+	}
+	if strings.HasPrefix(line, "Caused by: ") || strings.HasPrefix(line, "Suppressed: ") {
+		return true
+	}
+	if !goTrace {
+		return false
+	}
+	return strings.HasPrefix(line, "goroutine ") ||
+		strings.HasPrefix(line, "created by ") ||
+		strings.HasPrefix(line, "[signal ") ||
+		strings.HasSuffix(line, ")")
+}
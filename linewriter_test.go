@@ -0,0 +1,125 @@
+package logport
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLineWriterJoinsPartialWrites(t *testing.T) {
+	rec := &recordingLogger{}
+	w := NewLineWriter(rec, LineWriterOptions{IdleTimeout: -1})
+
+	for _, chunk := range []string{"WARN: disk ", "almost full\nINFO: re", "try\r\n", "trailing"} {
+		if n, err := w.Write([]byte(chunk)); err != nil || n != len(chunk) {
+			t.Fatalf("Write(%q) = %d, %v", chunk, n, err)
+		}
+	}
+	if len(rec.entries) != 1 {
+		t.Fatalf("expected the pending record to wait for more input, got %+v", rec.entries)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close returned %v", err)
+	}
+
+	want := []logEntry{
+		{level: WarnLevel, msg: "disk almost full"},
+		{level: InfoLevel, msg: "retry"},
+		{level: NoLevel, msg: "trailing"},
+	}
+	if len(rec.entries) != len(want) {
+		t.Fatalf("expected %d entries, got %+v", len(want), rec.entries)
+	}
+	for i, entry := range rec.entries {
+		if entry.level != want[i].level || entry.msg != want[i].msg {
+			t.Fatalf("entry %d = %+v, want %+v", i, entry, want[i])
+		}
+	}
+	if _, err := w.Write([]byte("late\n")); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("expected os.ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestLineWriterGroupsStackTraces(t *testing.T) {
+	rec := &recordingLogger{}
+	w := NewLineWriter(rec, LineWriterOptions{IdleTimeout: -1})
+
+	goPanic := "panic: boom\n\ngoroutine 1 [running]:\nmain.main()\n\t/src/main.go:5 +0x25\nexit status 2\n"
+	javaTrace := "Exception in thread \"main\" java.lang.IllegalStateException: bad\n" +
+		"\tat com.example.App.run(App.java:12)\n" +
+		"Caused by: java.io.IOException: closed\n" +
+		"\t... 3 more\n"
+	_, _ = w.Write([]byte(goPanic[:20]))
+	_, _ = w.Write([]byte(goPanic[20:] + javaTrace))
+	_ = w.Close()
+
+	if len(rec.entries) != 3 {
+		t.Fatalf("expected 3 entries, got %+v", rec.entries)
+	}
+	panicEntry := rec.entries[0]
+	if panicEntry.level != ErrorLevel || panicEntry.msg != "boom" {
+		t.Fatalf("expected panic header downgraded to error, got %+v", panicEntry)
+	}
+	stack, _ := panicEntry.field(StackKey)
+	if stack != "goroutine 1 [running]:\nmain.main()\n\t/src/main.go:5 +0x25" {
+		t.Fatalf("unexpected go stack %q", stack)
+	}
+	if rec.entries[1].msg != "exit status 2" || len(rec.entries[1].keyvals) != 0 {
+		t.Fatalf("expected exit status as a separate entry, got %+v", rec.entries[1])
+	}
+	javaStack, _ := rec.entries[2].field(StackKey)
+	if s, _ := javaStack.(string); !strings.HasPrefix(s, "\tat com.example.App.run") || !strings.HasSuffix(s, "\t... 3 more") {
+		t.Fatalf("unexpected java stack %q", javaStack)
+	}
+}
+
+func TestLineWriterSplitsOverlongLines(t *testing.T) {
+	rec := &recordingLogger{}
+	w := NewLineWriter(rec, LineWriterOptions{MaxLineBytes: 4, IdleTimeout: -1, DisableGrouping: true})
+
+	_, _ = w.Write([]byte("abcdefghij"))
+	_ = w.Close()
+
+	var msgs []string
+	for _, entry := range rec.entries {
+		msgs = append(msgs, entry.msg)
+	}
+	if got := strings.Join(msgs, ","); got != "abcd,efgh,ij" {
+		t.Fatalf("expected line split at MaxLineBytes, got %q", got)
+	}
+}
+
+func TestLineWriterKeepsPinnedFatalLevel(t *testing.T) {
+	rec := &recordingLogger{}
+	level := FatalLevel
+	w := NewLineWriter(rec, LineWriterOptions{Level: &level, IdleTimeout: -1})
+
+	_, _ = w.Write([]byte("ERROR: giving up\n"))
+	_ = w.Close()
+
+	if len(rec.entries) != 1 || rec.entries[0].level != FatalLevel || rec.entries[0].msg != "giving up" {
+		t.Fatalf("expected the pinned fatal level, got %+v", rec.entries)
+	}
+}
+
+func TestLineWriterFlushesAfterIdleTimeout(t *testing.T) {
+	rec := &lockedRecorder{}
+	w := NewLineWriter(rec, LineWriterOptions{IdleTimeout: 10 * time.Millisecond})
+	defer w.Close()
+
+	_, _ = w.Write([]byte("ERROR: no newline"))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		rec.mu.Lock()
+		n := len(rec.entries)
+		rec.mu.Unlock()
+		if n == 1 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("expected idle timeout to flush the partial line")
+}
@@ -176,15 +176,29 @@ func ContextWithLogger(ctx context.Context, logger ForLogging) context.Context {
 }
 
 // LoggerFromContext extracts a logger implementation from context if
-// present or returns a NoopLogger.
+// present or returns a NoopLogger, or Default() once SetContextFallback(true)
+// has been called.
 func LoggerFromContext(ctx context.Context) ForLogging {
+	if logger, ok := LoggerFromContextOK(ctx); ok {
+		return logger
+	}
+	if contextFallbackDefault.Load() {
+		return Default()
+	}
+	return noopLogger{}
+}
+
+// LoggerFromContextOK extracts a logger implementation from context and
+// reports whether one was present. Unlike LoggerFromContext it never
+// substitutes a fallback, letting callers apply their own.
+func LoggerFromContextOK(ctx context.Context) (ForLogging, bool) {
 	if ctx == nil {
-		return noopLogger{}
+		return nil, false
 	}
 	if logger, ok := ctx.Value(loggerContextKey{}).(ForLogging); ok && logger != nil {
-		return logger
+		return logger, true
 	}
-	return noopLogger{}
+	return nil, false
 }
 
 // LogLogger wraps a ForLogging implementation into a stdlib *log.Logger.
@@ -195,34 +209,67 @@ func LogLogger(logger ForLogging) *log.Logger {
 	return log.New(logger, "", 0)
 }
 
+// LogLoggerWithClassifier is like LogLogger but derives levels and messages
+// with classifier instead of DefaultClassifier.
+func LogLoggerWithClassifier(logger ForLogging, classifier Classifier) *log.Logger {
+	if logger == nil {
+		logger = noopLogger{}
+	}
+	return log.New(classifiedWriter{logger: logger, classifier: classifier}, "", 0)
+}
+
 // LogLoggerWithLevel wraps a ForLogging implementation into a stdlib
 // *log.Logger that pins every emitted entry to level. The wrapped logger still
 // benefits from prefix classification to strip level tokens from msg, but the
-// detected level is ignored in favour of the supplied one.
+// detected level is ignored in favour of the supplied one, including
+// FatalLevel and PanicLevel, which then exit or panic as the backend does.
 func LogLoggerWithLevel(logger ForLogging, level Level) *log.Logger {
+	return LogLoggerWithLevelAndClassifier(logger, level, nil)
+}
+
+// LogLoggerWithLevelAndClassifier is like LogLoggerWithLevel but strips level
+// markers from messages with classifier instead of DefaultClassifier.
+func LogLoggerWithLevelAndClassifier(logger ForLogging, level Level, classifier Classifier) *log.Logger {
 	if logger == nil {
 		logger = noopLogger{}
 	}
-	return log.New(levelPinnedWriter{logger: logger, level: level}, "", 0)
+	return log.New(levelPinnedWriter{logger: logger, level: level, classifier: classifier}, "", 0)
 }
 
 // WriteToLogger routes bytes to logger.Logp, splitting on newlines and
 // detecting severity prefixes. It always reports len(p) to comply with
-// io.Writer semantics.
+// io.Writer semantics. Lines detected as FatalLevel or PanicLevel are logged at
+// ErrorLevel, so bridged output cannot terminate or panic the process.
 func WriteToLogger(logger ForLogging, p []byte) (int, error) {
+	return WriteToLoggerWithClassifier(logger, p, nil)
+}
+
+// WriteToLoggerWithClassifier is like WriteToLogger but derives levels and
+// messages with classifier. A nil classifier selects DefaultClassifier.
+func WriteToLoggerWithClassifier(logger ForLogging, p []byte, classifier Classifier) (int, error) {
 	if len(p) == 0 {
 		return 0, nil
 	}
 	if logger == nil {
 		return len(p), nil
 	}
-	writeBytesToLogger(logger, p, nil)
+	writeBytesToLogger(logger, p, nil, nil, classifier)
 	return len(p), nil
 }
 
+type classifiedWriter struct {
+	logger     ForLogging
+	classifier Classifier
+}
+
+func (w classifiedWriter) Write(p []byte) (int, error) {
+	return WriteToLoggerWithClassifier(w.logger, p, w.classifier)
+}
+
 type levelPinnedWriter struct {
-	logger ForLogging
-	level  Level
+	logger     ForLogging
+	level      Level
+	classifier Classifier
 }
 
 func (w levelPinnedWriter) Write(p []byte) (int, error) {
@@ -233,11 +280,14 @@ func (w levelPinnedWriter) Write(p []byte) (int, error) {
 		return len(p), nil
 	}
 	level := w.level
-	writeBytesToLogger(w.logger, p, &level)
+	writeBytesToLogger(w.logger, p, &level, nil, w.classifier)
 	return len(p), nil
 }
 
-func writeBytesToLogger(logger ForLogging, p []byte, override *Level) {
+func writeBytesToLogger(logger ForLogging, p []byte, override, fallback *Level, classifier Classifier) {
+	if classifier == nil {
+		classifier = defaultClassifier
+	}
 	rest := p
 	for len(rest) > 0 {
 		line := rest
@@ -251,18 +301,35 @@ func writeBytesToLogger(logger ForLogging, p []byte, override *Level) {
 		if strings.TrimSpace(raw) == "" {
 			continue
 		}
-		level, msg := classifyLogLine(raw)
+		level, msg, keyvals := classifyLine(classifier, raw)
 		if override != nil {
 			level = *override
+		} else {
+			if level == NoLevel && fallback != nil {
+				level = *fallback
+			}
+			level = bridgedLevel(level)
 		}
-		if msg == "" {
+		if msg == "" && len(keyvals) == 0 {
 			msg = strings.TrimSpace(raw)
 		}
-		if msg == "" {
+		if msg == "" && len(keyvals) == 0 {
 			continue
 		}
-		logger.Logp(level, msg)
+		logger.Logp(level, msg, keyvals...)
+	}
+}
+
+// bridgedLevel lowers FatalLevel and PanicLevel to ErrorLevel so that a line
+// read by a writer bridge cannot terminate or panic the process. It is only
+// applied to detected and fallback levels; a level pinned by the caller is
+// logged as given.
+func bridgedLevel(level Level) Level {
+	switch level {
+	case FatalLevel, PanicLevel:
+		return ErrorLevel
 	}
+	return level
 }
 
 // NoopLogger provides a logger implementation that discards all log messages.
@@ -289,10 +356,10 @@ func (noopLogger) Warn(msg string, keyvals ...any)                 {}
 func (noopLogger) Warnf(string, ...any)                            {}
 func (noopLogger) Error(msg string, keyvals ...any)                {}
 func (noopLogger) Errorf(string, ...any)                           {}
-func (noopLogger) Fatal(msg string, keyvals ...any)                { os.Exit(1) }
-func (noopLogger) Fatalf(string, ...any)                           { os.Exit(1) }
-func (noopLogger) Panic(msg string, keyvals ...any)                { panic(msg) }
-func (noopLogger) Panicf(format string, v ...any)                  { panic(fmt.Sprintf(format, v...)) }
+func (noopLogger) Fatal(msg string, keyvals ...any)                { Exit(1) }
+func (noopLogger) Fatalf(string, ...any)                           { Exit(1) }
+func (noopLogger) Panic(msg string, keyvals ...any)                { noopPanic(msg) }
+func (noopLogger) Panicf(format string, v ...any)                  { noopPanic(fmt.Sprintf(format, v...)) }
 func (noopLogger) Trace(msg string, keyvals ...any)                {}
 func (noopLogger) Tracef(string, ...any)                           {}
 func (noopLogger) Write(p []byte) (int, error)                     { return len(p), nil }
@@ -302,6 +369,15 @@ func (noopLogger) Handle(context.Context, slog.Record) error { return nil }
 func (noopLogger) WithAttrs([]slog.Attr) slog.Handler        { return noopLogger{} }
 func (noopLogger) WithGroup(string) slog.Handler             { return noopLogger{} }
 
+// noopPanic panics with msg unless the process-wide PanicPolicy is
+// PanicContinue; there is nothing to log either way.
+func noopPanic(msg string) {
+	if (ExitPolicy{}).PanicContinues() {
+		return
+	}
+	panic(msg)
+}
+
 // ParseLevel converts a textual level into a Level value. It accepts values
 // such as "trace", "debug", "info", "warn", "warning", "error", "fatal",
 // "panic", "no", "nolevel", "disabled", and "off" (case insensitive).
@@ -360,6 +436,13 @@ func LevelFromSlog(level slog.Level) Level {
 	}
 }
 
+// terminates reports whether entries at level exit the process or panic.
+// Middlewares pass such entries straight through so they take effect on the
+// caller's goroutine.
+func terminates(level Level) bool {
+	return level == FatalLevel || level == PanicLevel
+}
+
 // LevelString returns the canonical string representation of a Level.
 func LevelString(level Level) string {
 	switch level {
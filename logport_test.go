@@ -46,6 +46,20 @@ func TestLoggerFromContextFallbacks(t *testing.T) {
 	}
 }
 
+func TestLoggerFromContextOK(t *testing.T) {
+	if _, ok := LoggerFromContextOK(context.Background()); ok {
+		t.Fatalf("expected no logger in empty context")
+	}
+	ctx := ContextWithLogger(context.Background(), &recordingLogger{})
+	logger, ok := LoggerFromContextOK(ctx)
+	if !ok {
+		t.Fatalf("expected logger to be reported as present")
+	}
+	if _, isRec := logger.(*recordingLogger); !isRec {
+		t.Fatalf("expected stored logger, got %T", logger)
+	}
+}
+
 func TestNoopLoggerBehaviour(t *testing.T) {
 	logger := NoopLogger()
 
@@ -173,8 +187,9 @@ func TestLogLoggerWithLevelPinsSeverity(t *testing.T) {
 }
 
 type logEntry struct {
-	level Level
-	msg   string
+	level   Level
+	msg     string
+	keyvals []any
 }
 
 type recordingLogger struct {
@@ -182,8 +197,25 @@ type recordingLogger struct {
 	entries []logEntry
 }
 
-func (r *recordingLogger) Logp(level Level, msg string, _ ...any) {
-	r.entries = append(r.entries, logEntry{level: level, msg: msg})
+func (r *recordingLogger) Logp(level Level, msg string, keyvals ...any) {
+	r.entries = append(r.entries, logEntry{level: level, msg: msg, keyvals: keyvals})
+}
+
+// field returns the value stored under key in the entry's keyvals.
+func (e logEntry) field(key string) (any, bool) {
+	for i := 0; i < len(e.keyvals); i++ {
+		if attr, ok := e.keyvals[i].(slog.Attr); ok {
+			if attr.Key == key {
+				return attr.Value, true
+			}
+			continue
+		}
+		if i+1 < len(e.keyvals) && e.keyvals[i] == key {
+			return e.keyvals[i+1], true
+		}
+		i++
+	}
+	return nil, false
 }
 
 func (r *recordingLogger) Write(p []byte) (int, error) {
@@ -0,0 +1,61 @@
+package logport
+
+import (
+	"context"
+	"log/slog"
+)
+
+// middleware is embedded by the loggers returned by Dedup, Redact, Sample,
+// AddSource and FlightRecorder. It forwards the level and derivation methods
+// to target and passes the derived logger to wrap, so everything derived
+// from a middleware logger keeps the middleware. Embedding types implement
+// the logging methods themselves and override any forwarding method whose
+// arguments they need to see.
+//
+// WithAttrs and WithGroup can only re-wrap when the target's derived handler
+// is itself a ForLogging, which holds for every logport adapter; any other
+// handler is returned as is and leaves the middleware behind.
+type middleware struct {
+	target ForLogging
+	wrap   func(ForLogging) ForLogging
+}
+
+func (m middleware) LogLevelFromEnv(key string) ForLogging {
+	return m.wrap(m.target.LogLevelFromEnv(key))
+}
+
+func (m middleware) LogLevel(level Level) ForLogging {
+	return m.wrap(m.target.LogLevel(level))
+}
+
+func (m middleware) WithLogLevel() ForLogging {
+	return m.wrap(m.target.WithLogLevel())
+}
+
+func (m middleware) With(keyvals ...any) ForLogging {
+	return m.wrap(m.target.With(keyvals...))
+}
+
+func (m middleware) WithTrace(ctx context.Context) ForLogging {
+	return m.wrap(m.target.WithTrace(ctx))
+}
+
+func (m middleware) Enabled(ctx context.Context, level slog.Level) bool {
+	return m.target.Enabled(ctx, level)
+}
+
+func (m middleware) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return m.rewrap(m.target.WithAttrs(attrs), m.wrap)
+}
+
+func (m middleware) WithGroup(name string) slog.Handler {
+	return m.rewrap(m.target.WithGroup(name), m.wrap)
+}
+
+// rewrap passes handler to wrap when it is a ForLogging.
+func (m middleware) rewrap(handler slog.Handler, wrap func(ForLogging) ForLogging) slog.Handler {
+	if target, ok := handler.(ForLogging); ok {
+		return wrap(target)
+	}
+	return handler
+}
@@ -0,0 +1,144 @@
+package logport
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+const (
+	// PanicKey is the structured logging key carrying a recovered panic value.
+	PanicKey = "panic"
+	// StackKey is the structured logging key carrying a goroutine stack trace.
+	StackKey = "stack"
+
+	// PanicMessage is the message logged for recovered panics when
+	// RecoverOptions.Message is empty.
+	PanicMessage = "panic recovered"
+)
+
+// RecoverAction selects what Recover does after logging a panic.
+type RecoverAction uint8
+
+const (
+	// RecoverRepanic re-raises the recovered value after logging it. This is
+	// the default and preserves the crash semantics of an unrecovered panic.
+	RecoverRepanic RecoverAction = iota
+	// RecoverExit terminates the process with RecoverOptions.ExitCode via
+	// Exit, so registered exit hooks run first.
+	RecoverExit
+	// RecoverSwallow logs the panic and lets the goroutine continue.
+	RecoverSwallow
+)
+
+// RecoverOptions configures Recover, HandlePanic and GoWithOptions.
+type RecoverOptions struct {
+	// Action decides what happens once the panic has been logged. Defaults to
+	// RecoverRepanic.
+	Action RecoverAction
+
+	// LogAsError logs the panic at ErrorLevel instead of PanicLevel.
+	LogAsError bool
+
+	// Context, when non-nil, contributes OpenTelemetry trace ids to the entry.
+	Context context.Context
+
+	// Message overrides PanicMessage.
+	Message string
+
+	// ExitCode is the status passed to the exit path when Action is
+	// RecoverExit. Defaults to 2, matching the Go runtime for unrecovered
+	// panics.
+	ExitCode int
+}
+
+// Recover logs a panic in progress together with the full goroutine stack and
+// then re-panics, exits or swallows it according to opts.Action. It must be
+// deferred directly:
+//
+//	defer logport.Recover(logger, logport.RecoverOptions{Context: ctx})
+func Recover(logger ForLogging, opts RecoverOptions) {
+	if value := recover(); value != nil {
+		HandlePanic(logger, value, opts)
+	}
+}
+
+// HandlePanic logs value, as returned by recover, with the current goroutine
+// stack and applies opts.Action. It is meant for deferred functions that need
+// to inspect the recovered value first; call it from within the deferred
+// function so the stack still includes the panic site.
+func HandlePanic(logger ForLogging, value any, opts RecoverOptions) {
+	if logger == nil {
+		logger = noopLogger{}
+	}
+	if opts.Context != nil {
+		logger = logger.WithTrace(opts.Context)
+	}
+	msg := opts.Message
+	if msg == "" {
+		msg = PanicMessage
+	}
+	level := PanicLevel
+	if opts.LogAsError {
+		level = ErrorLevel
+	}
+	logPanicEntry(logger, level, msg, PanicKey, panicValue(value), StackKey, string(debug.Stack()))
+
+	switch opts.Action {
+	case RecoverSwallow:
+		return
+	case RecoverExit:
+		code := opts.ExitCode
+		if code == 0 {
+			code = 2
+		}
+		Exit(code)
+	default:
+		panic(value)
+	}
+}
+
+// Go runs fn in a new goroutine, logging any panic through the logger stored
+// in ctx before re-panicking. Use GoWithOptions to exit or swallow instead.
+func Go(ctx context.Context, fn func(ctx context.Context)) {
+	GoWithOptions(ctx, fn, RecoverOptions{})
+}
+
+// GoWithOptions is like Go but applies opts to the recovery. When
+// opts.Context is nil, ctx supplies the trace ids.
+func GoWithOptions(ctx context.Context, fn func(ctx context.Context), opts RecoverOptions) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if opts.Context == nil {
+		opts.Context = ctx
+	}
+	logger := LoggerFromContext(ctx)
+	go func() {
+		defer Recover(logger, opts)
+		fn(ctx)
+	}()
+}
+
+// logPanicEntry emits msg at level. Adapters panic after writing PanicLevel
+// entries, so that panic is contained here to leave the decision to the
+// caller.
+func logPanicEntry(logger ForLogging, level Level, msg string, keyvals ...any) {
+	if level != PanicLevel {
+		logger.Logp(level, msg, keyvals...)
+		return
+	}
+	defer func() { _ = recover() }()
+	logger.Logp(PanicLevel, msg, keyvals...)
+}
+
+func panicValue(value any) any {
+	switch v := value.(type) {
+	case error:
+		return v.Error()
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,108 @@
+package logport
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRecoverRepanicsByDefault(t *testing.T) {
+	rec := &recordingLogger{}
+	var repanicked any
+	func() {
+		defer func() { repanicked = recover() }()
+		defer Recover(rec, RecoverOptions{})
+		panic("boom")
+	}()
+
+	if repanicked != "boom" {
+		t.Fatalf("expected original value to be re-panicked, got %v", repanicked)
+	}
+	if len(rec.entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(rec.entries))
+	}
+	entry := rec.entries[0]
+	if entry.level != PanicLevel || entry.msg != PanicMessage {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+	if v, _ := entry.field(PanicKey); v != "boom" {
+		t.Fatalf("expected panic=boom, got %v", v)
+	}
+	stack, _ := entry.field(StackKey)
+	if s, ok := stack.(string); !ok || !strings.Contains(s, "TestRecoverRepanicsByDefault") {
+		t.Fatalf("expected stack to include the panic site, got %v", stack)
+	}
+}
+
+func TestRecoverSwallowAtErrorLevel(t *testing.T) {
+	rec := &recordingLogger{}
+	func() {
+		defer Recover(rec, RecoverOptions{Action: RecoverSwallow, LogAsError: true, Message: "worker crashed"})
+		panic(errors.New("nil map"))
+	}()
+
+	if len(rec.entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(rec.entries))
+	}
+	if rec.entries[0].level != ErrorLevel || rec.entries[0].msg != "worker crashed" {
+		t.Fatalf("unexpected entry %+v", rec.entries[0])
+	}
+	if v, _ := rec.entries[0].field(PanicKey); v != "nil map" {
+		t.Fatalf("expected error text as panic value, got %v", v)
+	}
+}
+
+func TestRecoverExitUsesExitCode(t *testing.T) {
+	var code int
+	defer SetExitFunc(SetExitFunc(func(c int) { code = c }))
+
+	func() {
+		defer Recover(&recordingLogger{}, RecoverOptions{Action: RecoverExit})
+		panic("fatal")
+	}()
+	if code != 2 {
+		t.Fatalf("expected default exit code 2, got %d", code)
+	}
+}
+
+func TestRecoverContainsAdapterPanic(t *testing.T) {
+	// noopLogger panics on PanicLevel like the real adapters do.
+	func() {
+		defer Recover(NoopLogger(), RecoverOptions{Action: RecoverSwallow})
+		panic("boom")
+	}()
+}
+
+func TestGoLogsPanicsFromContextLogger(t *testing.T) {
+	rec := &lockedRecorder{}
+	ctx := ContextWithLogger(context.Background(), rec)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	GoWithOptions(ctx, func(context.Context) {
+		defer wg.Done()
+		panic("in goroutine")
+	}, RecoverOptions{Action: RecoverSwallow})
+	wg.Wait()
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.entries) != 1 || rec.entries[0].level != PanicLevel {
+		t.Fatalf("expected panic entry from goroutine, got %+v", rec.entries)
+	}
+}
+
+type lockedRecorder struct {
+	recordingLogger
+	mu sync.Mutex
+}
+
+func (r *lockedRecorder) Logp(level Level, msg string, keyvals ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recordingLogger.Logp(level, msg, keyvals...)
+}
+
+func (r *lockedRecorder) WithTrace(context.Context) ForLogging { return r }
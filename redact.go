@@ -0,0 +1,198 @@
+package logport
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// DefaultRedactReplacement is the value written in place of a redacted field
+// when RedactOptions.Replacement is empty.
+const DefaultRedactReplacement = "[REDACTED]"
+
+// RedactOptions configures Redact.
+type RedactOptions struct {
+	// Keys lists the field names whose values are replaced, matched without
+	// regard to case. Fields nested in slog groups are matched by their own
+	// key.
+	Keys []string
+
+	// Replacement is written instead of the original value. Defaults to
+	// DefaultRedactReplacement.
+	Replacement string
+}
+
+// Redact wraps logger so that the values of the fields named in opts.Keys
+// never reach it, whether they are passed to a logging call, attached with
+// With or carried by a slog record. Messages are left untouched.
+func Redact(logger ForLogging, opts RedactOptions) ForLogging {
+	if logger == nil {
+		logger = noopLogger{}
+	}
+	if opts.Replacement == "" {
+		opts.Replacement = DefaultRedactReplacement
+	}
+	keys := make(map[string]struct{}, len(opts.Keys))
+	for _, key := range opts.Keys {
+		keys[strings.ToLower(key)] = struct{}{}
+	}
+	state := &redactState{keys: keys, replacement: opts.Replacement}
+	return state.logger(logger)
+}
+
+type redactState struct {
+	keys        map[string]struct{}
+	replacement string
+}
+
+func (s *redactState) match(key string) bool {
+	_, ok := s.keys[strings.ToLower(key)]
+	return ok
+}
+
+// keyvals returns keyvals with matching values replaced. The input is only
+// copied when something needs redacting.
+func (s *redactState) keyvals(keyvals []any) []any {
+	var out []any
+	for i := 0; i < len(keyvals); i++ {
+		switch key := keyvals[i].(type) {
+		case slog.Attr:
+			if redacted, changed := s.attr(key); changed {
+				out = s.copyOnce(out, keyvals)
+				out[i] = redacted
+			}
+		case string:
+			if i+1 < len(keyvals) && s.match(key) {
+				out = s.copyOnce(out, keyvals)
+				out[i+1] = s.replacement
+			}
+			i++
+		default:
+			i++
+		}
+	}
+	if out == nil {
+		return keyvals
+	}
+	return out
+}
+
+func (s *redactState) copyOnce(out, keyvals []any) []any {
+	if out != nil {
+		return out
+	}
+	return append([]any(nil), keyvals...)
+}
+
+func (s *redactState) attr(attr slog.Attr) (slog.Attr, bool) {
+	if s.match(attr.Key) {
+		return slog.String(attr.Key, s.replacement), true
+	}
+	if attr.Value.Kind() != slog.KindGroup {
+		return attr, false
+	}
+	group := attr.Value.Group()
+	attrs, changed := s.attrs(group)
+	if !changed {
+		return attr, false
+	}
+	return slog.Attr{Key: attr.Key, Value: slog.GroupValue(attrs...)}, true
+}
+
+func (s *redactState) attrs(attrs []slog.Attr) ([]slog.Attr, bool) {
+	var out []slog.Attr
+	for i, attr := range attrs {
+		redacted, changed := s.attr(attr)
+		if !changed {
+			continue
+		}
+		if out == nil {
+			out = append([]slog.Attr(nil), attrs...)
+		}
+		out[i] = redacted
+	}
+	if out == nil {
+		return attrs, false
+	}
+	return out, true
+}
+
+type redactLogger struct {
+	middleware
+	state *redactState
+}
+
+func (s *redactState) logger(target ForLogging) ForLogging {
+	return redactLogger{middleware: middleware{target: target, wrap: s.logger}, state: s}
+}
+
+func (l redactLogger) With(keyvals ...any) ForLogging {
+	if len(keyvals) == 0 {
+		return l
+	}
+	return l.wrap(l.target.With(l.state.keyvals(keyvals)...))
+}
+
+func (l redactLogger) Logp(level Level, msg string, keyvals ...any) {
+	l.target.Logp(level, msg, l.state.keyvals(keyvals)...)
+}
+
+func (l redactLogger) Logf(level Level, format string, v ...any) {
+	l.target.Logp(level, fmt.Sprintf(format, v...))
+}
+
+func (l redactLogger) Logs(level string, msg string, keyvals ...any) {
+	l.target.Logs(level, msg, l.state.keyvals(keyvals)...)
+}
+
+func (l redactLogger) Log(ctx context.Context, level slog.Level, msg string, keyvals ...any) {
+	l.target.Log(ctx, level, msg, l.state.keyvals(keyvals)...)
+}
+
+func (l redactLogger) Trace(msg string, keyvals ...any) { l.Logp(TraceLevel, msg, keyvals...) }
+func (l redactLogger) Debug(msg string, keyvals ...any) { l.Logp(DebugLevel, msg, keyvals...) }
+func (l redactLogger) Info(msg string, keyvals ...any)  { l.Logp(InfoLevel, msg, keyvals...) }
+func (l redactLogger) Warn(msg string, keyvals ...any)  { l.Logp(WarnLevel, msg, keyvals...) }
+func (l redactLogger) Error(msg string, keyvals ...any) { l.Logp(ErrorLevel, msg, keyvals...) }
+func (l redactLogger) Fatal(msg string, keyvals ...any) {
+	l.target.Fatal(msg, l.state.keyvals(keyvals)...)
+}
+func (l redactLogger) Panic(msg string, keyvals ...any) {
+	l.target.Panic(msg, l.state.keyvals(keyvals)...)
+}
+
+func (l redactLogger) Tracef(format string, v ...any) { l.target.Tracef(format, v...) }
+func (l redactLogger) Debugf(format string, v ...any) { l.target.Debugf(format, v...) }
+func (l redactLogger) Infof(format string, v ...any)  { l.target.Infof(format, v...) }
+func (l redactLogger) Warnf(format string, v ...any)  { l.target.Warnf(format, v...) }
+func (l redactLogger) Errorf(format string, v ...any) { l.target.Errorf(format, v...) }
+func (l redactLogger) Fatalf(format string, v ...any) { l.target.Fatalf(format, v...) }
+func (l redactLogger) Panicf(format string, v ...any) { l.target.Panicf(format, v...) }
+
+func (l redactLogger) Write(p []byte) (int, error) {
+	return WriteToLogger(l, p)
+}
+
+func (l redactLogger) Handle(ctx context.Context, record slog.Record) error {
+	changed := false
+	record.Attrs(func(attr slog.Attr) bool {
+		_, changed = l.state.attr(attr)
+		return !changed
+	})
+	if !changed {
+		return l.target.Handle(ctx, record)
+	}
+	redacted := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		attr, _ = l.state.attr(attr)
+		redacted.AddAttrs(attr)
+		return true
+	})
+	return l.target.Handle(ctx, redacted)
+}
+
+func (l redactLogger) WithAttrs(attrs []slog.Attr) slog.Handler {
+	attrs, _ = l.state.attrs(attrs)
+	return l.rewrap(l.target.WithAttrs(attrs), l.wrap)
+}
@@ -0,0 +1,49 @@
+package logport_test
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	logport "pkt.systems/logport"
+	psladapter "pkt.systems/logport/adapters/psl"
+)
+
+func TestRedactReplacesFieldValues(t *testing.T) {
+	buf := &lockedBuffer{}
+	base := psladapter.NewWithOptions(buf, psladapter.Options{Mode: psladapter.ModeStructured, DisableTimestamp: true, NoColor: true})
+	logger := logport.Redact(base, logport.RedactOptions{Keys: []string{"password", "Authorization"}})
+
+	logger.With("authorization", "Bearer abc").Info("login", "user", "ada", "Password", "hunter2")
+	slog.New(logger).WithGroup("req").Info("call", slog.Group("headers", "authorization", "Bearer def", "accept", "*/*"))
+
+	if strings.Contains(buf.String(), "hunter2") || strings.Contains(buf.String(), "Bearer") {
+		t.Fatalf("secret leaked: %s", buf.String())
+	}
+	records := decodeRecords(t, buf.Bytes())
+	if len(records) != 2 {
+		t.Fatalf("expected two entries, got %s", buf.String())
+	}
+	if records[0]["Password"] != logport.DefaultRedactReplacement || records[0]["user"] != "ada" {
+		t.Fatalf("unexpected entry %v", records[0])
+	}
+	if !strings.Contains(buf.String(), `"req.headers.accept":"*/*"`) {
+		t.Fatalf("expected sibling fields to survive, got %s", buf.String())
+	}
+}
+
+func TestRedactLeavesUnmatchedKeyvalsAlone(t *testing.T) {
+	buf := &lockedBuffer{}
+	base := psladapter.NewWithOptions(buf, psladapter.Options{Mode: psladapter.ModeStructured, DisableTimestamp: true, NoColor: true})
+	logger := logport.Redact(base, logport.RedactOptions{Keys: []string{"token"}, Replacement: "***"})
+
+	logger.Warn("refresh", "token", "t0", "attempt", 2, slog.String("token", "t1"))
+
+	records := decodeRecords(t, buf.Bytes())
+	if len(records) != 1 || records[0]["token"] != "***" || records[0]["attempt"] != float64(2) {
+		t.Fatalf("unexpected entries %s", buf.String())
+	}
+	if strings.Contains(buf.String(), "t0") || strings.Contains(buf.String(), "t1") {
+		t.Fatalf("secret leaked: %s", buf.String())
+	}
+}
@@ -0,0 +1,88 @@
+package logport
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// DefaultBackend is the backend NewFromEnv opens when the variable is unset.
+const DefaultBackend = "psl"
+
+// ErrUnknownBackend is wrapped by the error Open returns for a name nobody
+// registered.
+var ErrUnknownBackend = errors.New("unknown logging backend")
+
+// Factory constructs a backend writing to w.
+type Factory func(w io.Writer, opts CommonOptions) (ForLogging, error)
+
+var registry = struct {
+	sync.RWMutex
+	factories map[string]Factory
+}{factories: make(map[string]Factory)}
+
+// Register makes a backend available to Open under name. The adapter
+// packages register themselves when imported ("psl", "zerolog", "zap",
+// "phuslu", "onelog", "charm" and "slog"), so a program selecting a backend
+// at runtime imports the ones it wants to offer:
+//
+//	import _ "pkt.systems/logport/adapters/zaplogger"
+//
+// Register panics when name is empty, factory is nil or name is already
+// registered.
+func Register(name string, factory Factory) {
+	if name == "" {
+		panic("logport: Register with empty backend name")
+	}
+	if factory == nil {
+		panic("logport: Register of nil factory for backend " + name)
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if _, dup := registry.factories[name]; dup {
+		panic("logport: Register called twice for backend " + name)
+	}
+	registry.factories[name] = factory
+}
+
+// Backends returns the registered backend names in sorted order.
+func Backends() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	names := make([]string, 0, len(registry.factories))
+	for name := range registry.factories {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Open constructs the backend registered as name. An unknown name yields an
+// error wrapping ErrUnknownBackend that lists the registered backends.
+func Open(name string, w io.Writer, opts CommonOptions) (ForLogging, error) {
+	registry.RLock()
+	factory, ok := registry.factories[name]
+	registry.RUnlock()
+	if !ok {
+		registered := Backends()
+		if len(registered) == 0 {
+			return nil, fmt.Errorf("logport: %w %q (no backends registered; import an adapter package)", ErrUnknownBackend, name)
+		}
+		return nil, fmt.Errorf("logport: %w %q (registered: %s)", ErrUnknownBackend, name, strings.Join(registered, ", "))
+	}
+	return factory(w, opts)
+}
+
+// NewFromEnv opens the backend named by the environment variable key, or
+// DefaultBackend when it is unset or empty, with zero CommonOptions.
+func NewFromEnv(key string, w io.Writer) (ForLogging, error) {
+	name := strings.TrimSpace(os.Getenv(key))
+	if name == "" {
+		name = DefaultBackend
+	}
+	return Open(name, w, CommonOptions{})
+}
@@ -0,0 +1,117 @@
+package logport_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	logport "pkt.systems/logport"
+)
+
+func TestAdaptersRegisterThemselves(t *testing.T) {
+	registered := logport.Backends()
+	for _, name := range []string{"charm", "onelog", "phuslu", "psl", "slog", "zap", "zerolog"} {
+		if !slices.Contains(registered, name) {
+			t.Errorf("backend %q not registered, have %v", name, registered)
+		}
+	}
+	if !slices.IsSorted(registered) {
+		t.Fatalf("expected sorted names, got %v", registered)
+	}
+}
+
+func TestOpenHonoursCommonOptions(t *testing.T) {
+	info := logport.InfoLevel
+	for _, name := range logport.Backends() {
+		for _, structured := range []bool{false, true} {
+			var buf bytes.Buffer
+			logger, err := logport.Open(name, &buf, logport.CommonOptions{MinLevel: &info, Structured: structured})
+			if err != nil {
+				t.Fatalf("%s: open: %v", name, err)
+			}
+			logger.Debug("debug-entry")
+			logger.Info("info-entry", "k", "v")
+			out := buf.String()
+			if strings.Contains(out, "debug-entry") || !strings.Contains(out, "info-entry") {
+				t.Errorf("%s (structured=%v): expected only the info entry, got %q", name, structured, out)
+			}
+			if (structured || name == "onelog") && !strings.HasPrefix(strings.TrimSpace(out), "{") {
+				t.Errorf("%s: expected JSON output, got %q", name, out)
+			}
+		}
+	}
+}
+
+func TestOpenEveryBackendWithZeroOptions(t *testing.T) {
+	for _, name := range logport.Backends() {
+		var buf bytes.Buffer
+		logger, err := logport.Open(name, &buf, logport.CommonOptions{})
+		if err != nil {
+			t.Errorf("%s: open with zero options: %v", name, err)
+			continue
+		}
+		logger.Info("zero-options")
+		if !strings.Contains(buf.String(), "zero-options") {
+			t.Errorf("%s: expected the entry, got %q", name, buf.String())
+		}
+	}
+}
+
+func TestOpenUnknownBackendListsRegistered(t *testing.T) {
+	_, err := logport.Open("log4go", io.Discard, logport.CommonOptions{})
+	if !errors.Is(err, logport.ErrUnknownBackend) {
+		t.Fatalf("expected ErrUnknownBackend, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `"log4go"`) || !strings.Contains(err.Error(), strings.Join(logport.Backends(), ", ")) {
+		t.Fatalf("expected the registered backends in %q", err)
+	}
+}
+
+func TestRegisterRejectsDuplicates(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic for a duplicate registration")
+		}
+	}()
+	logport.Register("psl", func(io.Writer, logport.CommonOptions) (logport.ForLogging, error) {
+		return logport.NoopLogger(), nil
+	})
+}
+
+func TestNewFromEnvSelectsBackend(t *testing.T) {
+	var buf bytes.Buffer
+	t.Setenv("TEST_LOGPORT_BACKEND", "zap")
+	logger, err := logport.NewFromEnv("TEST_LOGPORT_BACKEND", &buf)
+	if err != nil {
+		t.Fatalf("new from env: %v", err)
+	}
+	logger.Info("hello")
+	if !strings.Contains(buf.String(), "hello") || !strings.Contains(buf.String(), "INFO") {
+		t.Fatalf("expected zap console output, got %q", buf.String())
+	}
+
+	t.Setenv("TEST_LOGPORT_BACKEND", "")
+	logger, err = logport.NewFromEnv("TEST_LOGPORT_BACKEND", io.Discard)
+	if err != nil || logger == nil {
+		t.Fatalf("expected the default backend, got %v", err)
+	}
+
+	t.Setenv("TEST_LOGPORT_BACKEND", "onelog")
+	buf.Reset()
+	logger, err = logport.NewFromEnv("TEST_LOGPORT_BACKEND", &buf)
+	if err != nil {
+		t.Fatalf("onelog from env: %v", err)
+	}
+	logger.Info("hello")
+	if !strings.HasPrefix(buf.String(), "{") {
+		t.Fatalf("expected onelog JSON, got %q", buf.String())
+	}
+
+	t.Setenv("TEST_LOGPORT_BACKEND", "nope")
+	if _, err := logport.NewFromEnv("TEST_LOGPORT_BACKEND", io.Discard); !errors.Is(err, logport.ErrUnknownBackend) {
+		t.Fatalf("expected ErrUnknownBackend, got %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package logport
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSampleTick is the sampling interval used when SampleOptions.Tick
+	// is not positive.
+	DefaultSampleTick = time.Second
+	// DefaultSampleFirst is the number of identical entries written per tick
+	// when SampleOptions.First is not positive.
+	DefaultSampleFirst = 100
+)
+
+// SampleOptions configures Sample.
+type SampleOptions struct {
+	// Tick is the interval after which the per-entry counters reset.
+	// Defaults to DefaultSampleTick.
+	Tick time.Duration
+
+	// First is how many entries with the same level and message are written
+	// each tick before sampling starts. Defaults to DefaultSampleFirst.
+	First int
+
+	// Thereafter writes every Thereafter-th entry once First is exhausted.
+	// Zero drops the rest of the tick.
+	Thereafter int
+}
+
+// Sample wraps logger so that a burst of entries sharing level and message
+// is thinned out: per tick the first opts.First are written, then every
+// opts.Thereafter-th. Loggers derived with With share the counters. Fatal and
+// Panic are never sampled.
+func Sample(logger ForLogging, opts SampleOptions) ForLogging {
+	if logger == nil {
+		logger = noopLogger{}
+	}
+	if opts.Tick <= 0 {
+		opts.Tick = DefaultSampleTick
+	}
+	if opts.First <= 0 {
+		opts.First = DefaultSampleFirst
+	}
+	if opts.Thereafter < 0 {
+		opts.Thereafter = 0
+	}
+	state := &sampleState{
+		tick:       opts.Tick,
+		first:      uint64(opts.First),
+		thereafter: uint64(opts.Thereafter),
+		counts:     make(map[sampleKey]uint64),
+	}
+	return state.logger(logger)
+}
+
+type sampleKey struct {
+	level Level
+	msg   string
+}
+
+type sampleState struct {
+	tick       time.Duration
+	first      uint64
+	thereafter uint64
+
+	mu     sync.Mutex
+	start  time.Time
+	counts map[sampleKey]uint64
+}
+
+// observe reports whether the entry should be written. Counters are dropped
+// together when the tick ends, which also bounds the map to one tick's worth
+// of distinct messages.
+func (s *sampleState) observe(level Level, msg string) bool {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.start) >= s.tick {
+		clear(s.counts)
+		s.start = now
+	}
+	key := sampleKey{level: level, msg: msg}
+	n := s.counts[key] + 1
+	s.counts[key] = n
+	if n <= s.first {
+		return true
+	}
+	return s.thereafter > 0 && (n-s.first)%s.thereafter == 0
+}
+
+type sampleLogger struct {
+	middleware
+	state *sampleState
+}
+
+func (s *sampleState) logger(target ForLogging) ForLogging {
+	return sampleLogger{middleware: middleware{target: target, wrap: s.logger}, state: s}
+}
+
+// keep reports whether an entry is written. Fatal and Panic always are,
+// whichever method logged them.
+func (l sampleLogger) keep(level Level, key string) bool {
+	return terminates(level) || l.state.observe(level, key)
+}
+
+func (l sampleLogger) Logp(level Level, msg string, keyvals ...any) {
+	if l.keep(level, msg) {
+		l.target.Logp(level, msg, keyvals...)
+	}
+}
+
+// Logf samples on the format string so entries differing only in their
+// arguments count together.
+func (l sampleLogger) Logf(level Level, format string, v ...any) {
+	if l.keep(level, format) {
+		l.target.Logp(level, fmt.Sprintf(format, v...))
+	}
+}
+
+func (l sampleLogger) Logs(level string, msg string, keyvals ...any) {
+	parsed, ok := ParseLevel(level)
+	if !ok {
+		parsed = NoLevel
+	}
+	l.Logp(parsed, msg, keyvals...)
+}
+
+func (l sampleLogger) Log(ctx context.Context, level slog.Level, msg string, keyvals ...any) {
+	if l.keep(LevelFromSlog(level), msg) {
+		l.target.Log(ctx, level, msg, keyvals...)
+	}
+}
+
+func (l sampleLogger) Trace(msg string, keyvals ...any) { l.Logp(TraceLevel, msg, keyvals...) }
+func (l sampleLogger) Debug(msg string, keyvals ...any) { l.Logp(DebugLevel, msg, keyvals...) }
+func (l sampleLogger) Info(msg string, keyvals ...any)  { l.Logp(InfoLevel, msg, keyvals...) }
+func (l sampleLogger) Warn(msg string, keyvals ...any)  { l.Logp(WarnLevel, msg, keyvals...) }
+func (l sampleLogger) Error(msg string, keyvals ...any) { l.Logp(ErrorLevel, msg, keyvals...) }
+func (l sampleLogger) Fatal(msg string, keyvals ...any) { l.target.Fatal(msg, keyvals...) }
+func (l sampleLogger) Panic(msg string, keyvals ...any) { l.target.Panic(msg, keyvals...) }
+
+func (l sampleLogger) Tracef(format string, v ...any) { l.Logf(TraceLevel, format, v...) }
+func (l sampleLogger) Debugf(format string, v ...any) { l.Logf(DebugLevel, format, v...) }
+func (l sampleLogger) Infof(format string, v ...any)  { l.Logf(InfoLevel, format, v...) }
+func (l sampleLogger) Warnf(format string, v ...any)  { l.Logf(WarnLevel, format, v...) }
+func (l sampleLogger) Errorf(format string, v ...any) { l.Logf(ErrorLevel, format, v...) }
+func (l sampleLogger) Fatalf(format string, v ...any) { l.target.Fatalf(format, v...) }
+func (l sampleLogger) Panicf(format string, v ...any) { l.target.Panicf(format, v...) }
+
+func (l sampleLogger) Write(p []byte) (int, error) {
+	return WriteToLogger(l, p)
+}
+
+func (l sampleLogger) Handle(ctx context.Context, record slog.Record) error {
+	if l.keep(LevelFromSlog(record.Level), record.Message) {
+		return l.target.Handle(ctx, record)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package logport_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	logport "pkt.systems/logport"
+	psladapter "pkt.systems/logport/adapters/psl"
+)
+
+func TestSampleWritesFirstThenEveryNth(t *testing.T) {
+	buf := &lockedBuffer{}
+	base := psladapter.NewWithOptions(buf, psladapter.Options{Mode: psladapter.ModeStructured, DisableTimestamp: true, NoColor: true})
+	logger := logport.Sample(base, logport.SampleOptions{Tick: time.Hour, First: 3, Thereafter: 10})
+
+	for i := 0; i < 50; i++ {
+		logger.Info("cache.miss", "i", i)
+	}
+	logger.With("component", "cache").Warn("cache.miss")
+
+	records := decodeRecords(t, buf.Bytes())
+	var got []float64
+	for _, record := range records[:len(records)-1] {
+		got = append(got, record["i"].(float64))
+	}
+	want := []float64{0, 1, 2, 12, 22, 32, 42}
+	if len(got) != len(want) {
+		t.Fatalf("expected entries %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected entries %v, got %v", want, got)
+		}
+	}
+	if last := records[len(records)-1]; last["lvl"] != "warn" || last["component"] != "cache" {
+		t.Fatalf("expected the warn entry to be counted separately, got %v", last)
+	}
+}
+
+func TestSampleResetsEachTick(t *testing.T) {
+	buf := &lockedBuffer{}
+	base := psladapter.NewWithOptions(buf, psladapter.Options{Mode: psladapter.ModeStructured, DisableTimestamp: true, NoColor: true})
+	logger := logport.Sample(base, logport.SampleOptions{Tick: 20 * time.Millisecond, First: 1})
+
+	logger.Info("tick")
+	logger.Infof("tick %d", 1)
+	logger.Infof("tick %d", 2)
+	time.Sleep(40 * time.Millisecond)
+	logger.Info("tick")
+
+	if records := decodeRecords(t, buf.Bytes()); len(records) != 3 {
+		t.Fatalf("expected one entry per message and tick, got %s", buf.String())
+	}
+}
+
+func TestSampleNeverDropsFatal(t *testing.T) {
+	buf := &lockedBuffer{}
+	exits := 0
+	base := psladapter.NewWithOptions(buf, psladapter.Options{
+		Mode:             psladapter.ModeStructured,
+		DisableTimestamp: true,
+		NoColor:          true,
+		ExitFunc:         func(int) { exits++ },
+	})
+	logger := logport.Sample(base, logport.SampleOptions{Tick: time.Hour, First: 1})
+
+	logger.Logp(logport.FatalLevel, "disk gone")
+	logger.Logp(logport.FatalLevel, "disk gone")
+	logger.Logs("fatal", "disk gone")
+	logger.Log(context.Background(), slog.LevelError+4, "disk gone")
+	if records := decodeRecords(t, buf.Bytes()); len(records) != 4 {
+		t.Fatalf("expected every fatal entry, got %s", buf.String())
+	}
+	if exits != 4 {
+		t.Fatalf("expected every fatal entry to exit, got %d exits", exits)
+	}
+}
@@ -0,0 +1,386 @@
+// Package fluent ships log entries to Fluentd or Fluent Bit using the Fluent
+// Forward protocol: msgpack over TCP, TLS or a unix socket, with entries
+// batched into PackedForward messages and optional acknowledgements:
+//
+//	sink, err := fluent.New(fluent.Options{Address: "fluent-bit:24224", Tag: "app.billing", RequireAck: true})
+//	if err != nil {
+//		return err
+//	}
+//	defer sink.Close()
+//	logger := sink.Logger()
+//	logger.Info("invoice.sent", "invoice_id", id)
+//
+// Each record carries the message under MessageKey, the level name under
+// LevelKey and the syslog severity under SeverityKey next to the keyvals.
+// Handler implements slog.Handler; Logger wraps it in the slog adapter so it
+// satisfies logport.ForLogging.
+//
+// Batches are sent while the Handler's lock is held. The entry that fills a
+// batch flushes it from the logging goroutine, so that caller, and every
+// other logger sharing the Handler, can wait up to DialTimeout plus
+// WriteTimeout, and with RequireAck a further AckTimeout, when the receiver
+// is slow or unreachable.
+package fluent
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	logport "pkt.systems/logport"
+	"pkt.systems/logport/adapters/slogger"
+	"pkt.systems/logport/internal/netsink"
+	"pkt.systems/logport/internal/severity"
+)
+
+const (
+	// MessageKey holds the entry's message in each record.
+	MessageKey = "message"
+	// LevelKey holds the logport level name in each record.
+	LevelKey = "level"
+	// SeverityKey holds the numeric syslog severity in each record.
+	SeverityKey = "severity"
+
+	defaultTag           = "logport"
+	defaultBatchSize     = 100
+	defaultFlushInterval = time.Second
+	defaultAckTimeout    = 5 * time.Second
+)
+
+var (
+	// ErrBackoff is returned while the sink waits before reconnecting; the
+	// batch is dropped.
+	ErrBackoff = netsink.ErrBackoff
+	// ErrClosed is returned by Handle once the Handler is closed.
+	ErrClosed = errors.New("fluent: handler closed")
+)
+
+// Options configures New.
+type Options struct {
+	// Network is "tcp" (default), "tls" or "unix".
+	Network string
+	// Address is host:port, or a socket path for "unix". Defaults to
+	// "localhost:24224".
+	Address string
+	// TLSConfig is used with the "tls" network.
+	TLSConfig *tls.Config
+
+	// Tag routes the entries inside Fluentd or Fluent Bit. Defaults to
+	// "logport".
+	Tag string
+	// RequireAck asks the receiver to acknowledge every batch; a batch
+	// without a matching ack within AckTimeout fails and the connection is
+	// re-established.
+	RequireAck bool
+	// AckTimeout defaults to 5s.
+	AckTimeout time.Duration
+
+	// BatchSize is the number of entries packed into one PackedForward
+	// message. Defaults to 100; 1 sends every entry immediately.
+	BatchSize int
+	// FlushInterval is the longest an entry waits for its batch to fill.
+	// Defaults to 1s.
+	FlushInterval time.Duration
+
+	// DialTimeout and WriteTimeout default to 5s.
+	DialTimeout  time.Duration
+	WriteTimeout time.Duration
+	// MinBackoff and MaxBackoff bound the delay between reconnect attempts
+	// after a failed dial, write or ack. They default to 100ms and 30s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Handler is a slog.Handler writing Forward protocol messages. Handlers
+// derived with WithAttrs and WithGroup share the connection and the batch.
+type Handler struct {
+	fwd    *forwarder
+	attrs  []slog.Attr
+	prefix string
+}
+
+// New validates opts and returns a Handler. The connection is established
+// lazily on the first batch and re-established with exponential backoff
+// after failures. A background goroutine flushes batches every
+// FlushInterval until Close.
+func New(opts Options) (*Handler, error) {
+	if opts.Network == "" {
+		opts.Network = "tcp"
+	}
+	switch opts.Network {
+	case "tcp", "tcp4", "tcp6", "tls", "unix":
+	default:
+		return nil, fmt.Errorf("fluent: unsupported network %q", opts.Network)
+	}
+	if opts.Address == "" {
+		if opts.Network == "unix" {
+			return nil, errors.New("fluent: unix network requires an Address")
+		}
+		opts.Address = "localhost:24224"
+	}
+	if opts.Tag == "" {
+		opts.Tag = defaultTag
+	}
+	if opts.AckTimeout <= 0 {
+		opts.AckTimeout = defaultAckTimeout
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = defaultFlushInterval
+	}
+	netsink.ApplyDefaults(&opts.DialTimeout, &opts.WriteTimeout, &opts.MinBackoff, &opts.MaxBackoff)
+	fwd := &forwarder{opts: opts, done: make(chan struct{}), stopped: make(chan struct{})}
+	fwd.conn = netsink.Conn{
+		Dial: func() (net.Conn, error) {
+			conn, err := netsink.Dial(opts.Network, opts.Address, opts.TLSConfig, opts.DialTimeout)
+			if err == nil {
+				fwd.reader = bufio.NewReader(conn)
+			}
+			return conn, err
+		},
+		MinBackoff: opts.MinBackoff,
+		MaxBackoff: opts.MaxBackoff,
+	}
+	go fwd.run()
+	return &Handler{fwd: fwd}, nil
+}
+
+// Logger returns a logport.ForLogging writing through h.
+func (h *Handler) Logger() logport.ForLogging {
+	return slogger.NewWithHandler(h)
+}
+
+// Flush sends the pending batch.
+func (h *Handler) Flush() error {
+	h.fwd.mu.Lock()
+	defer h.fwd.mu.Unlock()
+	return h.fwd.flushLocked()
+}
+
+// Close sends the pending batch, stops the background flusher and closes
+// the connection.
+func (h *Handler) Close() error {
+	return h.fwd.close()
+}
+
+// Enabled implements slog.Handler; level filtering is left to the logger.
+func (h *Handler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle implements slog.Handler. It returns the error of the batch the
+// entry completed, if any.
+func (h *Handler) Handle(_ context.Context, record slog.Record) error {
+	ts := record.Time
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	level := logport.LevelFromSlog(record.Level)
+	fields := make([]slog.Attr, 0, len(h.attrs)+record.NumAttrs())
+	fields = append(fields, h.attrs...)
+	record.Attrs(func(attr slog.Attr) bool {
+		fields = appendFlattened(fields, h.prefix, attr)
+		return true
+	})
+
+	entry := appendArrayHeader(nil, 2)
+	entry = appendEventTime(entry, ts)
+	entry = appendMapHeader(entry, 3+len(fields))
+	entry = appendString(entry, MessageKey)
+	entry = appendString(entry, record.Message)
+	entry = appendString(entry, LevelKey)
+	entry = appendString(entry, logport.LevelString(level))
+	entry = appendString(entry, SeverityKey)
+	entry = appendInt(entry, int64(Severity(level)))
+	for _, attr := range fields {
+		entry = appendString(entry, attr.Key)
+		entry = appendValue(entry, attr.Value)
+	}
+	return h.fwd.add(entry)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	next := &Handler{fwd: h.fwd, prefix: h.prefix, attrs: append([]slog.Attr(nil), h.attrs...)}
+	for _, attr := range attrs {
+		next.attrs = appendFlattened(next.attrs, h.prefix, attr)
+	}
+	return next
+}
+
+// WithGroup implements slog.Handler; grouped keys are joined with ".".
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &Handler{fwd: h.fwd, attrs: h.attrs, prefix: h.prefix + name + "."}
+}
+
+// Severity maps a logport level onto a syslog severity.
+func Severity(level logport.Level) int {
+	return severity.FromLevel(level)
+}
+
+func appendFlattened(dst []slog.Attr, prefix string, attr slog.Attr) []slog.Attr {
+	attr.Value = attr.Value.Resolve()
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, nested := range attr.Value.Group() {
+			dst = appendFlattened(dst, prefix, nested)
+		}
+		return dst
+	}
+	if attr.Key == "" {
+		return dst
+	}
+	attr.Key = prefix + attr.Key
+	return append(dst, attr)
+}
+
+func appendValue(b []byte, v slog.Value) []byte {
+	switch v.Kind() {
+	case slog.KindString:
+		return appendString(b, v.String())
+	case slog.KindInt64:
+		return appendInt(b, v.Int64())
+	case slog.KindUint64:
+		return appendUint(b, v.Uint64())
+	case slog.KindFloat64:
+		return appendFloat(b, v.Float64())
+	case slog.KindBool:
+		return appendBool(b, v.Bool())
+	case slog.KindTime:
+		return appendString(b, v.Time().Format(time.RFC3339Nano))
+	default:
+		if v.Kind() == slog.KindAny && v.Any() == nil {
+			return append(b, 0xc0)
+		}
+		return appendString(b, v.String())
+	}
+}
+
+type forwarder struct {
+	opts Options
+
+	mu      sync.Mutex
+	entries []byte
+	count   int
+	closed  bool
+	conn    netsink.Conn
+	reader  *bufio.Reader
+
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func (f *forwarder) add(entry []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return ErrClosed
+	}
+	f.entries = append(f.entries, entry...)
+	f.count++
+	if f.count >= f.opts.BatchSize {
+		return f.flushLocked()
+	}
+	return nil
+}
+
+func (f *forwarder) run() {
+	defer close(f.stopped)
+	ticker := time.NewTicker(f.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			f.mu.Lock()
+			_ = f.flushLocked()
+			f.mu.Unlock()
+		case <-f.done:
+			return
+		}
+	}
+}
+
+func (f *forwarder) close() error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return ErrClosed
+	}
+	f.closed = true
+	f.mu.Unlock()
+	close(f.done)
+	<-f.stopped
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return errors.Join(f.flushLocked(), f.conn.Close())
+}
+
+// flushLocked sends the pending entries as one PackedForward message:
+// [tag, entries, {"size": n, "chunk": id}]. The batch is dropped when it
+// cannot be delivered.
+func (f *forwarder) flushLocked() error {
+	if f.count == 0 {
+		return nil
+	}
+	options := 1
+	var chunk string
+	if f.opts.RequireAck {
+		options++
+		var id [16]byte
+		_, _ = rand.Read(id[:])
+		chunk = base64.StdEncoding.EncodeToString(id[:])
+	}
+	msg := appendArrayHeader(nil, 3)
+	msg = appendString(msg, f.opts.Tag)
+	msg = appendBinary(msg, f.entries)
+	msg = appendMapHeader(msg, options)
+	msg = appendString(msg, "size")
+	msg = appendInt(msg, int64(f.count))
+	if chunk != "" {
+		msg = appendString(msg, "chunk")
+		msg = appendString(msg, chunk)
+	}
+	f.entries, f.count = f.entries[:0], 0
+
+	// A batch resent after a reconnect is safe because the receiver
+	// deduplicates by chunk.
+	return f.conn.Send(func(conn net.Conn) error { return f.send(conn, msg, chunk) })
+}
+
+func (f *forwarder) send(conn net.Conn, msg []byte, chunk string) error {
+	_ = conn.SetWriteDeadline(time.Now().Add(f.opts.WriteTimeout))
+	if _, err := conn.Write(msg); err != nil {
+		return err
+	}
+	if chunk == "" {
+		return nil
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(f.opts.AckTimeout))
+	resp, err := decode(f.reader)
+	if err != nil {
+		return fmt.Errorf("fluent: reading ack: %w", err)
+	}
+	if m, ok := resp.(map[string]any); !ok || m["ack"] != chunk {
+		return fmt.Errorf("fluent: unexpected ack %v", resp)
+	}
+	return nil
+}
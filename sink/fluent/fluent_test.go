@@ -0,0 +1,232 @@
+package fluent
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type forwardMessage struct {
+	tag     string
+	entries [][]any
+	options map[string]any
+}
+
+// forwardServer accepts Forward protocol connections, decodes PackedForward
+// messages and acknowledges chunks using ack to choose the reply.
+func forwardServer(t *testing.T, ln net.Listener, ack func(chunk string) string) <-chan forwardMessage {
+	t.Helper()
+	t.Cleanup(func() { ln.Close() })
+	out := make(chan forwardMessage, 16)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					value, err := decode(r)
+					if err != nil {
+						return
+					}
+					msg, ok := parseForward(value)
+					if !ok {
+						return
+					}
+					if chunk, ok := msg.options["chunk"].(string); ok {
+						reply := appendMapHeader(nil, 1)
+						reply = appendString(reply, "ack")
+						reply = appendString(reply, ack(chunk))
+						if _, err := conn.Write(reply); err != nil {
+							return
+						}
+					}
+					out <- msg
+				}
+			}()
+		}
+	}()
+	return out
+}
+
+func parseForward(value any) (forwardMessage, bool) {
+	parts, ok := value.([]any)
+	if !ok || len(parts) != 3 {
+		return forwardMessage{}, false
+	}
+	msg := forwardMessage{}
+	msg.tag, _ = parts[0].(string)
+	msg.options, _ = parts[2].(map[string]any)
+	packed, _ := parts[1].([]byte)
+	r := bufio.NewReader(bytes.NewReader(packed))
+	for {
+		entry, err := decode(r)
+		if err != nil {
+			break
+		}
+		pair, _ := entry.([]any)
+		msg.entries = append(msg.entries, pair)
+	}
+	return msg, true
+}
+
+func receive(t *testing.T, ch <-chan forwardMessage) forwardMessage {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for a message")
+		return forwardMessage{}
+	}
+}
+
+func listenTCP(t *testing.T) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	return ln
+}
+
+func TestPackedForwardBatch(t *testing.T) {
+	ln := listenTCP(t)
+	messages := forwardServer(t, ln, func(chunk string) string { return chunk })
+
+	sink, err := New(Options{Address: ln.Addr().String(), Tag: "app.billing", BatchSize: 3, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer sink.Close()
+	logger := sink.Logger().With("req", "r1")
+	before := time.Now()
+	logger.Info("one", "n", 1, "ok", true, "ratio", 0.5, "none", nil)
+	logger.Warn("two", "neg", -300)
+	logger.Error("three")
+
+	msg := receive(t, messages)
+	if msg.tag != "app.billing" || msg.options["size"] != int64(3) || len(msg.entries) != 3 {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+	ts, ok := msg.entries[0][0].(time.Time)
+	if !ok || ts.Before(before.Add(-time.Second)) {
+		t.Fatalf("expected EventTime, got %#v", msg.entries[0][0])
+	}
+	first := msg.entries[0][1].(map[string]any)
+	want := map[string]any{MessageKey: "one", LevelKey: "info", SeverityKey: int64(6), "req": "r1", "n": int64(1), "ok": true, "ratio": 0.5, "none": nil}
+	for key, value := range want {
+		if got, ok := first[key]; !ok || got != value {
+			t.Fatalf("%s = %#v, want %#v (record %v)", key, got, value, first)
+		}
+	}
+	second := msg.entries[1][1].(map[string]any)
+	third := msg.entries[2][1].(map[string]any)
+	if second[SeverityKey] != int64(4) || second["neg"] != int64(-300) || third[LevelKey] != "error" || third[SeverityKey] != int64(3) {
+		t.Fatalf("unexpected records %v and %v", second, third)
+	}
+}
+
+func TestFlushIntervalAndAck(t *testing.T) {
+	ln := listenTCP(t)
+	messages := forwardServer(t, ln, func(chunk string) string { return chunk })
+
+	sink, err := New(Options{Address: ln.Addr().String(), RequireAck: true, FlushInterval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer sink.Close()
+	sink.Logger().Info("tick")
+
+	msg := receive(t, messages)
+	if msg.tag != defaultTag || len(msg.entries) != 1 || msg.options["chunk"] == nil {
+		t.Fatalf("expected an acknowledged single-entry batch, got %+v", msg)
+	}
+}
+
+func TestMismatchedAckFails(t *testing.T) {
+	ln := listenTCP(t)
+	forwardServer(t, ln, func(string) string { return "wrong" })
+
+	sink, err := New(Options{Address: ln.Addr().String(), RequireAck: true, FlushInterval: time.Hour, MinBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer sink.Close()
+	sink.Logger().Info("lost")
+	if err := sink.Flush(); err == nil || !strings.Contains(err.Error(), "unexpected ack") {
+		t.Fatalf("expected an ack mismatch, got %v", err)
+	}
+}
+
+func TestUnixSocketFlushesOnClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fluent.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	messages := forwardServer(t, ln, func(chunk string) string { return chunk })
+
+	sink, err := New(Options{Network: "unix", Address: path, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	sink.Logger().Debug("pending")
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if msg := receive(t, messages); len(msg.entries) != 1 || msg.entries[0][1].(map[string]any)[SeverityKey] != int64(7) {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+	if err := sink.Close(); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	long := strings.Repeat("x", 70000)
+	var b []byte
+	b = appendArrayHeader(b, 20)
+	for _, s := range []string{"", "short", strings.Repeat("y", 40), strings.Repeat("z", 300), long} {
+		b = appendString(b, s)
+	}
+	for _, v := range []int64{0, 127, 128, 300, 70000, 1 << 40, -1, -32, -33, -1 << 40} {
+		b = appendInt(b, v)
+	}
+	b = appendBinary(b, []byte{1, 2, 3})
+	b = appendFloat(b, 1.5)
+	b = appendBool(b, false)
+	b = append(b, 0xc0)
+	b = appendMapHeader(b, 20)
+	for i := 0; i < 20; i++ {
+		b = appendString(b, string(rune('a'+i)))
+		b = appendInt(b, int64(i))
+	}
+
+	value, err := decode(bufio.NewReader(bytes.NewReader(b)))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	items := value.([]any)
+	if len(items) != 20 || items[4] != long || items[3] != strings.Repeat("z", 300) {
+		t.Fatalf("unexpected strings")
+	}
+	for i, want := range []int64{0, 127, 128, 300, 70000, 1 << 40, -1, -32, -33, -1 << 40} {
+		if items[5+i] != want {
+			t.Fatalf("int %d: got %v, want %d", i, items[5+i], want)
+		}
+	}
+	if !bytes.Equal(items[15].([]byte), []byte{1, 2, 3}) || items[16] != 1.5 || items[17] != false || items[18] != nil {
+		t.Fatalf("unexpected scalars %v", items[15:19])
+	}
+	if m := items[19].(map[string]any); len(m) != 20 || m["t"] != int64(19) {
+		t.Fatalf("unexpected map %v", m)
+	}
+}
@@ -0,0 +1,371 @@
+// Package gelf ships log entries to Graylog using GELF 1.1 over UDP (with
+// chunking and optional gzip compression), TCP or TLS (null-byte delimited).
+// Keyvals become "_"-prefixed additional fields and the level is mapped onto
+// the syslog severity GELF expects:
+//
+//	sink, err := gelf.New(gelf.Options{Network: "udp", Address: "graylog:12201"})
+//	if err != nil {
+//		return err
+//	}
+//	defer sink.Close()
+//	logger := sink.Logger()
+//	logger.Warn("disk.low", "free_mb", 120)
+//
+// Handler implements slog.Handler; Logger wraps it in the slog adapter so it
+// satisfies logport.ForLogging.
+//
+// Writes are synchronous: every entry is sent from the logging goroutine
+// while the Handler's lock is held, so an unreachable or slow receiver stalls
+// all loggers sharing the Handler for up to DialTimeout plus WriteTimeout per
+// entry. UDP rarely blocks; TCP and TLS can.
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	logport "pkt.systems/logport"
+	"pkt.systems/logport/adapters/slogger"
+	"pkt.systems/logport/internal/netsink"
+	"pkt.systems/logport/internal/severity"
+)
+
+const (
+	// DefaultChunkSize is the UDP payload size above which messages are
+	// chunked; it fits a typical 1500 byte MTU.
+	DefaultChunkSize = 1420
+	// maxChunks is the most chunks a GELF message may be split into.
+	maxChunks = 128
+	// chunkHeaderSize covers the magic bytes, message ID, sequence number
+	// and sequence count.
+	chunkHeaderSize = 12
+)
+
+var (
+	// ErrBackoff is returned by Handle while the sink waits before
+	// reconnecting; the entry is dropped.
+	ErrBackoff = netsink.ErrBackoff
+	// ErrTooLarge is returned for UDP messages that need more than 128
+	// chunks.
+	ErrTooLarge = errors.New("gelf: message exceeds 128 chunks")
+)
+
+// Options configures New.
+type Options struct {
+	// Network is "udp" (default), "tcp" or "tls".
+	Network string
+	// Address is host:port. Defaults to "localhost:12201".
+	Address string
+	// TLSConfig is used with the "tls" network.
+	TLSConfig *tls.Config
+
+	// Host is the GELF host field. Defaults to os.Hostname.
+	Host string
+	// ChunkSize bounds UDP datagrams. Defaults to DefaultChunkSize.
+	ChunkSize int
+	// Compress gzips UDP messages. Stream transports are never compressed.
+	Compress bool
+
+	// DialTimeout and WriteTimeout default to 5s.
+	DialTimeout  time.Duration
+	WriteTimeout time.Duration
+	// MinBackoff and MaxBackoff bound the delay between reconnect attempts
+	// after a failed dial or write. They default to 100ms and 30s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Handler is a slog.Handler writing GELF messages. Handlers derived with
+// WithAttrs and WithGroup share the connection.
+type Handler struct {
+	conn   *connection
+	attrs  []slog.Attr
+	prefix string
+}
+
+// New validates opts and returns a Handler. The connection is established
+// lazily on the first entry and re-established with exponential backoff
+// after failures.
+func New(opts Options) (*Handler, error) {
+	if opts.Network == "" {
+		opts.Network = "udp"
+	}
+	switch opts.Network {
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6", "tls":
+	default:
+		return nil, fmt.Errorf("gelf: unsupported network %q", opts.Network)
+	}
+	if opts.Address == "" {
+		opts.Address = "localhost:12201"
+	}
+	if opts.Host == "" {
+		opts.Host, _ = os.Hostname()
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultChunkSize
+	}
+	if opts.ChunkSize <= chunkHeaderSize {
+		return nil, fmt.Errorf("gelf: chunk size %d leaves no room for data", opts.ChunkSize)
+	}
+	netsink.ApplyDefaults(&opts.DialTimeout, &opts.WriteTimeout, &opts.MinBackoff, &opts.MaxBackoff)
+	c := &connection{opts: opts}
+	c.conn = netsink.Conn{
+		Dial: func() (net.Conn, error) {
+			return netsink.Dial(opts.Network, opts.Address, opts.TLSConfig, opts.DialTimeout)
+		},
+		MinBackoff: opts.MinBackoff,
+		MaxBackoff: opts.MaxBackoff,
+	}
+	return &Handler{conn: c}, nil
+}
+
+// Logger returns a logport.ForLogging writing through h.
+func (h *Handler) Logger() logport.ForLogging {
+	return slogger.NewWithHandler(h)
+}
+
+// Close closes the connection. Later entries reconnect.
+func (h *Handler) Close() error {
+	return h.conn.close()
+}
+
+// Enabled implements slog.Handler; level filtering is left to the logger.
+func (h *Handler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle implements slog.Handler.
+func (h *Handler) Handle(_ context.Context, record slog.Record) error {
+	ts := record.Time
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	msg := Encode(h.conn.opts.Host, ts, logport.LevelFromSlog(record.Level), record.Message, h.fields(record))
+	return h.conn.send(msg)
+}
+
+func (h *Handler) fields(record slog.Record) []slog.Attr {
+	fields := make([]slog.Attr, 0, len(h.attrs)+record.NumAttrs())
+	fields = append(fields, h.attrs...)
+	record.Attrs(func(attr slog.Attr) bool {
+		fields = appendFlattened(fields, h.prefix, attr)
+		return true
+	})
+	return fields
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	next := &Handler{conn: h.conn, prefix: h.prefix, attrs: append([]slog.Attr(nil), h.attrs...)}
+	for _, attr := range attrs {
+		next.attrs = appendFlattened(next.attrs, h.prefix, attr)
+	}
+	return next
+}
+
+// WithGroup implements slog.Handler; grouped keys are joined with ".".
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &Handler{conn: h.conn, attrs: h.attrs, prefix: h.prefix + name + "."}
+}
+
+// Severity maps a logport level onto the syslog severity used by the GELF
+// level field.
+func Severity(level logport.Level) int {
+	return severity.FromLevel(level)
+}
+
+// Encode renders one GELF 1.1 message. A multi-line msg keeps its first line
+// as short_message and the whole text as full_message. fields must already
+// be flattened; they become "_"-prefixed additional fields, numbers staying
+// numbers and everything else rendered as a string.
+func Encode(host string, ts time.Time, level logport.Level, msg string, fields []slog.Attr) []byte {
+	short, _, multiline := strings.Cut(msg, "\n")
+	if short == "" {
+		short = "-"
+	}
+	b := make([]byte, 0, 128+len(msg))
+	b = append(b, `{"version":"1.1","host":`...)
+	b = appendJSONString(b, host)
+	b = append(b, `,"short_message":`...)
+	b = appendJSONString(b, short)
+	if multiline {
+		b = append(b, `,"full_message":`...)
+		b = appendJSONString(b, msg)
+	}
+	b = append(b, `,"timestamp":`...)
+	b = strconv.AppendFloat(b, float64(ts.UnixMicro())/1e6, 'f', 6, 64)
+	b = append(b, `,"level":`...)
+	b = strconv.AppendInt(b, int64(Severity(level)), 10)
+	for _, attr := range fields {
+		b = append(b, ',')
+		b = appendJSONString(b, FieldName(attr.Key))
+		b = append(b, ':')
+		switch attr.Value.Kind() {
+		case slog.KindInt64:
+			b = strconv.AppendInt(b, attr.Value.Int64(), 10)
+		case slog.KindUint64:
+			b = strconv.AppendUint(b, attr.Value.Uint64(), 10)
+		case slog.KindFloat64:
+			b = appendJSONFloat(b, attr.Value.Float64())
+		case slog.KindTime:
+			b = appendJSONString(b, attr.Value.Time().Format(time.RFC3339Nano))
+		default:
+			b = appendJSONString(b, attr.Value.String())
+		}
+	}
+	return append(b, '}')
+}
+
+// FieldName turns key into a GELF additional field name: "_" followed by
+// characters from [A-Za-z0-9_.-]. The reserved "_id" becomes "_id_".
+func FieldName(key string) string {
+	b := make([]byte, 0, len(key)+1)
+	b = append(b, '_')
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '-') {
+			c = '_'
+		}
+		b = append(b, c)
+	}
+	if string(b) == "_id" {
+		b = append(b, '_')
+	}
+	return string(b)
+}
+
+func appendFlattened(dst []slog.Attr, prefix string, attr slog.Attr) []slog.Attr {
+	attr.Value = attr.Value.Resolve()
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, nested := range attr.Value.Group() {
+			dst = appendFlattened(dst, prefix, nested)
+		}
+		return dst
+	}
+	if attr.Key == "" {
+		return dst
+	}
+	attr.Key = prefix + attr.Key
+	return append(dst, attr)
+}
+
+func appendJSONString(b []byte, s string) []byte {
+	quoted, _ := json.Marshal(s)
+	return append(b, quoted...)
+}
+
+func appendJSONFloat(b []byte, f float64) []byte {
+	encoded, err := json.Marshal(f)
+	if err != nil {
+		// NaN and infinities are not valid JSON numbers.
+		return appendJSONString(b, strconv.FormatFloat(f, 'g', -1, 64))
+	}
+	return append(b, encoded...)
+}
+
+type connection struct {
+	opts Options
+
+	mu   sync.Mutex
+	conn netsink.Conn
+}
+
+func (c *connection) send(msg []byte) error {
+	var (
+		packets [][]byte
+		err     error
+	)
+	if c.isStream() {
+		packets = [][]byte{append(msg, 0)}
+	} else {
+		if c.opts.Compress {
+			if msg, err = compress(msg); err != nil {
+				return err
+			}
+		}
+		if packets, err = chunk(msg, c.opts.ChunkSize); err != nil {
+			return err
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.Send(func(conn net.Conn) error {
+		_ = conn.SetWriteDeadline(time.Now().Add(c.opts.WriteTimeout))
+		for _, packet := range packets {
+			if _, err := conn.Write(packet); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (c *connection) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.Close()
+}
+
+func (c *connection) isStream() bool {
+	return !strings.HasPrefix(c.opts.Network, "udp")
+}
+
+// chunk splits msg into GELF chunks of at most size bytes when it does not
+// fit in one datagram.
+func chunk(msg []byte, size int) ([][]byte, error) {
+	if len(msg) <= size {
+		return [][]byte{msg}, nil
+	}
+	payload := size - chunkHeaderSize
+	count := (len(msg) + payload - 1) / payload
+	if count > maxChunks {
+		return nil, ErrTooLarge
+	}
+	var id [8]byte
+	binary.BigEndian.PutUint64(id[:], rand.Uint64())
+	chunks := make([][]byte, 0, count)
+	for seq := 0; seq < count; seq++ {
+		part := msg[seq*payload : min((seq+1)*payload, len(msg))]
+		c := make([]byte, 0, chunkHeaderSize+len(part))
+		c = append(c, 0x1e, 0x0f)
+		c = append(c, id[:]...)
+		c = append(c, byte(seq), byte(count))
+		chunks = append(chunks, append(c, part...))
+	}
+	return chunks, nil
+}
+
+func compress(msg []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(msg); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,178 @@
+package gelf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	logport "pkt.systems/logport"
+)
+
+func TestUDPAdditionalFieldsAndSeverity(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer pc.Close()
+
+	sink, err := New(Options{Address: pc.LocalAddr().String(), Host: "web-01"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer sink.Close()
+	slog.New(sink).With("id", "r1").WithGroup("http").Warn("disk.low\nsee runbook", "free_mb", 120, "ratio", 0.25, "path", "/var", "ok", false)
+
+	msg := decode(t, readPacket(t, pc))
+	want := map[string]any{
+		"version":       "1.1",
+		"host":          "web-01",
+		"short_message": "disk.low",
+		"full_message":  "disk.low\nsee runbook",
+		"level":         float64(4),
+		"_id_":          "r1",
+		"_http.free_mb": float64(120),
+		"_http.ratio":   0.25,
+		"_http.path":    "/var",
+		"_http.ok":      "false",
+	}
+	for key, value := range want {
+		if msg[key] != value {
+			t.Fatalf("%s = %#v, want %#v (message %v)", key, msg[key], value, msg)
+		}
+	}
+	if ts, ok := msg["timestamp"].(float64); !ok || time.Since(time.Unix(int64(ts), 0)) > time.Minute {
+		t.Fatalf("unexpected timestamp %v", msg["timestamp"])
+	}
+}
+
+func TestUDPChunkingWithCompression(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer pc.Close()
+
+	sink, err := New(Options{Address: pc.LocalAddr().String(), ChunkSize: 100, Compress: true})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer sink.Close()
+	// Random-ish text so gzip cannot shrink it below one chunk.
+	var payload strings.Builder
+	for i := 0; i < 200; i++ {
+		payload.WriteString(time.Duration(i * 7919).String())
+	}
+	sink.Logger().Info("big", "payload", payload.String())
+
+	first := readPacket(t, pc)
+	if first[0] != 0x1e || first[1] != 0x0f {
+		t.Fatalf("expected a chunked message, got %x", first[:2])
+	}
+	count := int(first[11])
+	parts := make([][]byte, count)
+	parts[first[10]] = first[12:]
+	for received := 1; received < count; received++ {
+		chunk := readPacket(t, pc)
+		if !bytes.Equal(chunk[2:10], first[2:10]) || int(chunk[11]) != count || len(chunk) > 100 {
+			t.Fatalf("inconsistent chunk header %x", chunk[:12])
+		}
+		parts[chunk[10]] = chunk[12:]
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(bytes.Join(parts, nil)))
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	raw, _ := io.ReadAll(zr)
+	if msg := decode(t, raw); msg["_payload"] != payload.String() || msg["level"] != float64(6) {
+		t.Fatalf("unexpected reassembled message %v", msg)
+	}
+}
+
+func TestTCPNullDelimited(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	messages := make(chan []byte, 4)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			msg, err := r.ReadBytes(0)
+			if err != nil {
+				return
+			}
+			messages <- msg[:len(msg)-1]
+		}
+	}()
+
+	sink, err := New(Options{Network: "tcp", Address: ln.Addr().String(), Compress: true})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer sink.Close()
+	sink.Logger().Error("one")
+	sink.Logger().Debug("two")
+	for _, want := range []struct {
+		msg   string
+		level float64
+	}{{"one", 3}, {"two", 7}} {
+		select {
+		case raw := <-messages:
+			if msg := decode(t, raw); msg["short_message"] != want.msg || msg["level"] != want.level {
+				t.Fatalf("unexpected message %v", msg)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %q", want.msg)
+		}
+	}
+}
+
+func TestEncodeEdgeCases(t *testing.T) {
+	raw := Encode("h", time.Unix(1700000000, 123456000), logport.PanicLevel, "", []slog.Attr{slog.String("bad key!", "v")})
+	msg := decode(t, raw)
+	if msg["short_message"] != "-" || msg["level"] != float64(1) || msg["_bad_key_"] != "v" || msg["full_message"] != nil {
+		t.Fatalf("unexpected message %s", raw)
+	}
+	if !bytes.Contains(raw, []byte(`"timestamp":1700000000.123456`)) {
+		t.Fatalf("expected microsecond timestamp in %s", raw)
+	}
+	if _, err := chunk(make([]byte, 129*10), 22); err != ErrTooLarge {
+		t.Fatalf("expected ErrTooLarge, got %v", err)
+	}
+	if _, err := New(Options{Network: "unix"}); err == nil {
+		t.Fatalf("expected unsupported network error")
+	}
+}
+
+func decode(t *testing.T, raw []byte) map[string]any {
+	t.Helper()
+	var msg map[string]any
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		t.Fatalf("decode %s: %v", raw, err)
+	}
+	return msg
+}
+
+func readPacket(t *testing.T, pc net.PacketConn) []byte {
+	t.Helper()
+	buf := make([]byte, 64*1024)
+	_ = pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return buf[:n]
+}
@@ -0,0 +1,170 @@
+package httpship
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// JSONArray encodes a batch as a JSON array of the entries.
+func JSONArray() Encoder {
+	return jsonArrayEncoder{}
+}
+
+type jsonArrayEncoder struct{}
+
+func (jsonArrayEncoder) ContentType() string { return "application/json" }
+
+func (jsonArrayEncoder) Encode(entries []Entry) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, entry := range entries {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(entry.Line)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+// ElasticsearchOptions configures Elasticsearch.
+type ElasticsearchOptions struct {
+	// Index names the target index or data stream. When empty the index
+	// must be part of the URL (/<index>/_bulk).
+	Index string
+}
+
+// Elasticsearch encodes a batch as _bulk NDJSON with a create action per
+// entry, which works for both indices and data streams. Per-item failures
+// reported in the response body are not inspected.
+func Elasticsearch(opts ElasticsearchOptions) Encoder {
+	action := []byte(`{"create":{}}`)
+	if opts.Index != "" {
+		index, _ := json.Marshal(opts.Index)
+		action = []byte(`{"create":{"_index":` + string(index) + `}}`)
+	}
+	return elasticsearchEncoder{action: action}
+}
+
+type elasticsearchEncoder struct {
+	action []byte
+}
+
+func (elasticsearchEncoder) ContentType() string { return "application/x-ndjson" }
+
+func (e elasticsearchEncoder) Encode(entries []Entry) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		buf.Write(e.action)
+		buf.WriteByte('\n')
+		buf.Write(entry.Line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// DefaultLokiJob is the "job" label of entries that would otherwise carry no
+// labels at all, which Loki rejects with 400 Bad Request.
+const DefaultLokiJob = "logport"
+
+// LokiOptions configures Loki.
+type LokiOptions struct {
+	// Labels selects top-level entry keys whose values become stream labels.
+	// Keep the set small and low-cardinality, such as "app" or "lvl".
+	Labels []string
+	// StaticLabels are added to every stream, for example {"job": "edge"}.
+	StaticLabels map[string]string
+}
+
+// Loki encodes a batch for Loki's /loki/api/v1/push endpoint. Entries are
+// grouped into streams by their label values; each line is the entry's JSON
+// object with the time it was written as timestamp. An entry left without
+// any label is sent with job=DefaultLokiJob.
+func Loki(opts LokiOptions) Encoder {
+	return lokiEncoder{labels: slices.Clone(opts.Labels), static: maps.Clone(opts.StaticLabels)}
+}
+
+type lokiEncoder struct {
+	labels []string
+	static map[string]string
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func (lokiEncoder) ContentType() string { return "application/json" }
+
+func (e lokiEncoder) Encode(entries []Entry) ([]byte, error) {
+	var streams []*lokiStream
+	index := make(map[string]*lokiStream)
+	for _, entry := range entries {
+		labels := e.entryLabels(entry.Line)
+		key := labelKey(labels)
+		stream, ok := index[key]
+		if !ok {
+			stream = &lokiStream{Stream: labels}
+			index[key] = stream
+			streams = append(streams, stream)
+		}
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(entry.Time.UnixNano(), 10), string(entry.Line)})
+	}
+	return json.Marshal(map[string][]*lokiStream{"streams": streams})
+}
+
+func (e lokiEncoder) entryLabels(line []byte) map[string]string {
+	labels := make(map[string]string, len(e.static)+len(e.labels))
+	for name, value := range e.static {
+		labels[labelName(name)] = value
+	}
+	var fields map[string]any
+	if len(e.labels) > 0 && json.Unmarshal(line, &fields) != nil {
+		fields = nil
+	}
+	for _, key := range e.labels {
+		value, ok := fields[key]
+		if !ok || value == nil {
+			continue
+		}
+		if s, ok := value.(string); ok {
+			labels[labelName(key)] = s
+		} else {
+			labels[labelName(key)] = fmt.Sprint(value)
+		}
+	}
+	if len(labels) == 0 {
+		labels["job"] = DefaultLokiJob
+	}
+	return labels
+}
+
+func labelKey(labels map[string]string) string {
+	var b strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(labels)) {
+		b.WriteString(name)
+		b.WriteByte(0)
+		b.WriteString(labels[name])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// labelName makes name a valid Prometheus label name.
+func labelName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			b[i] = '_'
+		}
+	}
+	if len(b) == 0 {
+		return "_"
+	}
+	return string(b)
+}
@@ -0,0 +1,418 @@
+// Package httpship batches log entries and ships them to an HTTP endpoint:
+// Loki's push API, an Elasticsearch _bulk endpoint or any service accepting a
+// JSON array. A Shipper is an io.Writer, so it sits underneath any adapter
+// emitting one JSON object per entry:
+//
+//	shipper, err := httpship.New(httpship.Options{
+//		URL:     "http://loki:3100/loki/api/v1/push",
+//		Encoder: httpship.Loki(httpship.LokiOptions{Labels: []string{"app", "lvl"}}),
+//		Gzip:    true,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer shipper.Close()
+//	logger := psl.NewWithOptions(shipper, psl.Options{Mode: psl.ModeStructured})
+//
+// Batches are sent when they reach BatchSize entries or BatchBytes, or when
+// the oldest entry has waited FlushInterval. Failed requests are retried with
+// exponential backoff and jitter; entries that do not fit in MaxBufferedBytes
+// are dropped and counted rather than blocking the caller.
+package httpship
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultBatchSize        = 1000
+	defaultBatchBytes       = 1 << 20
+	defaultFlushInterval    = time.Second
+	defaultMaxBufferedBytes = 8 << 20
+	defaultMaxRetries       = 5
+	defaultMinBackoff       = 100 * time.Millisecond
+	defaultMaxBackoff       = 10 * time.Second
+	defaultTimeout          = 10 * time.Second
+)
+
+// ErrClosed is returned by Write, Flush and Close once the Shipper is closed.
+var ErrClosed = errors.New("httpship: shipper closed")
+
+// Entry is one buffered log entry.
+type Entry struct {
+	// Time is when the entry was written to the Shipper.
+	Time time.Time
+	// Line is the entry as a JSON object without a trailing newline.
+	Line []byte
+}
+
+// Encoder turns a batch of entries into a request body.
+type Encoder interface {
+	ContentType() string
+	Encode(entries []Entry) ([]byte, error)
+}
+
+// Options configures New.
+type Options struct {
+	// URL receives the batches via POST. Required.
+	URL string
+	// Encoder formats batches. Defaults to JSONArray.
+	Encoder Encoder
+	// Client defaults to an http.Client with Timeout.
+	Client *http.Client
+	// Header is added to every request, for example for authentication.
+	Header http.Header
+	// Gzip compresses request bodies.
+	Gzip bool
+
+	// BatchSize and BatchBytes bound one request. They default to 1000
+	// entries and 1 MiB.
+	BatchSize  int
+	BatchBytes int
+	// FlushInterval is the longest an entry waits for its batch to fill.
+	// Defaults to 1s.
+	FlushInterval time.Duration
+	// MaxBufferedBytes bounds the entries waiting to be sent; further entries
+	// are dropped. Defaults to 8 MiB.
+	MaxBufferedBytes int
+
+	// MaxRetries is how often a failed batch is retried before it is
+	// dropped. Defaults to 5; negative disables retries.
+	MaxRetries int
+	// MinBackoff and MaxBackoff bound the delay between retries. They
+	// default to 100ms and 10s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Timeout applies to each request made by the default Client. Defaults
+	// to 10s.
+	Timeout time.Duration
+
+	// OnError, when set, is called from the sending goroutine with every
+	// batch that is given up on and the number of entries lost.
+	OnError func(err error, dropped int)
+}
+
+// Shipper buffers entries written to it and sends them in batches from a
+// background goroutine.
+type Shipper struct {
+	opts Options
+
+	mu      sync.Mutex
+	queue   []Entry
+	bytes   int
+	closed  bool
+	dropped atomic.Uint64
+
+	wake    chan struct{}
+	flushes chan chan error
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// New validates opts and starts the sending goroutine.
+func New(opts Options) (*Shipper, error) {
+	if opts.URL == "" {
+		return nil, errors.New("httpship: URL is required")
+	}
+	if opts.Encoder == nil {
+		opts.Encoder = JSONArray()
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: opts.Timeout}
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	if opts.BatchBytes <= 0 {
+		opts.BatchBytes = defaultBatchBytes
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = defaultFlushInterval
+	}
+	if opts.MaxBufferedBytes <= 0 {
+		opts.MaxBufferedBytes = defaultMaxBufferedBytes
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = defaultMaxRetries
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = defaultMinBackoff
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = max(defaultMaxBackoff, opts.MinBackoff)
+	}
+	s := &Shipper{
+		opts:    opts,
+		wake:    make(chan struct{}, 1),
+		flushes: make(chan chan error),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// Write buffers every non-empty line of p as one entry. Lines that are not
+// JSON objects are wrapped as {"msg": line}. Entries that would exceed
+// MaxBufferedBytes are dropped; Write never blocks on the network.
+func (s *Shipper) Write(p []byte) (int, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, ErrClosed
+	}
+	wasEmpty := len(s.queue) == 0
+	for _, line := range bytes.Split(p, []byte{'\n'}) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if line[0] != '{' || !json.Valid(line) {
+			line, _ = json.Marshal(map[string]string{"msg": string(line)})
+		} else {
+			line = bytes.Clone(line)
+		}
+		if s.bytes+len(line) > s.opts.MaxBufferedBytes {
+			s.dropped.Add(1)
+			continue
+		}
+		s.queue = append(s.queue, Entry{Time: now, Line: line})
+		s.bytes += len(line)
+	}
+	if wasEmpty || s.fullLocked() {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends everything buffered and returns the first error encountered.
+func (s *Shipper) Flush(ctx context.Context) error {
+	reply := make(chan error, 1)
+	select {
+	case s.flushes <- reply:
+	case <-s.stopped:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting entries, sends what is buffered and stops the
+// sending goroutine.
+func (s *Shipper) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+	s.closed = true
+	s.mu.Unlock()
+	err := s.Flush(context.Background())
+	close(s.done)
+	<-s.stopped
+	return err
+}
+
+// Dropped reports how many entries were lost to a full buffer or to batches
+// given up on after retries.
+func (s *Shipper) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+func (s *Shipper) run() {
+	defer close(s.stopped)
+	for {
+		var (
+			timer   *time.Timer
+			timeout <-chan time.Time
+		)
+		if wait, ok := s.untilDue(); ok {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+		select {
+		case <-s.wake:
+		case <-timeout:
+		case reply := <-s.flushes:
+			reply <- s.ship(true)
+		case <-s.done:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		_ = s.ship(false)
+	}
+}
+
+// untilDue reports how long until the oldest buffered entry has waited
+// FlushInterval; ok is false when nothing is buffered.
+func (s *Shipper) untilDue() (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) == 0 {
+		return 0, false
+	}
+	return max(0, time.Until(s.queue[0].Time.Add(s.opts.FlushInterval))), true
+}
+
+func (s *Shipper) fullLocked() bool {
+	return len(s.queue) >= s.opts.BatchSize || s.bytes >= s.opts.BatchBytes
+}
+
+// ship sends batches while one is full or due, or until the buffer is empty
+// when all is set.
+func (s *Shipper) ship(all bool) error {
+	var first error
+	for {
+		batch := s.take(all)
+		if len(batch) == 0 {
+			return first
+		}
+		if err := s.send(batch); err != nil && first == nil {
+			first = err
+		}
+	}
+}
+
+// take removes the next batch from the queue when it is ready to be sent.
+func (s *Shipper) take(all bool) []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) == 0 {
+		return nil
+	}
+	if !all && !s.fullLocked() && time.Since(s.queue[0].Time) < s.opts.FlushInterval {
+		return nil
+	}
+	n, size := 0, 0
+	for n < len(s.queue) && n < s.opts.BatchSize {
+		if n > 0 && size+len(s.queue[n].Line) > s.opts.BatchBytes {
+			break
+		}
+		size += len(s.queue[n].Line)
+		n++
+	}
+	batch := s.queue[:n:n]
+	s.queue = s.queue[n:]
+	s.bytes -= size
+	if len(s.queue) == 0 {
+		s.queue = nil
+	}
+	return batch
+}
+
+func (s *Shipper) send(batch []Entry) error {
+	body, err := s.opts.Encoder.Encode(batch)
+	if err == nil && s.opts.Gzip {
+		body, err = compress(body)
+	}
+	for attempt := 0; err == nil; attempt++ {
+		var (
+			retry bool
+			wait  time.Duration
+		)
+		retry, wait, err = s.post(body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= s.opts.MaxRetries {
+			break
+		}
+		if wait <= 0 {
+			wait = s.backoff(attempt)
+		}
+		select {
+		case <-time.After(wait):
+		case <-s.done:
+			attempt = s.opts.MaxRetries
+		}
+		err = nil
+	}
+	s.dropped.Add(uint64(len(batch)))
+	if s.opts.OnError != nil {
+		s.opts.OnError(err, len(batch))
+	}
+	return err
+}
+
+// post makes one request and reports whether a failure is worth retrying,
+// together with the delay asked for by Retry-After.
+func (s *Shipper) post(body []byte) (retry bool, wait time.Duration, err error) {
+	req, err := http.NewRequest(http.MethodPost, s.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, 0, err
+	}
+	for key, values := range s.opts.Header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", s.opts.Encoder.ContentType())
+	if s.opts.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return true, 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 300 {
+		return false, 0, nil
+	}
+	err = fmt.Errorf("httpship: %s returned %s", s.opts.URL, resp.Status)
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode >= 500
+	if seconds, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil && seconds > 0 {
+		wait = min(time.Duration(seconds)*time.Second, s.opts.MaxBackoff)
+	}
+	return retry, wait, err
+}
+
+// backoff doubles the delay per attempt up to MaxBackoff and picks a random
+// point in its upper half.
+func (s *Shipper) backoff(attempt int) time.Duration {
+	d := s.opts.MaxBackoff
+	if attempt < 32 {
+		d = min(s.opts.MinBackoff<<attempt, s.opts.MaxBackoff)
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+func compress(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
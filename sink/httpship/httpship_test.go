@@ -0,0 +1,296 @@
+package httpship
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	psladapter "pkt.systems/logport/adapters/psl"
+)
+
+type request struct {
+	header http.Header
+	body   []byte
+}
+
+// collector is an httptest handler recording every request it accepts.
+type collector struct {
+	mu       sync.Mutex
+	requests []request
+	got      chan struct{}
+	status   func(n int) int
+}
+
+func newCollector(t *testing.T) (*collector, *httptest.Server) {
+	c := &collector{got: make(chan struct{}, 64)}
+	srv := httptest.NewServer(c)
+	t.Cleanup(srv.Close)
+	return c, srv
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body, _ = io.ReadAll(zr)
+	}
+	c.mu.Lock()
+	c.requests = append(c.requests, request{header: r.Header.Clone(), body: body})
+	n := len(c.requests)
+	c.mu.Unlock()
+	if c.status != nil {
+		w.WriteHeader(c.status(n))
+	}
+	c.got <- struct{}{}
+}
+
+func (c *collector) wait(t *testing.T) request {
+	t.Helper()
+	select {
+	case <-c.got:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for a request")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.requests[len(c.requests)-1]
+}
+
+func TestJSONArrayBatchesBySizeUnderAnAdapter(t *testing.T) {
+	c, srv := newCollector(t)
+	shipper, err := New(Options{URL: srv.URL, BatchSize: 3, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer shipper.Close()
+	logger := psladapter.NewWithOptions(shipper, psladapter.Options{Mode: psladapter.ModeStructured, DisableTimestamp: true, NoColor: true})
+
+	logger.Info("one")
+	logger.Info("two", "n", 2)
+	select {
+	case <-c.got:
+		t.Fatalf("batch sent before it was full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	logger.Warn("three")
+
+	req := c.wait(t)
+	if req.header.Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected content type %q", req.header.Get("Content-Type"))
+	}
+	var entries []map[string]any
+	if err := json.Unmarshal(req.body, &entries); err != nil {
+		t.Fatalf("decode %s: %v", req.body, err)
+	}
+	if len(entries) != 3 || entries[0]["msg"] != "one" || entries[1]["n"] != float64(2) || entries[2]["lvl"] != "warn" {
+		t.Fatalf("unexpected batch %v", entries)
+	}
+}
+
+func TestFlushIntervalAndPlainLines(t *testing.T) {
+	c, srv := newCollector(t)
+	shipper, err := New(Options{URL: srv.URL, FlushInterval: 20 * time.Millisecond, Header: http.Header{"Authorization": {"Bearer token"}}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer shipper.Close()
+
+	if _, err := shipper.Write([]byte("plain text\n{\"msg\":\"json\"}\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	req := c.wait(t)
+	if req.header.Get("Authorization") != "Bearer token" {
+		t.Fatalf("expected custom header, got %v", req.header)
+	}
+	if string(req.body) != `[{"msg":"plain text"},{"msg":"json"}]` {
+		t.Fatalf("unexpected body %s", req.body)
+	}
+}
+
+func TestLokiStreamsWithGzip(t *testing.T) {
+	c, srv := newCollector(t)
+	shipper, err := New(Options{
+		URL:           srv.URL,
+		Encoder:       Loki(LokiOptions{Labels: []string{"app", "lvl"}, StaticLabels: map[string]string{"job": "edge"}}),
+		Gzip:          true,
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	for _, line := range []string{
+		`{"lvl":"info","app":"api","msg":"a"}`,
+		`{"lvl":"error","app":"api","msg":"b"}`,
+		`{"lvl":"info","app":"api","msg":"c"}`,
+	} {
+		_, _ = shipper.Write([]byte(line))
+	}
+	if err := shipper.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	req := c.wait(t)
+	if req.header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected gzip encoding")
+	}
+	var push struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(req.body, &push); err != nil {
+		t.Fatalf("decode %s: %v", req.body, err)
+	}
+	if len(push.Streams) != 2 {
+		t.Fatalf("expected two streams, got %s", req.body)
+	}
+	info, errs := push.Streams[0], push.Streams[1]
+	if info.Stream["lvl"] != "info" || info.Stream["app"] != "api" || info.Stream["job"] != "edge" || len(info.Values) != 2 {
+		t.Fatalf("unexpected info stream %+v", info)
+	}
+	if errs.Stream["lvl"] != "error" || len(errs.Values) != 1 || !strings.Contains(errs.Values[0][1], `"msg":"b"`) {
+		t.Fatalf("unexpected error stream %+v", errs)
+	}
+}
+
+func TestLokiNeverSendsAnEmptyLabelSet(t *testing.T) {
+	for _, opts := range []LokiOptions{{}, {Labels: []string{"app"}}} {
+		body, err := Loki(opts).Encode([]Entry{{Time: time.Now(), Line: []byte(`{"msg":"unlabelled"}`)}})
+		if err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+		var push struct {
+			Streams []struct {
+				Stream map[string]string `json:"stream"`
+			} `json:"streams"`
+		}
+		if err := json.Unmarshal(body, &push); err != nil {
+			t.Fatalf("decode %s: %v", body, err)
+		}
+		if len(push.Streams) != 1 || push.Streams[0].Stream["job"] != DefaultLokiJob || len(push.Streams[0].Stream) != 1 {
+			t.Fatalf("expected the default job label, got %s", body)
+		}
+	}
+}
+
+func TestElasticsearchBulk(t *testing.T) {
+	c, srv := newCollector(t)
+	shipper, err := New(Options{URL: srv.URL + "/_bulk", Encoder: Elasticsearch(ElasticsearchOptions{Index: "logs-edge"}), BatchSize: 2})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer shipper.Close()
+	_, _ = shipper.Write([]byte(`{"msg":"a"}`))
+	_, _ = shipper.Write([]byte(`{"msg":"b"}`))
+
+	req := c.wait(t)
+	if req.header.Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("unexpected content type %q", req.header.Get("Content-Type"))
+	}
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(req.body))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	want := []string{`{"create":{"_index":"logs-edge"}}`, `{"msg":"a"}`, `{"create":{"_index":"logs-edge"}}`, `{"msg":"b"}`}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") || !bytes.HasSuffix(req.body, []byte("\n")) {
+		t.Fatalf("unexpected bulk body %q", req.body)
+	}
+}
+
+func TestRetriesTransientFailures(t *testing.T) {
+	c, srv := newCollector(t)
+	c.status = func(n int) int {
+		if n < 3 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	}
+	shipper, err := New(Options{URL: srv.URL, MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer shipper.Close()
+	_, _ = shipper.Write([]byte(`{"msg":"eventually"}`))
+	if err := shipper.Flush(t.Context()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	c.mu.Lock()
+	attempts := len(c.requests)
+	c.mu.Unlock()
+	if attempts != 3 || shipper.Dropped() != 0 {
+		t.Fatalf("expected delivery on the third attempt, got %d attempts and %d dropped", attempts, shipper.Dropped())
+	}
+}
+
+func TestClientErrorsAreNotRetried(t *testing.T) {
+	c, srv := newCollector(t)
+	c.status = func(int) int { return http.StatusBadRequest }
+	var reported atomic.Int64
+	shipper, err := New(Options{
+		URL:           srv.URL,
+		MinBackoff:    time.Millisecond,
+		FlushInterval: time.Hour,
+		OnError:       func(_ error, dropped int) { reported.Add(int64(dropped)) },
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	_, _ = shipper.Write([]byte("a\nb\n"))
+	if err := shipper.Flush(t.Context()); err == nil {
+		t.Fatalf("expected the 400 to surface from Flush")
+	}
+	if err := shipper.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	c.mu.Lock()
+	attempts := len(c.requests)
+	c.mu.Unlock()
+	if attempts != 1 || shipper.Dropped() != 2 || reported.Load() != 2 {
+		t.Fatalf("expected one attempt and two dropped entries, got %d attempts, %d dropped, %d reported", attempts, shipper.Dropped(), reported.Load())
+	}
+	if _, err := shipper.Write([]byte("late")); err != ErrClosed {
+		t.Fatalf("expected ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestBufferIsBounded(t *testing.T) {
+	c, srv := newCollector(t)
+	shipper, err := New(Options{URL: srv.URL, MaxBufferedBytes: 100, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		_, _ = shipper.Write([]byte(`{"msg":"0123456789"}`))
+	}
+	if err := shipper.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal(c.wait(t).body, &entries); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(entries) != 5 || shipper.Dropped() != 15 {
+		t.Fatalf("expected 5 shipped and 15 dropped, got %d and %d", len(entries), shipper.Dropped())
+	}
+}
+
+func TestOptionValidation(t *testing.T) {
+	if _, err := New(Options{}); err == nil {
+		t.Fatalf("expected an error without URL")
+	}
+}
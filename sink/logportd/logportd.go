@@ -0,0 +1,150 @@
+// Package logportd is the client sink for the logportd collector daemon
+// (cmd/logportd). A Client is an io.Writer, so it plugs into any adapter:
+//
+//	client, err := logportd.New(logportd.Options{Name: "build-step"})
+//	if err != nil {
+//		return err
+//	}
+//	defer client.Close()
+//	logger := psl.NewWithOptions(client, psl.Options{Mode: psl.ModeStructured})
+//
+// Every line written is sent as one frame; the daemon adds the process ID and
+// name and routes the entry. When the daemon falls behind, writes block for
+// up to WriteTimeout before failing.
+package logportd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"pkt.systems/logport/collector"
+	"pkt.systems/logport/internal/netsink"
+)
+
+var (
+	// ErrBackoff is returned by Write while the client waits before
+	// reconnecting; the entry is dropped.
+	ErrBackoff = netsink.ErrBackoff
+	// ErrClosed is returned by Write once the Client is closed.
+	ErrClosed = errors.New("logportd: client closed")
+)
+
+// Options configures New.
+type Options struct {
+	// Socket is the daemon's unix socket. Defaults to
+	// collector.DefaultSocketPath().
+	Socket string
+	// Name identifies the process to the daemon and its routes. Defaults to
+	// the executable's base name.
+	Name string
+
+	// DialTimeout defaults to 5s.
+	DialTimeout time.Duration
+	// WriteTimeout bounds how long a Write may block while the daemon
+	// applies backpressure. Defaults to 5s.
+	WriteTimeout time.Duration
+	// MinBackoff and MaxBackoff bound the delay between reconnect attempts
+	// after a failed dial or write. They default to 100ms and 30s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Client sends entries to logportd.
+type Client struct {
+	opts  Options
+	hello []byte
+
+	mu     sync.Mutex
+	conn   netsink.Conn
+	closed bool
+}
+
+// New returns a Client. The connection is established lazily on the first
+// Write and re-established with exponential backoff after failures.
+func New(opts Options) (*Client, error) {
+	if opts.Socket == "" {
+		opts.Socket = collector.DefaultSocketPath()
+	}
+	if opts.Name == "" {
+		opts.Name = filepath.Base(os.Args[0])
+	}
+	netsink.ApplyDefaults(&opts.DialTimeout, &opts.WriteTimeout, &opts.MinBackoff, &opts.MaxBackoff)
+	hello, err := json.Marshal(collector.Hello{PID: os.Getpid(), Name: opts.Name})
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{opts: opts, hello: collector.AppendFrame(nil, hello)}
+	c.conn = netsink.Conn{
+		Dial:       c.dial,
+		MinBackoff: opts.MinBackoff,
+		MaxBackoff: opts.MaxBackoff,
+		// A timeout means the daemon is alive but behind; a partial frame
+		// may have been sent, so the write is not repeated.
+		Retryable: func(err error) bool {
+			var netErr net.Error
+			return !errors.As(err, &netErr) || !netErr.Timeout()
+		},
+	}
+	return c, nil
+}
+
+// Write sends every non-empty line of p as one entry.
+func (c *Client) Write(p []byte) (int, error) {
+	var frames []byte
+	for _, line := range bytes.Split(p, []byte{'\n'}) {
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if len(line) > collector.MaxFrameSize {
+				return 0, collector.ErrFrameTooLarge
+			}
+			frames = collector.AppendFrame(frames, line)
+		}
+	}
+	if len(frames) == 0 {
+		return len(p), nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return 0, ErrClosed
+	}
+	err := c.conn.Send(func(conn net.Conn) error {
+		_ = conn.SetWriteDeadline(time.Now().Add(c.opts.WriteTimeout))
+		_, err := conn.Write(frames)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// dial connects to the daemon and introduces the process.
+func (c *Client) dial() (net.Conn, error) {
+	conn, err := net.DialTimeout("unix", c.opts.Socket, c.opts.DialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	_ = conn.SetWriteDeadline(time.Now().Add(c.opts.WriteTimeout))
+	if _, err := conn.Write(c.hello); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Close closes the connection; later writes fail with ErrClosed.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	c.closed = true
+	return c.conn.Close()
+}
@@ -0,0 +1,92 @@
+package logportd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"pkt.systems/logport/collector"
+)
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestReconnectsAfterDaemonRestart(t *testing.T) {
+	dir, err := os.MkdirTemp("", "lpd")
+	if err != nil {
+		t.Fatalf("tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "s")
+
+	out := &lockedBuffer{}
+	start := func() *collector.Server {
+		server, err := collector.Listen(socket, []collector.Route{{Output: out}})
+		if err != nil {
+			t.Fatalf("Listen failed: %v", err)
+		}
+		go server.Serve()
+		return server
+	}
+	server := start()
+
+	client, err := New(Options{Socket: socket, Name: "worker", MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if _, err := client.Write([]byte(`{"msg":"before"}`)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	waitFor(t, func() bool { return strings.Contains(out.String(), `"msg":"before"`) })
+	server.Close()
+
+	server = start()
+	defer server.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), `"msg":"after"`) {
+		if time.Now().After(deadline) {
+			t.Fatalf("no entry after the daemon restarted, got %s", out.String())
+		}
+		_, _ = client.Write([]byte(`{"msg":"after"}`))
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !strings.Contains(out.String(), `"source_name":"worker"`) {
+		t.Fatalf("expected the source name, got %s", out.String())
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := client.Write([]byte("late")); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,605 @@
+// Package spool puts a disk-backed write-ahead log in front of a network
+// sink, so entries survive outages of the remote endpoint and restarts of
+// the process. A Spool is an io.Writer: every Write is appended to a segment
+// file in a local directory and replayed to the downstream writer in order
+// from a background goroutine.
+//
+//	shipper, err := httpship.New(httpship.Options{URL: url})
+//	if err != nil {
+//		return err
+//	}
+//	sp, err := spool.New(spool.Options{Dir: "/var/spool/myapp", Downstream: shipper})
+//	if err != nil {
+//		return err
+//	}
+//	defer sp.Close()
+//	logger := psl.NewWithOptions(sp, psl.Options{Mode: psl.ModeStructured})
+//
+// An entry counts as delivered once the downstream Write returns nil and, if
+// the downstream has a Flush(context.Context) error method (as
+// httpship.Shipper does), once Flush returns nil after the batch. Failed
+// batches are replayed with exponential backoff, so delivery is at least
+// once. When the spool grows past MaxBytes the oldest segments are evicted,
+// delivered or not.
+package spool
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyncPolicy selects when segment files are fsynced.
+type SyncPolicy uint8
+
+const (
+	// SyncPeriodic fsyncs the active segment every SyncInterval; a crash
+	// loses at most that much.
+	SyncPeriodic SyncPolicy = iota
+	// SyncAlways fsyncs after every Write.
+	SyncAlways
+	// SyncNever leaves flushing to the operating system.
+	SyncNever
+)
+
+const (
+	defaultSegmentBytes = 8 << 20
+	defaultMaxBytes     = 256 << 20
+	defaultSyncInterval = time.Second
+	defaultBatchSize    = 256
+	defaultMinBackoff   = 100 * time.Millisecond
+	defaultMaxBackoff   = 30 * time.Second
+
+	segmentSuffix = ".seg"
+	cursorFile    = "cursor"
+	headerSize    = 8
+)
+
+// ErrClosed is returned by Write once the Spool is closed.
+var ErrClosed = errors.New("spool: closed")
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Options configures New.
+type Options struct {
+	// Dir holds the segment files and the replay cursor. It is created if
+	// missing. Required.
+	Dir string
+	// Downstream receives the replayed entries, one Write per entry.
+	// Required.
+	Downstream io.Writer
+
+	// SegmentBytes is the size at which a new segment file is started.
+	// Defaults to 8 MiB.
+	SegmentBytes int64
+	// MaxBytes bounds the disk used by segments; the oldest are evicted
+	// beyond it. Defaults to 256 MiB and must be at least twice
+	// SegmentBytes.
+	MaxBytes int64
+
+	// Sync selects the fsync policy. Defaults to SyncPeriodic.
+	Sync SyncPolicy
+	// SyncInterval is the fsync period for SyncPeriodic. Defaults to 1s.
+	SyncInterval time.Duration
+
+	// BatchSize is the number of entries replayed before the downstream is
+	// flushed and the cursor advanced. Defaults to 256.
+	BatchSize int
+	// MinBackoff and MaxBackoff bound the delay before a failed batch is
+	// replayed again. They default to 100ms and 30s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Stats describes what a Spool holds on disk.
+type Stats struct {
+	// Segments is the number of segment files.
+	Segments int
+	// Bytes is the size of all segment files.
+	Bytes int64
+	// Pending is the number of bytes not yet delivered downstream.
+	Pending int64
+	// Evicted counts segments removed to stay under MaxBytes since New.
+	Evicted uint64
+}
+
+type segment struct {
+	seq  uint64
+	size int64
+}
+
+// Spool is the disk-backed writer returned by New.
+type Spool struct {
+	opts Options
+
+	mu       sync.Mutex
+	segments []segment
+	active   *os.File
+	dirty    bool
+	readSeq  uint64
+	readOff  int64
+	cursor   *os.File
+	evicted  uint64
+	closed   bool
+	idle     chan struct{}
+	replayFd *os.File
+	replayOf uint64
+
+	wake    chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+// New opens or creates the spool in opts.Dir and starts replaying whatever
+// an earlier process left behind.
+func New(opts Options) (*Spool, error) {
+	if opts.Dir == "" {
+		return nil, errors.New("spool: Dir is required")
+	}
+	if opts.Downstream == nil {
+		return nil, errors.New("spool: Downstream is required")
+	}
+	if opts.SegmentBytes <= 0 {
+		opts.SegmentBytes = defaultSegmentBytes
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = max(defaultMaxBytes, 2*opts.SegmentBytes)
+	}
+	if opts.MaxBytes < 2*opts.SegmentBytes {
+		return nil, fmt.Errorf("spool: MaxBytes %d is less than twice SegmentBytes %d", opts.MaxBytes, opts.SegmentBytes)
+	}
+	if opts.SyncInterval <= 0 {
+		opts.SyncInterval = defaultSyncInterval
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = defaultMinBackoff
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = max(defaultMaxBackoff, opts.MinBackoff)
+	}
+	if err := os.MkdirAll(opts.Dir, 0o750); err != nil {
+		return nil, err
+	}
+	s := &Spool{opts: opts, wake: make(chan struct{}, 1), stopped: make(chan struct{})}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	go s.replay()
+	return s, nil
+}
+
+// load discovers existing segments, restores the cursor and starts a fresh
+// active segment; segments written by an earlier process are only read.
+func (s *Spool) load() error {
+	entries, err := os.ReadDir(s.opts.Dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		s.segments = append(s.segments, segment{seq: seq, size: info.Size()})
+	}
+	slices.SortFunc(s.segments, func(a, b segment) int { return cmp.Compare(a.seq, b.seq) })
+
+	s.cursor, err = os.OpenFile(filepath.Join(s.opts.Dir, cursorFile), os.O_RDWR|os.O_CREATE, 0o640)
+	if err != nil {
+		return err
+	}
+	var buf [16]byte
+	if n, _ := s.cursor.ReadAt(buf[:], 0); n == len(buf) {
+		s.readSeq = binary.LittleEndian.Uint64(buf[:8])
+		s.readOff = int64(binary.LittleEndian.Uint64(buf[8:]))
+	}
+	next := uint64(1)
+	if len(s.segments) > 0 {
+		next = s.segments[len(s.segments)-1].seq + 1
+	}
+	if err := s.openSegment(next); err != nil {
+		s.cursor.Close()
+		return err
+	}
+	s.clampCursor()
+	return nil
+}
+
+func (s *Spool) segmentPath(seq uint64) string {
+	return filepath.Join(s.opts.Dir, fmt.Sprintf("%020d%s", seq, segmentSuffix))
+}
+
+// openSegment makes seq the active segment. Callers hold mu or own s.
+func (s *Spool) openSegment(seq uint64) error {
+	f, err := os.OpenFile(s.segmentPath(seq), os.O_WRONLY|os.O_CREATE|os.O_EXCL|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	if s.opts.Sync != SyncNever {
+		syncDir(s.opts.Dir)
+	}
+	s.active = f
+	s.segments = append(s.segments, segment{seq: seq})
+	return nil
+}
+
+// clampCursor moves the cursor to the oldest segment when the one it points
+// at no longer exists.
+func (s *Spool) clampCursor() {
+	if s.segmentIndex(s.readSeq) >= 0 {
+		return
+	}
+	s.readSeq, s.readOff = s.segments[0].seq, 0
+}
+
+func (s *Spool) segmentIndex(seq uint64) int {
+	for i, seg := range s.segments {
+		if seg.seq == seq {
+			return i
+		}
+	}
+	return -1
+}
+
+// Write appends p as one entry. It returns an error only when the entry
+// cannot be written to disk.
+func (s *Spool) Write(p []byte) (int, error) {
+	record := make([]byte, headerSize+len(p))
+	binary.LittleEndian.PutUint32(record[:4], uint32(len(p)))
+	binary.LittleEndian.PutUint32(record[4:8], crc32.Checksum(p, crcTable))
+	copy(record[headerSize:], p)
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return 0, ErrClosed
+	}
+	active := &s.segments[len(s.segments)-1]
+	if active.size > 0 && active.size+int64(len(record)) > s.opts.SegmentBytes {
+		if err := s.rotate(); err != nil {
+			s.mu.Unlock()
+			return 0, err
+		}
+		active = &s.segments[len(s.segments)-1]
+	}
+	if _, err := s.active.Write(record); err != nil {
+		s.mu.Unlock()
+		return 0, err
+	}
+	active.size += int64(len(record))
+	s.dirty = true
+	if s.opts.Sync == SyncAlways {
+		if err := s.active.Sync(); err != nil {
+			s.mu.Unlock()
+			return 0, err
+		}
+		s.dirty = false
+	}
+	s.evict()
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// rotate closes the active segment and starts the next one.
+func (s *Spool) rotate() error {
+	if s.opts.Sync != SyncNever {
+		if err := s.active.Sync(); err != nil {
+			return err
+		}
+	}
+	if err := s.active.Close(); err != nil {
+		return err
+	}
+	s.dirty = false
+	return s.openSegment(s.segments[len(s.segments)-1].seq + 1)
+}
+
+// evict removes the oldest inactive segments while the spool is over
+// MaxBytes.
+func (s *Spool) evict() {
+	var total int64
+	for _, seg := range s.segments {
+		total += seg.size
+	}
+	for total > s.opts.MaxBytes && len(s.segments) > 1 {
+		oldest := s.segments[0]
+		if err := os.Remove(s.segmentPath(oldest.seq)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return
+		}
+		s.segments = s.segments[1:]
+		total -= oldest.size
+		s.evicted++
+	}
+	s.clampCursor()
+}
+
+// Stats reports the current disk usage.
+func (s *Spool) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := Stats{Segments: len(s.segments), Evicted: s.evicted}
+	for _, seg := range s.segments {
+		stats.Bytes += seg.size
+		if seg.seq > s.readSeq {
+			stats.Pending += seg.size
+		} else if seg.seq == s.readSeq {
+			stats.Pending += seg.size - s.readOff
+		}
+	}
+	return stats
+}
+
+// Drain waits until every entry written so far has been delivered or ctx is
+// done.
+func (s *Spool) Drain(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		idle := s.idle
+		if idle == nil {
+			idle = make(chan struct{})
+			s.idle = idle
+		}
+		s.mu.Unlock()
+		if s.Stats().Pending == 0 {
+			return nil
+		}
+		select {
+		case <-idle:
+		case <-s.stopped:
+			return ErrClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close stops replaying, syncs the active segment and persists the cursor.
+// Undelivered entries stay on disk for the next New with the same Dir.
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+	s.closed = true
+	s.mu.Unlock()
+	s.cancel()
+	<-s.stopped
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.active.Sync()
+	if closeErr := s.active.Close(); err == nil {
+		err = closeErr
+	}
+	if s.replayFd != nil {
+		s.replayFd.Close()
+	}
+	if syncErr := s.cursor.Sync(); err == nil {
+		err = syncErr
+	}
+	if closeErr := s.cursor.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// replay delivers entries downstream until Close.
+func (s *Spool) replay() {
+	defer close(s.stopped)
+	var (
+		ticker  <-chan time.Time
+		backoff time.Duration
+	)
+	if s.opts.Sync == SyncPeriodic {
+		t := time.NewTicker(s.opts.SyncInterval)
+		defer t.Stop()
+		ticker = t.C
+	}
+	for {
+		batch, seq, end, err := s.next()
+		if err == nil && len(batch) > 0 {
+			err = s.deliver(batch)
+			if err == nil {
+				s.commit(seq, end)
+				backoff = 0
+				continue
+			}
+		}
+		var retry <-chan time.Time
+		if err != nil {
+			backoff = min(max(backoff*2, s.opts.MinBackoff), s.opts.MaxBackoff)
+			retry = time.After(backoff)
+		} else {
+			s.signalIdle()
+		}
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker:
+			s.syncActive()
+		case <-s.wake:
+			if retry != nil {
+				// Keep backing off; new entries do not mean the
+				// downstream recovered.
+				select {
+				case <-retry:
+				case <-s.ctx.Done():
+					return
+				}
+			}
+		case <-retry:
+		}
+	}
+}
+
+func (s *Spool) signalIdle() {
+	s.mu.Lock()
+	if s.idle != nil {
+		close(s.idle)
+		s.idle = nil
+	}
+	s.mu.Unlock()
+}
+
+func (s *Spool) syncActive() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dirty && !s.closed {
+		_ = s.active.Sync()
+		s.dirty = false
+	}
+}
+
+// next reads up to BatchSize entries at the cursor. It removes fully
+// delivered segments on the way and returns where the batch ends.
+func (s *Spool) next() (batch [][]byte, seq uint64, end int64, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		s.clampCursor()
+		idx := s.segmentIndex(s.readSeq)
+		seg := s.segments[idx]
+		if s.readOff >= seg.size {
+			if idx == len(s.segments)-1 {
+				return nil, 0, 0, nil
+			}
+			// Fully delivered, or a torn tail left by a crash.
+			_ = os.Remove(s.segmentPath(seg.seq))
+			s.segments = slices.Delete(s.segments, idx, idx+1)
+			s.readSeq, s.readOff = s.segments[idx].seq, 0
+			s.saveCursor()
+			continue
+		}
+		f, err := s.reader(seg.seq)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		off := s.readOff
+		for len(batch) < s.opts.BatchSize && off < seg.size {
+			payload, n, ok := readRecord(f, off, seg.size)
+			if !ok {
+				// Corrupt or partial record: skip the rest of the segment.
+				off = seg.size
+				break
+			}
+			batch = append(batch, payload)
+			off += n
+		}
+		if len(batch) == 0 {
+			s.readOff = off
+			s.saveCursor()
+			continue
+		}
+		return batch, seg.seq, off, nil
+	}
+}
+
+// reader returns a read handle for segment seq, reusing the last one.
+func (s *Spool) reader(seq uint64) (*os.File, error) {
+	if s.replayFd != nil && s.replayOf == seq {
+		return s.replayFd, nil
+	}
+	if s.replayFd != nil {
+		s.replayFd.Close()
+		s.replayFd = nil
+	}
+	f, err := os.Open(s.segmentPath(seq))
+	if err != nil {
+		return nil, err
+	}
+	s.replayFd, s.replayOf = f, seq
+	return f, nil
+}
+
+func readRecord(f *os.File, off, size int64) (payload []byte, n int64, ok bool) {
+	if off+headerSize > size {
+		return nil, 0, false
+	}
+	var header [headerSize]byte
+	if _, err := f.ReadAt(header[:], off); err != nil {
+		return nil, 0, false
+	}
+	length := int64(binary.LittleEndian.Uint32(header[:4]))
+	if off+headerSize+length > size {
+		return nil, 0, false
+	}
+	payload = make([]byte, length)
+	if _, err := f.ReadAt(payload, off+headerSize); err != nil {
+		return nil, 0, false
+	}
+	if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(header[4:]) {
+		return nil, 0, false
+	}
+	return payload, headerSize + length, true
+}
+
+// deliver writes batch downstream and flushes it when the downstream can.
+func (s *Spool) deliver(batch [][]byte) error {
+	for _, payload := range batch {
+		if _, err := s.opts.Downstream.Write(payload); err != nil {
+			return err
+		}
+	}
+	if flusher, ok := s.opts.Downstream.(interface{ Flush(context.Context) error }); ok {
+		return flusher.Flush(s.ctx)
+	}
+	return nil
+}
+
+// commit advances the cursor past a delivered batch unless its segment was
+// evicted in the meantime.
+func (s *Spool) commit(seq uint64, end int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.readSeq != seq {
+		return
+	}
+	s.readOff = end
+	s.saveCursor()
+}
+
+// saveCursor records the cursor; it is synced on Close, so a crash replays
+// at most what was delivered since.
+func (s *Spool) saveCursor() {
+	var buf [16]byte
+	binary.LittleEndian.PutUint64(buf[:8], s.readSeq)
+	binary.LittleEndian.PutUint64(buf[8:], uint64(s.readOff))
+	_, _ = s.cursor.WriteAt(buf[:], 0)
+}
+
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+}
@@ -0,0 +1,228 @@
+package spool
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// downstream records delivered entries and fails while down is set.
+type downstream struct {
+	mu      sync.Mutex
+	down    bool
+	entries []string
+}
+
+func (d *downstream) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.down {
+		return 0, errors.New("endpoint unavailable")
+	}
+	d.entries = append(d.entries, string(p))
+	return len(p), nil
+}
+
+func (d *downstream) setDown(down bool) {
+	d.mu.Lock()
+	d.down = down
+	d.mu.Unlock()
+}
+
+func (d *downstream) got() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return slices.Clone(d.entries)
+}
+
+// flushingDownstream buffers writes and fails its first flushes.
+type flushingDownstream struct {
+	downstream
+	failures int
+	pending  []string
+}
+
+func (d *flushingDownstream) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	d.pending = append(d.pending, string(p))
+	d.mu.Unlock()
+	return len(p), nil
+}
+
+func (d *flushingDownstream) Flush(context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	pending := d.pending
+	d.pending = nil
+	if d.failures > 0 {
+		d.failures--
+		return errors.New("flush failed")
+	}
+	d.entries = append(d.entries, pending...)
+	return nil
+}
+
+func drain(t *testing.T, s *Spool) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Drain(ctx); err != nil {
+		t.Fatalf("Drain failed: %v (stats %+v)", err, s.Stats())
+	}
+}
+
+func fastOptions(dir string, d interface{ Write([]byte) (int, error) }) Options {
+	return Options{Dir: dir, Downstream: d, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+}
+
+func TestReplaysAfterDownstreamRecovers(t *testing.T) {
+	d := &downstream{down: true}
+	s, err := New(fastOptions(t.TempDir(), d))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := fmt.Fprintf(s, `{"msg":"entry %d"}`, i); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	if len(d.got()) != 0 || s.Stats().Pending == 0 {
+		t.Fatalf("expected entries to stay spooled, got %v and %+v", d.got(), s.Stats())
+	}
+
+	d.setDown(false)
+	drain(t, s)
+	want := []string{`{"msg":"entry 0"}`, `{"msg":"entry 1"}`, `{"msg":"entry 2"}`}
+	if !slices.Equal(d.got(), want) {
+		t.Fatalf("unexpected delivery %q", d.got())
+	}
+}
+
+func TestSurvivesRestartWithoutRedelivery(t *testing.T) {
+	dir := t.TempDir()
+	first := &downstream{}
+	s, err := New(fastOptions(dir, first))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	_, _ = s.Write([]byte("delivered"))
+	drain(t, s)
+	first.setDown(true)
+	_, _ = s.Write([]byte("spooled"))
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	second := &downstream{}
+	s, err = New(fastOptions(dir, second))
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer s.Close()
+	_, _ = s.Write([]byte("after restart"))
+	drain(t, s)
+	if got := second.got(); !slices.Equal(got, []string{"spooled", "after restart"}) {
+		t.Fatalf("unexpected delivery after restart %q", got)
+	}
+	if stats := s.Stats(); stats.Segments != 1 || stats.Pending != 0 {
+		t.Fatalf("expected delivered segments to be removed, got %+v", stats)
+	}
+}
+
+func TestEvictsOldestSegmentsBeyondMaxBytes(t *testing.T) {
+	d := &downstream{down: true}
+	opts := fastOptions(t.TempDir(), d)
+	opts.SegmentBytes, opts.MaxBytes, opts.Sync = 64, 128, SyncNever
+	s, err := New(opts)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 40; i++ {
+		fmt.Fprintf(s, "entry-%02d", i)
+	}
+	stats := s.Stats()
+	if stats.Evicted == 0 || stats.Bytes > opts.MaxBytes {
+		t.Fatalf("expected eviction to bound disk usage, got %+v", stats)
+	}
+
+	d.setDown(false)
+	drain(t, s)
+	got := d.got()
+	if len(got) == 0 || len(got) >= 40 || got[len(got)-1] != "entry-39" || slices.Contains(got, "entry-00") {
+		t.Fatalf("expected only the newest entries, got %q", got)
+	}
+}
+
+func TestFlushFailureReplaysBatch(t *testing.T) {
+	d := &flushingDownstream{failures: 2}
+	s, err := New(fastOptions(t.TempDir(), d))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer s.Close()
+	_, _ = s.Write([]byte("a"))
+	_, _ = s.Write([]byte("b"))
+	drain(t, s)
+	if got := d.got(); len(got) < 2 || got[len(got)-2] != "a" || got[len(got)-1] != "b" {
+		t.Fatalf("expected the batch to be replayed after failed flushes, got %q", got)
+	}
+}
+
+func TestTornTailIsSkipped(t *testing.T) {
+	dir := t.TempDir()
+	record := func(p string) []byte {
+		b := binary.LittleEndian.AppendUint32(nil, uint32(len(p)))
+		b = binary.LittleEndian.AppendUint32(b, crc32.Checksum([]byte(p), crcTable))
+		return append(b, p...)
+	}
+	data := append(record("intact"), record("torn")[:6]...)
+	if err := os.WriteFile(dir+"/00000000000000000001.seg", data, 0o640); err != nil {
+		t.Fatalf("write segment: %v", err)
+	}
+
+	d := &downstream{}
+	s, err := New(fastOptions(dir, d))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer s.Close()
+	_, _ = s.Write([]byte("fresh"))
+	drain(t, s)
+	if got := d.got(); !slices.Equal(got, []string{"intact", "fresh"}) {
+		t.Fatalf("unexpected delivery %q", got)
+	}
+}
+
+func TestOptionValidationAndClose(t *testing.T) {
+	if _, err := New(Options{Downstream: &downstream{}}); err == nil {
+		t.Fatalf("expected an error without Dir")
+	}
+	if _, err := New(Options{Dir: t.TempDir()}); err == nil {
+		t.Fatalf("expected an error without Downstream")
+	}
+	if _, err := New(Options{Dir: t.TempDir(), Downstream: &downstream{}, SegmentBytes: 100, MaxBytes: 150}); err == nil {
+		t.Fatalf("expected an error for MaxBytes below twice SegmentBytes")
+	}
+	s, err := New(Options{Dir: t.TempDir(), Downstream: &downstream{}, Sync: SyncAlways})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := s.Write([]byte("late")); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
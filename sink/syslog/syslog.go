@@ -0,0 +1,407 @@
+// Package syslog ships log entries to a syslog receiver over UDP, TCP, TLS or
+// unix sockets. Entries are formatted as RFC 5424 (the default) or RFC 3164;
+// with RFC 5424 the structured keyvals travel as STRUCTURED-DATA:
+//
+//	sink, err := syslog.New(syslog.Options{
+//		Network:  "tcp",
+//		Address:  "logs.internal:601",
+//		Facility: syslog.Local0,
+//		AppName:  "billing",
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer sink.Close()
+//	logger := sink.Logger()
+//	logger.Info("invoice.sent", "invoice_id", id)
+//
+// Handler implements slog.Handler; Logger wraps it in the slog adapter so it
+// satisfies logport.ForLogging.
+//
+// Writes are synchronous: every entry is sent from the logging goroutine
+// while the Handler's lock is held, so an unreachable or slow receiver stalls
+// all loggers sharing the Handler for up to DialTimeout plus WriteTimeout per
+// entry.
+package syslog
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	logport "pkt.systems/logport"
+	"pkt.systems/logport/adapters/slogger"
+	"pkt.systems/logport/internal/netsink"
+	"pkt.systems/logport/internal/severity"
+)
+
+// Format selects the syslog message format.
+type Format uint8
+
+const (
+	// RFC5424 is the structured syslog protocol; keyvals are sent as
+	// STRUCTURED-DATA.
+	RFC5424 Format = iota
+	// RFC3164 is the legacy BSD format; keyvals are appended to the message as
+	// key=value pairs.
+	RFC3164
+)
+
+// Framing selects how messages are delimited on stream transports.
+type Framing uint8
+
+const (
+	// FramingDefault uses octet counting for tcp and tls and newline
+	// termination for unix stream sockets.
+	FramingDefault Framing = iota
+	// OctetCounting prefixes every message with its length (RFC 6587 3.4.1).
+	OctetCounting
+	// NonTransparent terminates every message with a newline (RFC 6587
+	// 3.4.2).
+	NonTransparent
+)
+
+// Facility is a syslog facility code.
+type Facility uint8
+
+// Syslog facilities. The kernel facility (0) is deliberately absent: the zero
+// value selects User.
+const (
+	User     Facility = 1
+	Mail     Facility = 2
+	Daemon   Facility = 3
+	Auth     Facility = 4
+	Syslog   Facility = 5
+	LPR      Facility = 6
+	News     Facility = 7
+	UUCP     Facility = 8
+	Cron     Facility = 9
+	AuthPriv Facility = 10
+	FTP      Facility = 11
+	Local0   Facility = 16
+	Local1   Facility = 17
+	Local2   Facility = 18
+	Local3   Facility = 19
+	Local4   Facility = 20
+	Local5   Facility = 21
+	Local6   Facility = 22
+	Local7   Facility = 23
+)
+
+// DefaultSDID is the STRUCTURED-DATA element ID carrying keyvals. 32473 is
+// the private enterprise number reserved for documentation (RFC 5612).
+const DefaultSDID = "logport@32473"
+
+// ErrBackoff is returned by Handle while the sink waits before reconnecting;
+// the entry is dropped.
+var ErrBackoff = netsink.ErrBackoff
+
+// Options configures New.
+type Options struct {
+	// Network is "udp" (default), "tcp", "tls", "unix" or "unixgram".
+	Network string
+	// Address is host:port for udp, tcp and tls, or a socket path for unix
+	// networks. Defaults to "localhost:514", or "/dev/log" for unix networks.
+	Address string
+	// TLSConfig is used with the "tls" network.
+	TLSConfig *tls.Config
+
+	// Format selects RFC5424 (default) or RFC3164.
+	Format Format
+	// Framing overrides the stream framing. Ignored for datagram networks.
+	Framing Framing
+	// Facility defaults to User.
+	Facility Facility
+	// AppName defaults to the executable's base name.
+	AppName string
+	// Hostname defaults to os.Hostname.
+	Hostname string
+	// SDID is the STRUCTURED-DATA element ID. Defaults to DefaultSDID.
+	SDID string
+
+	// DialTimeout and WriteTimeout default to 5s.
+	DialTimeout  time.Duration
+	WriteTimeout time.Duration
+	// MinBackoff and MaxBackoff bound the delay between reconnect attempts
+	// after a failed dial or write. They default to 100ms and 30s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Handler is a slog.Handler writing to a syslog receiver. Handlers derived
+// with WithAttrs and WithGroup share the connection.
+type Handler struct {
+	conn   *connection
+	attrs  []slog.Attr
+	prefix string
+}
+
+// New validates opts and returns a Handler. The connection is established
+// lazily on the first entry and re-established with exponential backoff
+// after failures.
+func New(opts Options) (*Handler, error) {
+	if opts.Network == "" {
+		opts.Network = "udp"
+	}
+	switch opts.Network {
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6", "tls", "unix", "unixgram":
+	default:
+		return nil, fmt.Errorf("syslog: unsupported network %q", opts.Network)
+	}
+	if opts.Address == "" {
+		if strings.HasPrefix(opts.Network, "unix") {
+			opts.Address = "/dev/log"
+		} else {
+			opts.Address = "localhost:514"
+		}
+	}
+	if opts.Facility == 0 {
+		opts.Facility = User
+	}
+	if opts.Facility > Local7 {
+		return nil, fmt.Errorf("syslog: invalid facility %d", opts.Facility)
+	}
+	if opts.AppName == "" {
+		opts.AppName = filepath.Base(os.Args[0])
+	}
+	if opts.Hostname == "" {
+		opts.Hostname, _ = os.Hostname()
+	}
+	if opts.SDID == "" {
+		opts.SDID = DefaultSDID
+	}
+	if opts.Framing == FramingDefault {
+		switch opts.Network {
+		case "unix":
+			opts.Framing = NonTransparent
+		default:
+			opts.Framing = OctetCounting
+		}
+	}
+	netsink.ApplyDefaults(&opts.DialTimeout, &opts.WriteTimeout, &opts.MinBackoff, &opts.MaxBackoff)
+	opts.AppName = headerField(opts.AppName, 48)
+	opts.Hostname = headerField(opts.Hostname, 255)
+	c := &connection{opts: opts, pid: os.Getpid()}
+	c.conn = netsink.Conn{
+		Dial: func() (net.Conn, error) {
+			return netsink.Dial(opts.Network, opts.Address, opts.TLSConfig, opts.DialTimeout)
+		},
+		MinBackoff: opts.MinBackoff,
+		MaxBackoff: opts.MaxBackoff,
+	}
+	return &Handler{conn: c}, nil
+}
+
+// Logger returns a logport.ForLogging writing through h.
+func (h *Handler) Logger() logport.ForLogging {
+	return slogger.NewWithHandler(h)
+}
+
+// Close closes the connection. Later entries reconnect.
+func (h *Handler) Close() error {
+	return h.conn.close()
+}
+
+// Enabled implements slog.Handler; level filtering is left to the logger.
+func (h *Handler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle implements slog.Handler.
+func (h *Handler) Handle(_ context.Context, record slog.Record) error {
+	fields := make([]field, 0, len(h.attrs)+record.NumAttrs())
+	for _, attr := range h.attrs {
+		fields = appendFields(fields, "", attr)
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		fields = appendFields(fields, h.prefix, attr)
+		return true
+	})
+	ts := record.Time
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	return h.conn.send(h.conn.format(logport.LevelFromSlog(record.Level), ts, record.Message, fields))
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	next := &Handler{conn: h.conn, prefix: h.prefix, attrs: append([]slog.Attr(nil), h.attrs...)}
+	for _, attr := range attrs {
+		if h.prefix != "" {
+			attr.Key = h.prefix + attr.Key
+		}
+		next.attrs = append(next.attrs, attr)
+	}
+	return next
+}
+
+// WithGroup implements slog.Handler; grouped keys are joined with ".".
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &Handler{conn: h.conn, attrs: h.attrs, prefix: h.prefix + name + "."}
+}
+
+// Severity maps a logport level onto a syslog severity.
+func Severity(level logport.Level) int {
+	return severity.FromLevel(level)
+}
+
+type field struct {
+	key   string
+	value string
+}
+
+func appendFields(dst []field, prefix string, attr slog.Attr) []field {
+	attr.Value = attr.Value.Resolve()
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, nested := range attr.Value.Group() {
+			dst = appendFields(dst, prefix, nested)
+		}
+		return dst
+	}
+	if attr.Key == "" {
+		return dst
+	}
+	var value string
+	if attr.Value.Kind() == slog.KindTime {
+		value = attr.Value.Time().Format(time.RFC3339Nano)
+	} else {
+		value = attr.Value.String()
+	}
+	return append(dst, field{key: prefix + attr.Key, value: value})
+}
+
+type connection struct {
+	opts Options
+	pid  int
+
+	mu   sync.Mutex
+	conn netsink.Conn
+}
+
+func (c *connection) format(level logport.Level, ts time.Time, msg string, fields []field) []byte {
+	pri := int(c.opts.Facility)*8 + Severity(level)
+	var b strings.Builder
+	if c.opts.Format == RFC3164 {
+		fmt.Fprintf(&b, "<%d>%s %s %s[%d]: %s", pri, ts.Format(time.Stamp), c.opts.Hostname, c.opts.AppName, c.pid, msg)
+		for _, f := range fields {
+			b.WriteByte(' ')
+			b.WriteString(f.key)
+			b.WriteByte('=')
+			if strings.ContainsAny(f.value, " \"=") || f.value == "" {
+				b.WriteString(strconv.Quote(f.value))
+			} else {
+				b.WriteString(f.value)
+			}
+		}
+		return []byte(b.String())
+	}
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %d - ", pri, ts.Format("2006-01-02T15:04:05.000000Z07:00"), c.opts.Hostname, c.opts.AppName, c.pid)
+	if len(fields) == 0 {
+		b.WriteByte('-')
+	} else {
+		b.WriteByte('[')
+		b.WriteString(c.opts.SDID)
+		for _, f := range fields {
+			b.WriteByte(' ')
+			b.WriteString(sdName(f.key))
+			b.WriteString(`="`)
+			b.WriteString(sdValueEscaper.Replace(f.value))
+			b.WriteByte('"')
+		}
+		b.WriteByte(']')
+	}
+	if msg != "" {
+		b.WriteByte(' ')
+		b.WriteString(msg)
+	}
+	return []byte(b.String())
+}
+
+func (c *connection) send(msg []byte) error {
+	if c.isStream() {
+		switch c.opts.Framing {
+		case NonTransparent:
+			msg = append(msg, '\n')
+		default:
+			msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.Send(func(conn net.Conn) error {
+		_ = conn.SetWriteDeadline(time.Now().Add(c.opts.WriteTimeout))
+		_, err := conn.Write(msg)
+		return err
+	})
+}
+
+func (c *connection) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.Close()
+}
+
+func (c *connection) isStream() bool {
+	switch c.opts.Network {
+	case "tcp", "tcp4", "tcp6", "tls", "unix":
+		return true
+	default:
+		return false
+	}
+}
+
+var sdValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// sdName turns key into a valid SD-NAME: at most 32 printable US-ASCII
+// characters other than '=', ' ', ']' and '"'.
+func sdName(key string) string {
+	b := []byte(key)
+	if len(b) > 32 {
+		b = b[:32]
+	}
+	for i, c := range b {
+		if c <= ' ' || c >= 127 || c == '=' || c == ']' || c == '"' {
+			b[i] = '_'
+		}
+	}
+	if len(b) == 0 {
+		return "_"
+	}
+	return string(b)
+}
+
+// headerField makes s usable as an RFC 5424 header field: printable
+// US-ASCII, at most limit characters, "-" when empty.
+func headerField(s string, limit int) string {
+	b := []byte(s)
+	if len(b) > limit {
+		b = b[:limit]
+	}
+	for i, c := range b {
+		if c <= ' ' || c >= 127 {
+			b[i] = '_'
+		}
+	}
+	if len(b) == 0 {
+		return "-"
+	}
+	return string(b)
+}
@@ -0,0 +1,256 @@
+package syslog
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"log/slog"
+	"net"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	logport "pkt.systems/logport"
+)
+
+var rfc5424Pattern = regexp.MustCompile(`^<(\d+)>1 \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}(Z|[+-]\d\d:\d\d) (\S+) (\S+) (\d+) - (.*)$`)
+
+func TestUDPSendsRFC5424WithStructuredData(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer pc.Close()
+
+	sink, err := New(Options{Address: pc.LocalAddr().String(), Facility: Local0, AppName: "billing", Hostname: "web 01"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer sink.Close()
+	sink.Logger().With("req", map[string]string{"id": "r1"}).Warn("invoice.sent", "note", `a "quoted" ] value`, "bad key", 1)
+
+	msg := readPacket(t, pc)
+	match := rfc5424Pattern.FindStringSubmatch(msg)
+	if match == nil {
+		t.Fatalf("unexpected message %q", msg)
+	}
+	if match[1] != strconv.Itoa(16*8+4) || match[3] != "web_01" || match[4] != "billing" {
+		t.Fatalf("unexpected header in %q", msg)
+	}
+	wantTail := `[logport@32473 req="map[id:r1\]" note="a \"quoted\" \] value" bad_key="1"] invoice.sent`
+	if match[6] != wantTail {
+		t.Fatalf("unexpected structured data/message:\n got %s\nwant %s", match[6], wantTail)
+	}
+}
+
+func TestTCPUsesOctetCountingAndReconnects(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	messages := make(chan string, 16)
+	go acceptFramed(ln, messages)
+
+	sink, err := New(Options{Network: "tcp", Address: addr, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer sink.Close()
+	logger := sink.Logger()
+
+	logger.Error("first")
+	if msg := receive(t, messages); !strings.HasSuffix(msg, " - - first") || !strings.HasPrefix(msg, "<11>1 ") {
+		t.Fatalf("unexpected first message %q", msg)
+	}
+
+	ln.Close()
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("cannot rebind %s: %v", addr, err)
+	}
+	defer ln.Close()
+	go acceptFramed(ln, messages)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		logger.Info("after restart")
+		select {
+		case msg := <-messages:
+			if strings.HasSuffix(msg, "after restart") {
+				return
+			}
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	t.Fatalf("no message after the receiver restarted")
+}
+
+func TestTLSAndRFC3164(t *testing.T) {
+	srv := httptest.NewUnstartedServer(nil)
+	srv.StartTLS()
+	serverConfig := srv.TLS.Clone()
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	srv.Close()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", serverConfig)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	messages := make(chan string, 4)
+	go acceptFramed(ln, messages)
+
+	sink, err := New(Options{
+		Network:   "tls",
+		Address:   ln.Addr().String(),
+		TLSConfig: &tls.Config{RootCAs: pool, ServerName: "example.com"},
+		Format:    RFC3164,
+		Facility:  Daemon,
+		AppName:   "appliance",
+		Hostname:  "host",
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer sink.Close()
+	sink.Logger().Debug("probe", "target", "db 1", "ok", true)
+
+	msg := receive(t, messages)
+	if !regexp.MustCompile(`^<31>[A-Z][a-z]{2} [ \d]\d \d\d:\d\d:\d\d host appliance\[\d+\]: probe target="db 1" ok=true$`).MatchString(msg) {
+		t.Fatalf("unexpected message %q", msg)
+	}
+}
+
+func TestUnixStreamUsesNewlineFraming(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 4)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	sink, err := New(Options{Network: "unix", Address: path})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer sink.Close()
+	sink.Logger().Info("one")
+	sink.Logger().Info("two")
+	for _, want := range []string{"one", "two"} {
+		if got := receive(t, lines); !strings.HasSuffix(got, " - - "+want) {
+			t.Fatalf("expected %q, got %q", want, got)
+		}
+	}
+}
+
+func TestSeverityAndOptionValidation(t *testing.T) {
+	cases := map[logport.Level]int{
+		logport.TraceLevel: 7, logport.DebugLevel: 7, logport.InfoLevel: 6, logport.NoLevel: 6,
+		logport.WarnLevel: 4, logport.ErrorLevel: 3, logport.FatalLevel: 2, logport.PanicLevel: 1,
+	}
+	for level, want := range cases {
+		if got := Severity(level); got != want {
+			t.Fatalf("Severity(%v) = %d, want %d", level, got, want)
+		}
+	}
+	if _, err := New(Options{Network: "sctp"}); err == nil {
+		t.Fatalf("expected unsupported network error")
+	}
+	if _, err := New(Options{Facility: 24}); err == nil {
+		t.Fatalf("expected invalid facility error")
+	}
+}
+
+func TestBackoffDropsWhileReceiverIsDown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	sink, err := New(Options{Network: "tcp", Address: addr, MinBackoff: time.Hour})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := sink.Handle(t.Context(), newRecord("lost")); err == nil || err == ErrBackoff {
+		t.Fatalf("expected dial error, got %v", err)
+	}
+	if err := sink.Handle(t.Context(), newRecord("lost")); err != ErrBackoff {
+		t.Fatalf("expected ErrBackoff, got %v", err)
+	}
+}
+
+func newRecord(msg string) slog.Record {
+	return slog.NewRecord(time.Now(), slog.LevelInfo, msg, 0)
+}
+
+func readPacket(t *testing.T, pc net.PacketConn) string {
+	t.Helper()
+	buf := make([]byte, 64*1024)
+	_ = pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return string(buf[:n])
+}
+
+// acceptFramed reads octet-counted messages from every accepted connection.
+func acceptFramed(ln net.Listener, out chan<- string) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			for {
+				size, err := r.ReadString(' ')
+				if err != nil {
+					return
+				}
+				n, err := strconv.Atoi(strings.TrimSpace(size))
+				if err != nil {
+					return
+				}
+				msg := make([]byte, n)
+				if _, err := io.ReadFull(r, msg); err != nil {
+					return
+				}
+				out <- string(msg)
+			}
+		}()
+	}
+}
+
+func receive(t *testing.T, ch <-chan string) string {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for a message")
+		return ""
+	}
+}
@@ -0,0 +1,132 @@
+package logport
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// AddSource wraps logger so that every entry carries SourceKey with the
+// file:line of the code that logged it. Frames inside logport, its
+// adapters, log/slog and the standard library log package are skipped, so
+// the location is the same whichever backend and entry point is used. slog
+// records that already carry a caller PC keep it.
+func AddSource(logger ForLogging) ForLogging {
+	if logger == nil {
+		logger = noopLogger{}
+	}
+	if _, ok := logger.(sourceLogger); ok {
+		return logger
+	}
+	return newSourceLogger(logger)
+}
+
+// callerSource returns the first call site outside the logging stack.
+func callerSource() string {
+	var pcs [32]uintptr
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !isLoggingFrame(frame) {
+			return shortSource(frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+func isLoggingFrame(frame runtime.Frame) bool {
+	fn := frame.Function
+	switch {
+	case strings.HasPrefix(fn, "runtime."), strings.HasPrefix(fn, "log/slog."), strings.HasPrefix(fn, "log."):
+		return true
+	case strings.HasPrefix(fn, "pkt.systems/logport.") || strings.HasPrefix(fn, "pkt.systems/logport/"):
+		return !strings.HasSuffix(frame.File, "_test.go")
+	}
+	return false
+}
+
+func shortSource(file string, line int) string {
+	if file == "" {
+		return ""
+	}
+	return filepath.Base(file) + ":" + strconv.Itoa(line)
+}
+
+type sourceLogger struct {
+	middleware
+}
+
+func newSourceLogger(target ForLogging) ForLogging {
+	return sourceLogger{middleware{target: target, wrap: newSourceLogger}}
+}
+
+func (l sourceLogger) keyvals(keyvals []any) []any {
+	source := callerSource()
+	if source == "" {
+		return keyvals
+	}
+	out := make([]any, 0, len(keyvals)+2)
+	return append(append(out, keyvals...), SourceKey, source)
+}
+
+func (l sourceLogger) Logp(level Level, msg string, keyvals ...any) {
+	l.target.Logp(level, msg, l.keyvals(keyvals)...)
+}
+
+func (l sourceLogger) Logf(level Level, format string, v ...any) {
+	l.target.Logp(level, fmt.Sprintf(format, v...), l.keyvals(nil)...)
+}
+
+func (l sourceLogger) Logs(level string, msg string, keyvals ...any) {
+	l.target.Logs(level, msg, l.keyvals(keyvals)...)
+}
+
+func (l sourceLogger) Log(ctx context.Context, level slog.Level, msg string, keyvals ...any) {
+	l.target.Log(ctx, level, msg, l.keyvals(keyvals)...)
+}
+
+func (l sourceLogger) Trace(msg string, keyvals ...any) { l.Logp(TraceLevel, msg, keyvals...) }
+func (l sourceLogger) Debug(msg string, keyvals ...any) { l.Logp(DebugLevel, msg, keyvals...) }
+func (l sourceLogger) Info(msg string, keyvals ...any)  { l.Logp(InfoLevel, msg, keyvals...) }
+func (l sourceLogger) Warn(msg string, keyvals ...any)  { l.Logp(WarnLevel, msg, keyvals...) }
+func (l sourceLogger) Error(msg string, keyvals ...any) { l.Logp(ErrorLevel, msg, keyvals...) }
+func (l sourceLogger) Fatal(msg string, keyvals ...any) { l.target.Fatal(msg, l.keyvals(keyvals)...) }
+func (l sourceLogger) Panic(msg string, keyvals ...any) { l.target.Panic(msg, l.keyvals(keyvals)...) }
+
+func (l sourceLogger) Tracef(format string, v ...any) { l.Logf(TraceLevel, format, v...) }
+func (l sourceLogger) Debugf(format string, v ...any) { l.Logf(DebugLevel, format, v...) }
+func (l sourceLogger) Infof(format string, v ...any)  { l.Logf(InfoLevel, format, v...) }
+func (l sourceLogger) Warnf(format string, v ...any)  { l.Logf(WarnLevel, format, v...) }
+func (l sourceLogger) Errorf(format string, v ...any) { l.Logf(ErrorLevel, format, v...) }
+func (l sourceLogger) Fatalf(format string, v ...any) {
+	l.target.Fatal(fmt.Sprintf(format, v...), l.keyvals(nil)...)
+}
+func (l sourceLogger) Panicf(format string, v ...any) {
+	l.target.Panic(fmt.Sprintf(format, v...), l.keyvals(nil)...)
+}
+
+func (l sourceLogger) Write(p []byte) (int, error) {
+	return WriteToLogger(l, p)
+}
+
+func (l sourceLogger) Handle(ctx context.Context, record slog.Record) error {
+	source := ""
+	if record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		source = shortSource(frame.File, frame.Line)
+	} else {
+		source = callerSource()
+	}
+	if source != "" {
+		record = record.Clone()
+		record.AddAttrs(slog.String(SourceKey, source))
+	}
+	return l.target.Handle(ctx, record)
+}
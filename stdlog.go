@@ -0,0 +1,130 @@
+package logport
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// StdLogOptions configures RedirectStdLog.
+type StdLogOptions struct {
+	// Level, when non-nil, pins every line written through the log package to
+	// this level.
+	Level *Level
+
+	// DefaultLevel is the level of log package lines without a detectable
+	// level. Defaults to InfoLevel.
+	DefaultLevel *Level
+
+	// Classifier derives levels and messages from log package lines. Nil
+	// selects DefaultClassifier.
+	Classifier Classifier
+
+	// AddSource adds a SourceKey field with the file:line of the log.Print*
+	// or slog call.
+	AddSource bool
+}
+
+// RedirectStdLog routes the package-level log functions and slog.Default()
+// through logger. It sets the log package's output to logger with no flags
+// or prefix and installs slog.New(logger) as the slog default. The returned
+// function restores the previous log output, flags, prefix and slog default;
+// it is safe to call more than once, which makes the redirect suitable for
+// tests:
+//
+//	restore := logport.RedirectStdLog(logger, logport.StdLogOptions{})
+//	defer restore()
+//
+// Redirects do not nest independently; restore them in reverse order.
+func RedirectStdLog(logger ForLogging, opts StdLogOptions) (restore func()) {
+	if logger == nil {
+		logger = noopLogger{}
+	}
+	if opts.DefaultLevel == nil {
+		level := InfoLevel
+		opts.DefaultLevel = &level
+	}
+
+	prevSlog := slog.Default()
+	prevOutput := log.Writer()
+	prevFlags := log.Flags()
+	prevPrefix := log.Prefix()
+
+	var handler slog.Handler = logger
+	if opts.AddSource {
+		handler = sourceHandler{Handler: logger}
+	}
+	// slog.SetDefault points the log package at the new handler, so it must
+	// run before the log package is configured.
+	slog.SetDefault(slog.New(handler))
+	flags := 0
+	if opts.AddSource {
+		flags = log.Lshortfile
+	}
+	log.SetOutput(stdLogWriter{logger: logger, opts: opts})
+	log.SetFlags(flags)
+	log.SetPrefix("")
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			slog.SetDefault(prevSlog)
+			log.SetOutput(prevOutput)
+			log.SetFlags(prevFlags)
+			log.SetPrefix(prevPrefix)
+		})
+	}
+}
+
+// stdLogWriter receives one log package entry per Write.
+type stdLogWriter struct {
+	logger ForLogging
+	opts   StdLogOptions
+}
+
+func (w stdLogWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	logger := w.logger
+	entry := p
+	if w.opts.AddSource {
+		// Lshortfile renders "file.go:12: " ahead of the message.
+		if idx := bytes.Index(entry, []byte(": ")); idx > 0 && isSourceLocation(string(entry[:idx])) {
+			logger = logger.With(SourceKey, string(entry[:idx]))
+			entry = entry[idx+2:]
+		}
+	}
+	writeBytesToLogger(logger, entry, w.opts.Level, w.opts.DefaultLevel, w.opts.Classifier)
+	return len(p), nil
+}
+
+// sourceHandler adds a SourceKey attribute derived from the record's program
+// counter.
+type sourceHandler struct {
+	slog.Handler
+}
+
+func (h sourceHandler) Handle(ctx context.Context, record slog.Record) error {
+	if record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		if frame.File != "" {
+			record = record.Clone()
+			record.AddAttrs(slog.String(SourceKey, filepath.Base(frame.File)+":"+strconv.Itoa(frame.Line)))
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h sourceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return sourceHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h sourceHandler) WithGroup(name string) slog.Handler {
+	return sourceHandler{Handler: h.Handler.WithGroup(name)}
+}
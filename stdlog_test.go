@@ -0,0 +1,164 @@
+package logport_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+
+	logport "pkt.systems/logport"
+	psladapter "pkt.systems/logport/adapters/psl"
+)
+
+func TestRedirectStdLogRoutesAndRestores(t *testing.T) {
+	prevSlog := slog.Default()
+	prevOutput := log.Writer()
+	prevFlags := log.Flags()
+
+	var buf bytes.Buffer
+	logger := psladapter.NewWithOptions(&buf, psladapter.Options{Mode: psladapter.ModeStructured, DisableTimestamp: true, NoColor: true})
+	restore := logport.RedirectStdLog(logger, logport.StdLogOptions{AddSource: true})
+
+	log.Printf("plain %d", 1)
+	log.Print("WARN: disk low")
+	slog.Info("from slog", "k", 2)
+	restore()
+	restore()
+
+	if slog.Default() != prevSlog || log.Writer() != prevOutput || log.Flags() != prevFlags {
+		t.Fatalf("expected originals to be restored")
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 entries, got %q", buf.String())
+	}
+	expect := []struct{ msg, lvl string }{
+		{"plain 1", "info"},
+		{"disk low", "warn"},
+		{"from slog", "info"},
+	}
+	for i, line := range lines {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("decode %q: %v", line, err)
+		}
+		if record["msg"] != expect[i].msg || record["lvl"] != expect[i].lvl {
+			t.Fatalf("entry %d: expected %v, got %v", i, expect[i], record)
+		}
+		source, _ := record[logport.SourceKey].(string)
+		if !strings.HasPrefix(source, "stdlog_test.go:") {
+			t.Fatalf("entry %d: expected source in this file, got %v", i, record)
+		}
+	}
+}
+
+func TestRedirectStdLogPinnedLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := psladapter.NewWithOptions(&buf, psladapter.Options{Mode: psladapter.ModeStructured, DisableTimestamp: true, NoColor: true})
+	level := logport.ErrorLevel
+	restore := logport.RedirectStdLog(logger, logport.StdLogOptions{Level: &level})
+	log.Print("[INFO] still an error")
+	restore()
+
+	var record map[string]any
+	if err := json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &record); err != nil {
+		t.Fatalf("decode %q: %v", buf.String(), err)
+	}
+	if record["lvl"] != "error" || record["msg"] != "still an error" {
+		t.Fatalf("unexpected entry %v", record)
+	}
+	if _, ok := record[logport.SourceKey]; ok {
+		t.Fatalf("source should be omitted without AddSource, got %v", record)
+	}
+}
+
+func TestBridgedNestedFieldsUseBackendGroupRendering(t *testing.T) {
+	for _, name := range logport.Backends() {
+		var buf bytes.Buffer
+		logger, err := logport.Open(name, &buf, logport.CommonOptions{Structured: true})
+		if err != nil {
+			t.Fatalf("%s: open: %v", name, err)
+		}
+		if _, err := logport.WriteToLogger(logger, []byte(`{"level":"info","msg":"nested","req":{"peer":{"ip":"10.0.0.1"}}}`+"\n")); err != nil {
+			t.Fatalf("%s: write: %v", name, err)
+		}
+		records := decodeRecords(t, buf.Bytes())
+		if len(records) != 1 {
+			t.Fatalf("%s: expected one entry, got %s", name, buf.String())
+		}
+		got := records[0]["req.peer.ip"]
+		if name == "slog" {
+			req, _ := records[0]["req"].(map[string]any)
+			peer, _ := req["peer"].(map[string]any)
+			got = peer["ip"]
+		}
+		if got != "10.0.0.1" {
+			t.Errorf("%s: expected req.peer.ip rendered as a group, got %s", name, buf.String())
+		}
+	}
+}
+
+func TestBridgedFatalLinesDoNotExit(t *testing.T) {
+	buf := &lockedBuffer{}
+	exits := 0
+	logger := psladapter.NewWithOptions(buf, psladapter.Options{
+		Mode:             psladapter.ModeStructured,
+		DisableTimestamp: true,
+		NoColor:          true,
+		ExitFunc:         func(int) { exits++ },
+		PanicPolicy:      logport.PanicRaise,
+	})
+	input := strings.Join([]string{
+		`{"level":"fatal","msg":"json fatal"}`,
+		`{"level":60,"msg":"pino fatal"}`,
+		`F0501 10:00:00.000000    1 main.go:1] klog fatal`,
+		`<2>syslog critical`,
+		`PANIC: text panic`,
+	}, "\n") + "\n"
+	if _, err := logport.WriteToLogger(logger, []byte(input)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if exits != 0 {
+		t.Fatalf("bridged lines called ExitFunc %d times", exits)
+	}
+	records := decodeRecords(t, buf.Bytes())
+	if len(records) != 5 {
+		t.Fatalf("expected five entries, got %s", buf.String())
+	}
+	for _, record := range records {
+		if record["lvl"] != "error" {
+			t.Errorf("expected the fatal line at error, got %v", record)
+		}
+	}
+}
+
+func TestPinnedFatalLevelIsNotClamped(t *testing.T) {
+	buf := &lockedBuffer{}
+	exits := 0
+	logger := psladapter.NewWithOptions(buf, psladapter.Options{
+		Mode:             psladapter.ModeStructured,
+		DisableTimestamp: true,
+		NoColor:          true,
+		ExitFunc:         func(int) { exits++ },
+		PanicPolicy:      logport.PanicRaise,
+	})
+	logport.LogLoggerWithLevel(logger, logport.FatalLevel).Print("pinned fatal")
+	if exits != 1 {
+		t.Fatalf("expected the pinned fatal entry to call ExitFunc once, got %d", exits)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected the pinned panic entry to panic")
+			}
+		}()
+		logport.LogLoggerWithLevelAndClassifier(logger, logport.PanicLevel, nil).Print("pinned panic")
+	}()
+	records := decodeRecords(t, buf.Bytes())
+	if len(records) != 2 || records[0]["lvl"] != "fatal" || records[1]["lvl"] != "panic" {
+		t.Fatalf("expected fatal and panic entries, got %s", buf.String())
+	}
+}
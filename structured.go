@@ -0,0 +1,363 @@
+package logport
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OriginalTimeKey is the structured logging key carrying the timestamp found
+// in a line written through the io.Writer bridges. The adapter stamps entries
+// with its own time, so the original is kept alongside it.
+const OriginalTimeKey = "orig_time"
+
+// FieldClassifier is an optional extension of Classifier for lines that carry
+// their own fields, such as JSON objects and logfmt records. The writer
+// bridges prefer ClassifyFields when a classifier implements it and pass the
+// returned keyvals on to the target logger.
+type FieldClassifier interface {
+	Classifier
+	ClassifyFields(line string) (level Level, msg string, keyvals []any)
+}
+
+// ClassifyFields implements FieldClassifier. Unless disabled with
+// ClassifierBuilder.DisableHeaders, syslog, klog/glog, log package and
+// timestamp headers are stripped first: their time becomes OriginalTimeKey, a
+// file:line becomes SourceKey and a syslog priority or klog severity letter
+// sets the level. Unless disabled with ClassifierBuilder.DisableStructured,
+// the rest of the line is then decoded when it is a JSON object or logfmt
+// record: level/lvl/severity select the level, msg/message the message,
+// time/ts/timestamp become OriginalTimeKey and the remaining pairs are returned
+// in order after the header fields, replacing any header field of the same
+// key. Nested objects are returned as slog.Group attrs so each adapter renders
+// them the way it renders groups. Whatever remains is classified like
+// Classify.
+func (c *RuleClassifier) ClassifyFields(raw string) (Level, string, []any) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return NoLevel, "", nil
+	}
+	for _, rule := range c.rules {
+		if level, msg, ok := rule(trimmed); ok {
+			return level, msg, nil
+		}
+	}
+	level, rest, header, found := NoLevel, trimmed, []any(nil), false
+	if !c.noHeaders {
+		level, rest, header, found = parseLineHeader(trimmed)
+	}
+	if !c.noStructured {
+		if fieldLevel, msg, keyvals, ok := parseStructuredLine(rest); ok {
+			if fieldLevel != NoLevel {
+				level = fieldLevel
+			}
+			return level, msg, mergeKeyvals(header, keyvals)
+		}
+	}
+	if found {
+		if level == NoLevel {
+			level, rest = c.classifyHeuristics(rest)
+		}
+		return level, rest, header
+	}
+	level, msg := c.classifyHeuristics(trimmed)
+	return level, msg, nil
+}
+
+// mergeKeyvals returns the pairs of base whose keys are not set in override,
+// followed by override. base holds key/value pairs; override may also hold
+// slog.Attr groups.
+func mergeKeyvals(base, override []any) []any {
+	if len(base) == 0 {
+		return override
+	}
+	set := make(map[string]struct{}, len(override)/2)
+	for i := 0; i < len(override); i++ {
+		switch kv := override[i].(type) {
+		case slog.Attr:
+			set[kv.Key] = struct{}{}
+		case string:
+			set[kv] = struct{}{}
+			i++
+		}
+	}
+	merged := make([]any, 0, len(base)+len(override))
+	for i := 0; i+1 < len(base); i += 2 {
+		if key, ok := base[i].(string); ok {
+			if _, dup := set[key]; dup {
+				continue
+			}
+		}
+		merged = append(merged, base[i], base[i+1])
+	}
+	return append(merged, override...)
+}
+
+// classifyLine runs classifier, preferring FieldClassifier when available.
+func classifyLine(classifier Classifier, raw string) (Level, string, []any) {
+	if fc, ok := classifier.(FieldClassifier); ok {
+		return fc.ClassifyFields(raw)
+	}
+	level, msg := classifier.Classify(raw)
+	return level, msg, nil
+}
+
+func parseStructuredLine(line string) (Level, string, []any, bool) {
+	var pairs []slog.Attr
+	switch {
+	case strings.HasPrefix(line, "{") && strings.HasSuffix(line, "}"):
+		attrs, ok := parseJSONObject(line)
+		if !ok {
+			return NoLevel, "", nil, false
+		}
+		pairs = attrs
+	case strings.IndexByte(line, '=') > 0:
+		attrs, ok := parseLogfmt(line)
+		if !ok {
+			return NoLevel, "", nil, false
+		}
+		pairs = attrs
+	default:
+		return NoLevel, "", nil, false
+	}
+
+	level, msg := NoLevel, ""
+	keyvals := make([]any, 0, len(pairs))
+	for _, attr := range pairs {
+		switch strings.ToLower(attr.Key) {
+		case "level", "lvl", "severity":
+			if lvl, ok := structuredLevel(attr.Value); ok {
+				level = lvl
+				continue
+			}
+		case "msg", "message":
+			if attr.Value.Kind() == slog.KindString {
+				msg = attr.Value.String()
+				continue
+			}
+		case "time", "ts", "timestamp":
+			keyvals = append(keyvals, OriginalTimeKey, structuredTime(attr.Value))
+			continue
+		}
+		if attr.Value.Kind() == slog.KindGroup {
+			keyvals = append(keyvals, attr)
+			continue
+		}
+		keyvals = append(keyvals, attr.Key, attr.Value.Any())
+	}
+	return level, msg, keyvals, true
+}
+
+// parseJSONObject decodes a single JSON object into attrs, preserving key
+// order and turning nested objects into group attrs.
+func parseJSONObject(line string) ([]slog.Attr, bool) {
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+	attrs, err := decodeJSONObject(dec)
+	if err != nil || dec.More() {
+		return nil, false
+	}
+	if _, err := dec.Token(); err == nil {
+		return nil, false
+	}
+	return attrs, true
+}
+
+func decodeJSONObject(dec *json.Decoder) ([]slog.Attr, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, errNotObject
+	}
+	var attrs []slog.Attr
+	for dec.More() {
+		keyTok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := keyTok.(string)
+		value, err := decodeJSONValue(dec)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, slog.Attr{Key: key, Value: value})
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return attrs, nil
+}
+
+func decodeJSONValue(dec *json.Decoder) (slog.Value, error) {
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return slog.Value{}, err
+	}
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '{' {
+		inner := json.NewDecoder(bytes.NewReader(raw))
+		inner.UseNumber()
+		attrs, err := decodeJSONObject(inner)
+		if err != nil {
+			return slog.Value{}, err
+		}
+		return slog.GroupValue(attrs...), nil
+	}
+	var v any
+	inner := json.NewDecoder(bytes.NewReader(raw))
+	inner.UseNumber()
+	if err := inner.Decode(&v); err != nil {
+		return slog.Value{}, err
+	}
+	if n, ok := v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return slog.Int64Value(i), nil
+		}
+		f, _ := n.Float64()
+		return slog.Float64Value(f), nil
+	}
+	return slog.AnyValue(v), nil
+}
+
+type structuredError string
+
+func (e structuredError) Error() string { return string(e) }
+
+const errNotObject = structuredError("not a JSON object")
+
+// parseLogfmt decodes key=value pairs separated by spaces. Values may be
+// double-quoted with Go escapes. The line is only accepted when every token is
+// a pair and a level or message key is present, so ordinary prose containing
+// "=" is left alone.
+func parseLogfmt(line string) ([]slog.Attr, bool) {
+	var attrs []slog.Attr
+	known := false
+	for i := 0; i < len(line); {
+		for i < len(line) && line[i] == ' ' {
+			i++
+		}
+		if i >= len(line) {
+			break
+		}
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' && line[i] != '"' {
+			i++
+		}
+		key := line[start:i]
+		if key == "" {
+			return nil, false
+		}
+		var value slog.Value
+		switch {
+		case i >= len(line) || line[i] != '=':
+			return nil, false
+		default:
+			i++ // '='
+			if i < len(line) && line[i] == '"' {
+				end := i + 1
+				for end < len(line) && line[end] != '"' {
+					if line[end] == '\\' {
+						end++
+					}
+					end++
+				}
+				if end >= len(line) {
+					return nil, false
+				}
+				unquoted, err := strconv.Unquote(line[i : end+1])
+				if err != nil {
+					return nil, false
+				}
+				value = slog.StringValue(unquoted)
+				i = end + 1
+				if i < len(line) && line[i] != ' ' {
+					return nil, false
+				}
+			} else {
+				vstart := i
+				for i < len(line) && line[i] != ' ' {
+					i++
+				}
+				value = logfmtScalar(line[vstart:i])
+			}
+		}
+		switch strings.ToLower(key) {
+		case "level", "lvl", "severity", "msg", "message":
+			known = true
+		}
+		attrs = append(attrs, slog.Attr{Key: key, Value: value})
+	}
+	return attrs, known && len(attrs) > 0
+}
+
+func logfmtScalar(s string) slog.Value {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return slog.Int64Value(i)
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return slog.Float64Value(f)
+	}
+	if b, err := strconv.ParseBool(s); err == nil {
+		return slog.BoolValue(b)
+	}
+	return slog.StringValue(s)
+}
+
+// structuredLevel maps textual levels and the numeric levels used by
+// bunyan/pino (10 trace … 60 fatal) onto Level.
+func structuredLevel(v slog.Value) (Level, bool) {
+	switch v.Kind() {
+	case slog.KindString:
+		if lvl, ok := levelFromToken(v.String()); ok {
+			return lvl, true
+		}
+		return levelFromLetter(v.String())
+	case slog.KindInt64:
+		switch n := v.Int64(); {
+		case n >= 60:
+			return FatalLevel, true
+		case n >= 50:
+			return ErrorLevel, true
+		case n >= 40:
+			return WarnLevel, true
+		case n >= 30:
+			return InfoLevel, true
+		case n >= 20:
+			return DebugLevel, true
+		case n >= 10:
+			return TraceLevel, true
+		}
+	}
+	return NoLevel, false
+}
+
+// structuredTime parses RFC 3339 strings and Unix epoch numbers (seconds,
+// milliseconds or nanoseconds) into time.Time, returning other values as-is.
+func structuredTime(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindString:
+		if t, err := time.Parse(time.RFC3339Nano, v.String()); err == nil {
+			return t
+		}
+	case slog.KindInt64:
+		n := v.Int64()
+		switch {
+		case n > 1e17:
+			return time.Unix(0, n).UTC()
+		case n > 1e11:
+			return time.UnixMilli(n).UTC()
+		case n > 0:
+			return time.Unix(n, 0).UTC()
+		}
+	case slog.KindFloat64:
+		f := v.Float64()
+		sec := int64(f)
+		return time.Unix(sec, int64((f-float64(sec))*1e9)).UTC()
+	}
+	return v.Any()
+}
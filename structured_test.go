@@ -0,0 +1,130 @@
+package logport
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestWriteToLoggerParsesJSONLines(t *testing.T) {
+	rec := &recordingLogger{}
+	line := `{"time":"2024-05-01T10:00:00Z","level":"warn","msg":"disk low","free":12,"ratio":0.5,"req":{"id":"abc","peer":{"ip":"10.0.0.1"}},"ok":true}` + "\n"
+	if _, err := WriteToLogger(rec, []byte(line)); err != nil {
+		t.Fatalf("WriteToLogger failed: %v", err)
+	}
+	if len(rec.entries) != 1 {
+		t.Fatalf("expected one entry, got %+v", rec.entries)
+	}
+	entry := rec.entries[0]
+	if entry.level != WarnLevel || entry.msg != "disk low" {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+	wantTime := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	if got, ok := entry.field(OriginalTimeKey); !ok || !got.(time.Time).Equal(wantTime) {
+		t.Fatalf("expected %s=%v, got %v", OriginalTimeKey, wantTime, got)
+	}
+	want := map[string]any{
+		"free":  int64(12),
+		"ratio": 0.5,
+		"ok":    true,
+	}
+	for key, value := range want {
+		if got, ok := entry.field(key); !ok || got != value {
+			t.Fatalf("expected %s=%v, got %v (keyvals %v)", key, value, got, entry.keyvals)
+		}
+	}
+	wantReq := slog.GroupValue(slog.String("id", "abc"), slog.Group("peer", slog.String("ip", "10.0.0.1")))
+	if got, ok := entry.field("req"); !ok || !got.(slog.Value).Equal(wantReq) {
+		t.Fatalf("expected req as a group %v, got %v (keyvals %v)", wantReq, got, entry.keyvals)
+	}
+	if _, ok := entry.field("level"); ok {
+		t.Fatalf("level should be consumed, got %v", entry.keyvals)
+	}
+}
+
+func TestWriteToLoggerParsesLogfmtLines(t *testing.T) {
+	rec := &recordingLogger{}
+	input := "ts=1714557600 lvl=error msg=\"connect failed\" host=db-1 attempt=3 retry=true\n"
+	if _, err := WriteToLogger(rec, []byte(input)); err != nil {
+		t.Fatalf("WriteToLogger failed: %v", err)
+	}
+	if len(rec.entries) != 1 {
+		t.Fatalf("expected one entry, got %+v", rec.entries)
+	}
+	entry := rec.entries[0]
+	if entry.level != ErrorLevel || entry.msg != "connect failed" {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+	if got, _ := entry.field(OriginalTimeKey); !got.(time.Time).Equal(time.Unix(1714557600, 0)) {
+		t.Fatalf("unexpected %s %v", OriginalTimeKey, got)
+	}
+	for key, value := range map[string]any{"host": "db-1", "attempt": int64(3), "retry": true} {
+		if got, ok := entry.field(key); !ok || got != value {
+			t.Fatalf("expected %s=%v, got %v (keyvals %v)", key, value, got, entry.keyvals)
+		}
+	}
+}
+
+func TestStructuredParsingLeavesProseAlone(t *testing.T) {
+	cases := []struct {
+		input string
+		level Level
+		msg   string
+	}{
+		{"set x=1 because reasons", NoLevel, "set x=1 because reasons"},
+		{"user=bob id=7", NoLevel, "user=bob id=7"},
+		{"{not json}", NoLevel, "{not json}"},
+		{`{"a":1} {"b":2}`, NoLevel, `{"a":1} {"b":2}`},
+		{`level=warn msg="unterminated`, NoLevel, `level=warn msg="unterminated`},
+		{"Request failed with level=3 retrying now", NoLevel, "Request failed with level=3 retrying now"},
+		{"user set msg=hi for everyone", NoLevel, "user set msg=hi for everyone"},
+		{"level=info retry", NoLevel, "level=info retry"},
+	}
+	for _, tc := range cases {
+		level, msg, keyvals := DefaultClassifier().(FieldClassifier).ClassifyFields(tc.input)
+		if level != tc.level || msg != tc.msg || len(keyvals) != 0 {
+			t.Fatalf("ClassifyFields(%q) = %v %q %v, want %v %q", tc.input, level, msg, keyvals, tc.level, tc.msg)
+		}
+	}
+}
+
+func TestStructuredLevels(t *testing.T) {
+	cases := map[string]Level{
+		`{"level":30,"msg":"pino"}`:        InfoLevel,
+		`{"level":50,"msg":"pino"}`:        ErrorLevel,
+		`{"severity":"DEBUG","msg":"gcp"}`: DebugLevel,
+		`{"lvl":"W","message":"letter"}`:   WarnLevel,
+		`{"msg":"no level"}`:               NoLevel,
+	}
+	for input, want := range cases {
+		if level, _ := DefaultClassifier().Classify(input); level != want {
+			t.Fatalf("Classify(%q) = %v, want %v", input, level, want)
+		}
+	}
+}
+
+func TestDisableStructuredKeepsLinesVerbatim(t *testing.T) {
+	classifier := NewClassifierBuilder().DisableStructured().Build()
+	line := `{"level":"info","msg":"hello"}`
+	level, msg, keyvals := classifier.ClassifyFields(line)
+	if level != NoLevel || msg != line || keyvals != nil {
+		t.Fatalf("expected verbatim line, got %v %q %v", level, msg, keyvals)
+	}
+}
+
+func TestLineWriterParsesJSONLines(t *testing.T) {
+	rec := &recordingLogger{}
+	w := NewLineWriter(rec, LineWriterOptions{IdleTimeout: -1})
+	_, _ = w.Write([]byte(`{"level":"info","msg":"started","port":8080}` + "\n"))
+	_ = w.Close()
+	if len(rec.entries) != 1 {
+		t.Fatalf("expected one entry, got %+v", rec.entries)
+	}
+	entry := rec.entries[0]
+	if entry.level != InfoLevel || entry.msg != "started" {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+	if got, ok := entry.field("port"); !ok || got != int64(8080) {
+		t.Fatalf("expected port field, got %v", entry.keyvals)
+	}
+}
@@ -0,0 +1,186 @@
+package logport
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultWideEventMaxFields bounds the number of distinct keys a wide
+	// event accumulates.
+	DefaultWideEventMaxFields = 256
+
+	// DroppedFieldsKey is the structured logging key counting the fields a
+	// wide event discarded after reaching its limit.
+	DroppedFieldsKey = "dropped_fields"
+)
+
+// WideEventOptions configures StartWideEventWithOptions.
+type WideEventOptions struct {
+	// Level is the minimum level of the emitted entry. Defaults to InfoLevel.
+	Level *Level
+
+	// MaxFields bounds the number of distinct keys kept. Further keys are
+	// counted in DroppedFieldsKey. Defaults to DefaultWideEventMaxFields.
+	MaxFields int
+}
+
+type wideEventContextKey struct{}
+
+// attrField holds a field added as a slog.Attr so it is emitted as one.
+type attrField slog.Attr
+
+// wideEvent accumulates the fields of one canonical log line.
+type wideEvent struct {
+	logger    ForLogging
+	name      string
+	started   time.Time
+	maxFields int
+
+	mu       sync.Mutex
+	level    Level
+	keys     []string
+	values   map[string]any
+	dropped  int
+	finished bool
+}
+
+// StartWideEvent begins a canonical log line named name and returns a child
+// context carrying it. Code holding the context adds fields with AddFields;
+// FinishWideEvent then emits a single entry with every field, DurationKey and
+// the highest level recorded:
+//
+//	ctx = logport.StartWideEvent(ctx, logger, "http.request")
+//	defer logport.FinishWideEvent(ctx)
+//	…
+//	logport.AddFields(ctx, "user_id", id, "cache_hit", true)
+func StartWideEvent(ctx context.Context, logger ForLogging, name string) context.Context {
+	return StartWideEventWithOptions(ctx, logger, name, WideEventOptions{})
+}
+
+// StartWideEventWithOptions is like StartWideEvent with explicit options.
+func StartWideEventWithOptions(ctx context.Context, logger ForLogging, name string, opts WideEventOptions) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if logger == nil {
+		logger = noopLogger{}
+	}
+	level := InfoLevel
+	if opts.Level != nil {
+		level = *opts.Level
+	}
+	if opts.MaxFields <= 0 {
+		opts.MaxFields = DefaultWideEventMaxFields
+	}
+	event := &wideEvent{
+		logger:    logger,
+		name:      name,
+		started:   time.Now(),
+		maxFields: opts.MaxFields,
+		level:     level,
+		values:    make(map[string]any),
+	}
+	return context.WithValue(ctx, wideEventContextKey{}, event)
+}
+
+// AddFields records key/value pairs on the wide event in ctx. A key added
+// twice keeps its first position and its latest value. It is safe for
+// concurrent use and does nothing when ctx carries no event or the event has
+// finished.
+func AddFields(ctx context.Context, keyvals ...any) {
+	if event := wideEventFromContext(ctx); event != nil {
+		event.add(NoLevel, keyvals)
+	}
+}
+
+// AddFieldsAt is like AddFields and also raises the level of the wide event
+// to level if it is higher than any seen so far. FatalLevel and PanicLevel
+// count as ErrorLevel so finishing an event never exits or panics.
+func AddFieldsAt(ctx context.Context, level Level, keyvals ...any) {
+	if event := wideEventFromContext(ctx); event != nil {
+		event.add(level, keyvals)
+	}
+}
+
+// FinishWideEvent emits the wide event in ctx as a single entry. Only the
+// first call emits; later calls and calls without an event do nothing.
+func FinishWideEvent(ctx context.Context) {
+	event := wideEventFromContext(ctx)
+	if event == nil {
+		return
+	}
+	event.mu.Lock()
+	if event.finished {
+		event.mu.Unlock()
+		return
+	}
+	event.finished = true
+	keyvals := make([]any, 0, len(event.keys)*2+4)
+	for _, key := range event.keys {
+		if attr, ok := event.values[key].(attrField); ok {
+			keyvals = append(keyvals, slog.Attr(attr))
+			continue
+		}
+		keyvals = append(keyvals, key, event.values[key])
+	}
+	keyvals = append(keyvals, DurationKey, time.Since(event.started))
+	if event.dropped > 0 {
+		keyvals = append(keyvals, DroppedFieldsKey, event.dropped)
+	}
+	level := event.level
+	event.values = nil
+	event.mu.Unlock()
+
+	event.logger.Logp(level, event.name, keyvals...)
+}
+
+func wideEventFromContext(ctx context.Context) *wideEvent {
+	if ctx == nil {
+		return nil
+	}
+	event, _ := ctx.Value(wideEventContextKey{}).(*wideEvent)
+	return event
+}
+
+func (e *wideEvent) add(level Level, keyvals []any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.finished {
+		return
+	}
+	switch level {
+	case NoLevel, Disabled:
+	case FatalLevel, PanicLevel:
+		e.level = max(e.level, ErrorLevel)
+	default:
+		e.level = max(e.level, level)
+	}
+	for i := 0; i < len(keyvals); i++ {
+		var key string
+		var value any
+		switch kv := keyvals[i].(type) {
+		case slog.Attr:
+			key, value = kv.Key, attrField(kv)
+		case string:
+			key = kv
+			if i+1 < len(keyvals) {
+				value = keyvals[i+1]
+			}
+			i++
+		default:
+			i++
+			continue
+		}
+		if _, exists := e.values[key]; !exists {
+			if len(e.keys) >= e.maxFields {
+				e.dropped++
+				continue
+			}
+			e.keys = append(e.keys, key)
+		}
+		e.values[key] = value
+	}
+}
@@ -0,0 +1,114 @@
+package logport
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWideEventEmitsOnceWithAllFields(t *testing.T) {
+	rec := &lockedRecorder{}
+	ctx := StartWideEvent(context.Background(), rec, "http.request")
+
+	AddFields(ctx, "route", "/users", "status", 200)
+	AddFieldsAt(ctx, WarnLevel, "slow", true)
+	AddFields(ctx, "status", 503)
+	AddFieldsAt(ctx, DebugLevel, "cache", "miss")
+	FinishWideEvent(ctx)
+	FinishWideEvent(ctx)
+	AddFields(ctx, "late", 1)
+
+	if len(rec.entries) != 1 {
+		t.Fatalf("expected exactly one entry, got %+v", rec.entries)
+	}
+	entry := rec.entries[0]
+	if entry.level != WarnLevel || entry.msg != "http.request" {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+	wantKeys := []any{"route", "/users", "status", 503, "slow", true, "cache", "miss", DurationKey}
+	for i, want := range wantKeys {
+		if i >= len(entry.keyvals) || entry.keyvals[i] != want {
+			t.Fatalf("unexpected keyvals %v", entry.keyvals)
+		}
+	}
+	if len(entry.keyvals) != 10 {
+		t.Fatalf("expected no late or dropped fields, got %v", entry.keyvals)
+	}
+}
+
+func TestWideEventKeepsAttrFields(t *testing.T) {
+	rec := &lockedRecorder{}
+	ctx := StartWideEvent(context.Background(), rec, "job")
+
+	AddFields(ctx, slog.Group("req", slog.String("id", "abc")), "status", 200)
+	FinishWideEvent(ctx)
+
+	if len(rec.entries) != 1 {
+		t.Fatalf("expected one entry, got %+v", rec.entries)
+	}
+	entry := rec.entries[0]
+	req, ok := entry.field("req")
+	if !ok || !req.(slog.Value).Equal(slog.GroupValue(slog.String("id", "abc"))) {
+		t.Fatalf("expected the req group, got %v", entry.keyvals)
+	}
+	if got, ok := entry.field("status"); !ok || got != 200 {
+		t.Fatalf("expected status=200 after the group, got %v", entry.keyvals)
+	}
+}
+
+func TestWideEventConcurrentAndBounded(t *testing.T) {
+	rec := &lockedRecorder{}
+	ctx := StartWideEventWithOptions(context.Background(), rec, "job", WideEventOptions{MaxFields: 10})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			AddFields(ctx, fmt.Sprintf("k%d", i), i)
+			if i == 7 {
+				AddFieldsAt(ctx, PanicLevel, "crashed", true)
+			}
+		}(i)
+	}
+	wg.Wait()
+	FinishWideEvent(ctx)
+
+	if len(rec.entries) != 1 {
+		t.Fatalf("expected one entry, got %d", len(rec.entries))
+	}
+	entry := rec.entries[0]
+	if entry.level != ErrorLevel {
+		t.Fatalf("expected panic to be clamped to error, got %v", entry.level)
+	}
+	// 10 kept fields, duration and dropped_fields.
+	if len(entry.keyvals) != 24 {
+		t.Fatalf("expected 10 fields plus duration and dropped count, got %v", entry.keyvals)
+	}
+	if dropped, _ := entry.field(DroppedFieldsKey); dropped != 11 {
+		t.Fatalf("expected 11 dropped fields, got %v", dropped)
+	}
+}
+
+func TestWideEventWithoutContextIsNoop(t *testing.T) {
+	ctx := context.Background()
+	AddFields(ctx, "k", "v")
+	AddFieldsAt(ctx, ErrorLevel, "k", "v")
+	FinishWideEvent(ctx)
+
+	level := ErrorLevel
+	rec := &lockedRecorder{}
+	ctx = StartWideEventWithOptions(ctx, rec, "batch", WideEventOptions{Level: &level})
+	FinishWideEvent(ctx)
+	if len(rec.entries) != 1 || rec.entries[0].level != ErrorLevel {
+		t.Fatalf("expected entry at configured level, got %+v", rec.entries)
+	}
+	if d, ok := rec.entries[0].field(DurationKey); !ok {
+		t.Fatalf("expected duration, got %v", d)
+	} else if _, ok := d.(time.Duration); !ok {
+		t.Fatalf("expected time.Duration, got %T", d)
+	}
+}